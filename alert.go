@@ -0,0 +1,66 @@
+package tdigest
+
+// QuantileAlert reports that a watched quantile crossed its threshold.
+type QuantileAlert struct {
+	Quantile  float64
+	Threshold float64
+	// Value is the estimate of the quantile that crossed the threshold.
+	Value float64
+	// Above is set if the quantile rose above the threshold, and unset if it
+	// fell back below the threshold minus the hysteresis.
+	Above bool
+}
+
+// quantileWatch is a quantile watched by WatchQuantile.
+type quantileWatch struct {
+	q, threshold, hysteresis float64
+	f                        func(QuantileAlert)
+	above                    bool
+}
+
+// WatchQuantile calls f whenever quantile q of the digest rises above the
+// threshold, and again once it falls below the threshold minus the
+// hysteresis, which keeps a quantile hovering around the threshold from
+// raising a stream of alerts. Quantiles are evaluated lazily, after every
+// compression of the centroids, so an alert is raised when the buffer of
+// incoming centroids fills up or at the next query, not on every sample. f
+// is called with the lock set by WithLocker held, so it must not use the
+// digest, and should return quickly. The returned function stops watching.
+// Clones of the digest do not inherit its watches.
+func (t *TDigest) WatchQuantile(q, threshold, hysteresis float64, f func(QuantileAlert)) (cancel func()) {
+	w := &quantileWatch{q: q, threshold: threshold, hysteresis: hysteresis, f: f}
+	t.lock()
+	t.watches = append(t.watches, w)
+	t.unlock()
+	return func() {
+		t.lock()
+		defer t.unlock()
+		for i, x := range t.watches {
+			if x == w {
+				t.watches = append(t.watches[:i:i], t.watches[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// checkWatches evaluates the watched quantiles after a compression.
+func (t *TDigest) checkWatches() {
+	if len(t.watches) == 0 {
+		return
+	}
+	t.updateCumulative()
+	s := t.summary()
+	for _, w := range t.watches {
+		v := s.quantile(w.q)
+		switch {
+		case !w.above && v > w.threshold:
+			w.above = true
+		case w.above && v < w.threshold-w.hysteresis:
+			w.above = false
+		default:
+			continue
+		}
+		w.f(QuantileAlert{Quantile: w.q, Threshold: w.threshold, Value: v, Above: w.above})
+	}
+}
@@ -0,0 +1,50 @@
+package tdigest_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_WatchQuantile(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	var alerts []bool
+	cancel := td.WatchQuantile(0.5, 100, 10, func(a tdigest.QuantileAlert) {
+		if a.Quantile != 0.5 || a.Threshold != 100 {
+			t.Errorf("unexpected alert %+v", a)
+		}
+		if a.Above != (a.Value > 100) {
+			t.Errorf("unexpected value in alert %+v", a)
+		}
+		alerts = append(alerts, a.Above)
+	})
+
+	add := func(x float64, n int) {
+		for i := 0; i < n; i++ {
+			td.Add(x, 1)
+		}
+		td.Flush()
+	}
+	add(50, 100)
+	if len(alerts) != 0 {
+		t.Fatalf("unexpected alerts %v", alerts)
+	}
+	add(200, 200)
+	// The median falls to 95, within the hysteresis, and then below it.
+	add(95, 200)
+	add(80, 1000)
+	clone := td.Clone()
+	add(300, 3000)
+	if want := []bool{true, false, true}; !reflect.DeepEqual(alerts, want) {
+		t.Errorf("unexpected alerts, got %v want %v", alerts, want)
+	}
+
+	clone.Add(300, 10000)
+	clone.Flush()
+	cancel()
+	add(0, 10000)
+	if len(alerts) != 3 {
+		t.Errorf("unexpected alerts after cancel, got %v", alerts)
+	}
+}
@@ -0,0 +1,109 @@
+package tdigest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidAVLTreeDigest is returned when decoding a malformed
+// AVLTreeDigest encoding.
+const ErrInvalidAVLTreeDigest = Error("invalid AVLTreeDigest encoding")
+
+// Encodings of the AVLTreeDigest of tdunning/t-digest.
+const (
+	avlVerboseEncoding = 1
+	avlSmallEncoding   = 2
+)
+
+// avlHeaderSize is the size of the encoding, min, max, compression and
+// number of centroids that precede the centroids.
+const avlHeaderSize = 4 + 8 + 8 + 8 + 4
+
+// ParseAVLTreeDigest decodes the output of the asBytes and asSmallBytes
+// methods of the AVLTreeDigest of tdunning/t-digest (versions 3.1 and later)
+// into a new digest, applying the given options. Both start with the
+// encoding as an int, the min, max and compression as doubles, and the
+// number of centroids as an int, all big endian. The verbose encoding
+// follows with the means as doubles and the counts as ints, and the small
+// encoding with the differences between consecutive means as floats and the
+// counts as varints. The encodings of MergingDigest are not supported.
+//
+// The small encoding only keeps the means to float precision, so the min
+// and max are widened where needed to enclose them.
+func ParseAVLTreeDigest(data []byte, opts ...Option) (*TDigest, error) {
+	if len(data) < avlHeaderSize {
+		return nil, fmt.Errorf("%w: truncated", ErrInvalidAVLTreeDigest)
+	}
+	encoding := binary.BigEndian.Uint32(data)
+	h := binaryHeader{
+		min:         readFloat64(data[4:]),
+		max:         readFloat64(data[12:]),
+		compression: readFloat64(data[20:]),
+	}
+	n := int(int32(binary.BigEndian.Uint32(data[28:])))
+	data = data[avlHeaderSize:]
+	if encoding != avlVerboseEncoding && encoding != avlSmallEncoding {
+		return nil, fmt.Errorf("%w: unsupported encoding %d", ErrInvalidAVLTreeDigest, encoding)
+	}
+	if !(h.compression >= 1 && h.compression <= maxDecodeCompression) {
+		return nil, fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidAVLTreeDigest, float64(maxDecodeCompression))
+	}
+	// Every centroid takes at least five bytes.
+	if n < 0 || n > len(data)/5 {
+		return nil, fmt.Errorf("%w: invalid number of centroids", ErrInvalidAVLTreeDigest)
+	}
+
+	means := make([]float64, n)
+	if encoding == avlVerboseEncoding {
+		if len(data) != 12*n {
+			return nil, fmt.Errorf("%w: expected %d centroids in %d bytes", ErrInvalidAVLTreeDigest, n, len(data))
+		}
+		for i := range means {
+			means[i] = readFloat64(data[8*i:])
+		}
+		data = data[8*n:]
+	} else {
+		x := 0.0
+		for i := range means {
+			x += float64(math.Float32frombits(binary.BigEndian.Uint32(data[4*i:])))
+			means[i] = x
+		}
+		data = data[4*n:]
+	}
+
+	d := &binaryDecoder{centroids: make(CentroidList, 0, n), invalid: ErrInvalidAVLTreeDigest}
+	for i, mean := range means {
+		var count uint64
+		if encoding == avlVerboseEncoding {
+			count = uint64(int32(binary.BigEndian.Uint32(data[4*i:])))
+		} else {
+			var k int
+			if count, k = readJavaVarint(data, 5); k <= 0 {
+				return nil, fmt.Errorf("%w: centroid %d: invalid count", ErrInvalidAVLTreeDigest, i)
+			}
+			data = data[k:]
+		}
+		if count > math.MaxInt32 {
+			return nil, fmt.Errorf("%w: centroid %d: invalid count", ErrInvalidAVLTreeDigest, i)
+		}
+		if err := d.add(mean, float64(count)); err != nil {
+			return nil, err
+		}
+	}
+	if encoding == avlSmallEncoding {
+		if len(data) > 0 {
+			return nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalidAVLTreeDigest, len(data))
+		}
+		if n > 0 {
+			h.min = math.Min(h.min, d.centroids[0].Mean)
+			h.max = math.Max(h.max, d.centroids[n-1].Mean)
+		}
+	}
+
+	t := NewWithCompression(h.compression, opts...)
+	if err := t.setDecoded(h, d); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,90 @@
+package tdigest_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+// avlTreeDigestBytes encodes centroids like the asBytes, or if small
+// asSmallBytes, methods of AVLTreeDigest.
+func avlTreeDigestBytes(small bool, min, max, compression float64, cl tdigest.CentroidList) []byte {
+	encoding := uint32(1)
+	if small {
+		encoding = 2
+	}
+	data := appendUint32(nil, encoding)
+	for _, x := range []float64{min, max, compression} {
+		data = appendUint64(data, math.Float64bits(x))
+	}
+	data = appendUint32(data, uint32(len(cl)))
+	x := 0.0
+	for _, c := range cl {
+		if small {
+			data = appendUint32(data, math.Float32bits(float32(c.Mean-x)))
+			x = c.Mean
+		} else {
+			data = appendUint64(data, math.Float64bits(c.Mean))
+		}
+	}
+	for _, c := range cl {
+		if small {
+			data = append(data, uvarint(uint64(c.Weight))...)
+		} else {
+			data = appendUint32(data, uint32(c.Weight))
+		}
+	}
+	return data
+}
+
+func TestParseAVLTreeDigest(t *testing.T) {
+	cl := tdigest.CentroidList{{Mean: 0.5, Weight: 1}, {Mean: 1.25, Weight: 300}, {Mean: 3, Weight: 2}}
+	for _, small := range []bool{false, true} {
+		td, err := tdigest.ParseAVLTreeDigest(avlTreeDigestBytes(small, 0.5, 3, 100, cl))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if td.Compression != 100 || !reflect.DeepEqual(td.Centroids(nil), cl) {
+			t.Errorf("unexpected digest %g %v", td.Compression, td.Centroids(nil))
+		}
+		if td.Quantile(0) != 0.5 || td.Quantile(1) != 3 {
+			t.Errorf("unexpected min and max %g %g", td.Quantile(0), td.Quantile(1))
+		}
+	}
+
+	// Means rounded to float precision may fall outside the min and max.
+	want := NormalDigest.Centroids(nil)
+	td, err := tdigest.ParseAVLTreeDigest(avlTreeDigestBytes(true, NormalDigest.Quantile(0), NormalDigest.Quantile(1), 1000, want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := compareQuantiles(td, NormalDigest, 1e-5); err != nil {
+		t.Error(err)
+	}
+
+	valid := avlTreeDigestBytes(false, 0.5, 3, 100, cl)
+	corrupt := func(offset int, b ...byte) []byte {
+		data := append([]byte(nil), valid...)
+		copy(data[offset:], b)
+		return data
+	}
+	for name, data := range map[string][]byte{
+		"empty":       nil,
+		"encoding":    corrupt(3, 3),
+		"compression": corrupt(20, 0),
+		"count":       corrupt(28, 0x7f),
+		"truncated":   valid[:len(valid)-1],
+		"unsorted":    avlTreeDigestBytes(false, 0, 3, 100, tdigest.CentroidList{{Mean: 2, Weight: 1}, {Mean: 1, Weight: 1}}),
+		"weight":      avlTreeDigestBytes(false, 0, 3, 100, tdigest.CentroidList{{Mean: 1, Weight: 0}}),
+		"min":         avlTreeDigestBytes(false, 1, 3, 100, cl),
+		"trailing":    append(avlTreeDigestBytes(true, 0.5, 3, 100, cl), 0),
+		"varint":      append(avlTreeDigestBytes(true, 0.5, 3, 100, cl[:1])[:36], 0xff, 0xff, 0xff, 0xff, 0xff, 0x01),
+	} {
+		if _, err := tdigest.ParseAVLTreeDigest(data); !errors.Is(err, tdigest.ErrInvalidAVLTreeDigest) {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+}
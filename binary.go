@@ -0,0 +1,351 @@
+package tdigest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrInvalidBinary is returned when decoding a malformed binary encoding.
+const ErrInvalidBinary = Error("invalid tdigest binary encoding")
+
+// encodingVersion is the version of the binary encoding.
+const encodingVersion = 1
+
+// maxBinaryHeaderSize is the maximum size of the version, compression, min,
+// max and number of centroids that precede the centroids.
+const maxBinaryHeaderSize = 4 + 8 + 8 + 8 + binary.MaxVarintLen64
+
+// maxBinaryCentroidSize is the maximum size of an encoded centroid.
+const maxBinaryCentroidSize = 2 * binary.MaxVarintLen64
+
+// MarshalBinary encodes the digest, processing any pending centroids first.
+// It implements encoding.BinaryMarshaler.
+//
+// The encoding starts with the version as a big endian uint32, followed by
+// the compression, min and max as big endian float64s, and the number of
+// centroids as a uvarint. The mean of the first centroid is a float64, and
+// every following mean is the uvarint difference to the previous one, in a
+// mapping of float64 bits that preserves their order. Integral weights are
+// stored as a uvarint of twice the weight, and any other weight as a uvarint
+// 1 followed by the float64. The encoding ends with the CRC-32 (Castagnoli)
+// of all the preceding bytes as a big endian uint32.
+func (t *TDigest) MarshalBinary() ([]byte, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, err
+	}
+	// Most centroids take about half the maximum size.
+	return t.appendBinary(make([]byte, 0, maxBinaryHeaderSize+t.processed.Len()*maxBinaryCentroidSize/2+checksumSize)), nil
+}
+
+// AppendBinary appends the encoding of MarshalBinary to buf and returns the
+// extended buffer, which avoids allocating when buf has enough capacity.
+// In strict mode it returns ErrUnflushed if the digest has pending
+// centroids.
+func (t *TDigest) AppendBinary(buf []byte) ([]byte, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return buf, err
+	}
+	return t.appendBinary(buf), nil
+}
+
+func (t *TDigest) appendBinary(buf []byte) []byte {
+	start := len(buf)
+	buf = t.appendBinaryHeader(buf)
+	var key uint64
+	for i, c := range t.processed {
+		buf, key = appendBinaryCentroid(buf, i, key, c)
+	}
+	return appendChecksum(buf, start)
+}
+
+func (t *TDigest) appendBinaryHeader(buf []byte) []byte {
+	buf = appendUint32(buf, encodingVersion)
+	buf = appendFloat64(buf, t.Compression)
+	buf = appendFloat64(buf, t.min)
+	buf = appendFloat64(buf, t.max)
+	return appendUvarint(buf, uint64(t.processed.Len()))
+}
+
+// appendBinaryCentroid appends the i-th centroid c, given the ordered key of
+// the previous mean, and returns the ordered key of its mean.
+func appendBinaryCentroid(buf []byte, i int, prev uint64, c Centroid) ([]byte, uint64) {
+	key := orderedKey(c.Mean)
+	if i == 0 {
+		buf = appendFloat64(buf, c.Mean)
+	} else {
+		buf = appendUvarint(buf, key-prev)
+	}
+	if c.Weight == math.Trunc(c.Weight) && c.Weight < 1<<53 {
+		buf = appendUvarint(buf, uint64(c.Weight)<<1)
+	} else {
+		buf = appendUvarint(buf, 1)
+		buf = appendFloat64(buf, c.Weight)
+	}
+	return buf, key
+}
+
+// UnmarshalBinary replaces the data and compression of the digest by those
+// encoded by MarshalBinary, keeping its other settings. It implements
+// encoding.BinaryUnmarshaler. Decoding is as strict as ParseText, and leaves
+// the digest unchanged on error. The checksum is verified before any other
+// field, and a mismatch is reported as ErrChecksum. Encodings compressed by
+// MarshalBinaryCompressed are detected and decompressed with the registered
+// codec.
+func (t *TDigest) UnmarshalBinary(data []byte) error {
+	if bytes.HasPrefix(data, []byte(compressedMagic)) {
+		var err error
+		if data, err = decompressBinary(data); err != nil {
+			return err
+		}
+	}
+	if err := verifyChecksum(data); err != nil {
+		return err
+	}
+	r := bytes.NewReader(data[:len(data)-checksumSize])
+	h, d, err := decodeBinary(r)
+	if err != nil {
+		return err
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidBinary, r.Len())
+	}
+	return t.setDecoded(h, d)
+}
+
+// binaryReader is a source of an encoded digest.
+type binaryReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// binaryHeader holds the fields preceding the centroids.
+type binaryHeader struct {
+	compression float64
+	min         float64
+	max         float64
+	count       uint64
+}
+
+// decodeBinary reads an encoded digest from r, up to its checksum.
+func decodeBinary(r binaryReader) (binaryHeader, *binaryDecoder, error) {
+	var h binaryHeader
+	var buf [4 + 8 + 8 + 8]byte
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		return h, nil, readError(err)
+	}
+	if version := binary.BigEndian.Uint32(buf[:]); version != encodingVersion {
+		return h, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBinary, version)
+	}
+	if _, err := io.ReadFull(r, buf[4:]); err != nil {
+		return h, nil, readError(err)
+	}
+	h.compression = readFloat64(buf[4:])
+	h.min = readFloat64(buf[12:])
+	h.max = readFloat64(buf[20:])
+	var err error
+	if h.count, err = readUvarint(r); err != nil {
+		return h, nil, err
+	}
+	if !(h.compression >= 1 && h.compression <= maxDecodeCompression) {
+		return h, nil, fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidBinary, float64(maxDecodeCompression))
+	}
+
+	// Do not trust the count with a large allocation up front.
+	n := h.count
+	if n > 1<<16 {
+		n = 1 << 16
+	}
+	d := &binaryDecoder{centroids: make(CentroidList, 0, n)}
+	var key uint64
+	for i := uint64(0); i < h.count; i++ {
+		var mean, weight float64
+		if mean, weight, key, err = readBinaryCentroid(r, i, key); err != nil {
+			return h, nil, err
+		}
+		if err := d.add(mean, weight); err != nil {
+			return h, nil, err
+		}
+	}
+	return h, d, nil
+}
+
+// readBinaryCentroid reads the i-th centroid, given the ordered key of the
+// previous mean, and returns the ordered key of its mean.
+func readBinaryCentroid(r binaryReader, i, prev uint64) (mean, weight float64, key uint64, err error) {
+	if i == 0 {
+		if mean, err = readBinaryFloat64(r); err != nil {
+			return 0, 0, 0, err
+		}
+		key = orderedKey(mean)
+	} else {
+		delta, err := readUvarint(r)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		key = prev + delta
+		if key < prev {
+			return 0, 0, 0, fmt.Errorf("%w: centroid %d: invalid mean", ErrInvalidBinary, i)
+		}
+		mean = fromOrderedKey(key)
+	}
+
+	v, err := readUvarint(r)
+	switch {
+	case err != nil:
+		return 0, 0, 0, err
+	case v == 1:
+		weight, err = readBinaryFloat64(r)
+	case v&1 == 0:
+		weight = float64(v >> 1)
+	default:
+		err = fmt.Errorf("%w: centroid %d: invalid weight", ErrInvalidBinary, i)
+	}
+	return mean, weight, key, err
+}
+
+func readBinaryFloat64(r io.Reader) (float64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, readError(err)
+	}
+	return readFloat64(buf[:]), nil
+}
+
+// readUvarint reads a uvarint like binary.ReadUvarint, reporting an
+// overlong one as ErrInvalidBinary.
+func readUvarint(r io.ByteReader) (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, readError(err)
+		}
+		if b < 0x80 {
+			if shift == 63 && b > 1 {
+				break
+			}
+			return v | uint64(b)<<shift, nil
+		}
+		v |= uint64(b&0x7f) << shift
+	}
+	return 0, fmt.Errorf("%w: varint overflows", ErrInvalidBinary)
+}
+
+// readError reports a truncated encoding as ErrInvalidBinary, and passes on
+// any other error of the reader.
+func readError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: truncated", ErrInvalidBinary)
+	}
+	return err
+}
+
+// orderedKey maps x to an integer that sorts like x, with both zeros mapped
+// to the key of positive zero.
+func orderedKey(x float64) uint64 {
+	if x == 0 {
+		x = 0
+	}
+	b := math.Float64bits(x)
+	if b>>63 == 1 {
+		return ^b
+	}
+	return b | 1<<63
+}
+
+func fromOrderedKey(k uint64) float64 {
+	if k>>63 == 1 {
+		return math.Float64frombits(k &^ (1 << 63))
+	}
+	return math.Float64frombits(^k)
+}
+
+// binaryDecoder validates and collects decoded centroids.
+type binaryDecoder struct {
+	centroids CentroidList
+	weight    float64
+	// invalid is reported for invalid data, ErrInvalidBinary if empty.
+	invalid Error
+}
+
+func (d *binaryDecoder) errorf(format string, args ...interface{}) error {
+	invalid := d.invalid
+	if invalid == "" {
+		invalid = ErrInvalidBinary
+	}
+	return fmt.Errorf("%w: "+format, append([]interface{}{invalid}, args...)...)
+}
+
+func (d *binaryDecoder) add(mean, weight float64) error {
+	i := d.centroids.Len()
+	if math.IsNaN(mean) {
+		return d.errorf("centroid %d: invalid mean", i)
+	}
+	if !(weight > 0) || math.IsInf(weight, 1) {
+		return d.errorf("centroid %d: invalid weight", i)
+	}
+	if i > 0 && mean < d.centroids[i-1].Mean {
+		return d.errorf("centroid %d: means are not sorted", i)
+	}
+	d.centroids = append(d.centroids, Centroid{Mean: mean, Weight: weight})
+	d.weight += weight
+	return nil
+}
+
+// setDecoded replaces the data of the digest by the decoded centroids, once
+// they have been validated against the header.
+func (t *TDigest) setDecoded(h binaryHeader, d *binaryDecoder) error {
+	n := d.centroids.Len()
+	if math.IsInf(d.weight, 1) {
+		return d.errorf("total weight overflows")
+	}
+	if n > 0 && (math.IsNaN(h.min) || math.IsNaN(h.max) || h.min > d.centroids[0].Mean || h.max < d.centroids[n-1].Mean) {
+		return d.errorf("min and max do not enclose the centroids")
+	}
+
+	t.lock()
+	defer t.unlock()
+	t.setCompression(h.compression)
+	t.reallocate()
+	t.reset()
+	t.processed = append(t.processed, d.centroids...)
+	t.processedWeight = d.weight
+	// Encodings do not record the samples, so every centroid counts as one.
+	t.accepted = uint64(n)
+	if n > 0 {
+		// The centroids may well have been compressed.
+		t.exact = false
+		t.min, t.max = h.min, h.max
+	}
+	t.publishStats()
+	return nil
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendFloat64(buf []byte, x float64) []byte {
+	v := math.Float64bits(x)
+	return append(buf, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+		byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+func readFloat64(b []byte) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(b))
+}
@@ -0,0 +1,192 @@
+package tdigest_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_MarshalBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		td   func() *tdigest.TDigest
+	}{
+		{name: "empty", td: func() *tdigest.TDigest { return tdigest.NewWithCompression(100) }},
+		{name: "single", td: func() *tdigest.TDigest {
+			td := tdigest.NewWithCompression(100)
+			td.Add(1, 2)
+			return td
+		}},
+		{name: "normal", td: func() *tdigest.TDigest { return NormalDigest }},
+		{name: "infinite", td: func() *tdigest.TDigest {
+			td := tdigest.NewWithCompression(10)
+			td.AddValues(math.Inf(-1), 1, 2, math.Inf(1))
+			return td
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := tt.td()
+			data, err := td.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := tdigest.New()
+			if err := got.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			if got.Compression != td.Compression {
+				t.Errorf("unexpected compression, got %g want %g", got.Compression, td.Compression)
+			}
+			if !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+				t.Error("centroids differ after round trip")
+			}
+			for _, q := range []float64{0, 0.5, 1} {
+				if g, w := got.Quantile(q), td.Quantile(q); g != w && !(math.IsNaN(g) && math.IsNaN(w)) {
+					t.Errorf("unexpected quantile %g, got %g want %g", q, g, w)
+				}
+			}
+
+			appended, err := td.AppendBinary([]byte("prefix"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(appended[:6]) != "prefix" || !reflect.DeepEqual(appended[6:], data) {
+				t.Error("AppendBinary differs from MarshalBinary")
+			}
+		})
+	}
+}
+
+func TestTdigest_AppendBinaryAllocs(t *testing.T) {
+	buf, _ := NormalDigest.AppendBinary(nil)
+	allocs := testing.AllocsPerRun(10, func() {
+		buf, _ = NormalDigest.AppendBinary(buf[:0])
+	})
+	if allocs != 0 {
+		t.Errorf("unexpected allocations, got %g want 0", allocs)
+	}
+}
+
+// binaryHeader returns the version, compression, min and max of a binary
+// encoding.
+func binaryHeader(version uint32, compression, min, max float64) []byte {
+	data := appendUint32(nil, version)
+	for _, x := range []float64{compression, min, max} {
+		data = appendUint64(data, math.Float64bits(x))
+	}
+	return data
+}
+
+func appendUint32(data []byte, v uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return append(data, b[:]...)
+}
+
+func appendUint64(data []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(data, b[:]...)
+}
+
+func uvarint(v uint64) []byte {
+	b := make([]byte, binary.MaxVarintLen64)
+	return b[:binary.PutUvarint(b, v)]
+}
+
+// sealed returns data followed by its checksum.
+func sealed(data []byte) []byte {
+	return appendUint32(append([]byte(nil), data...), crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+}
+
+func TestTdigest_MarshalBinarySize(t *testing.T) {
+	// Integral weights take much less than a float64 mean and weight.
+	data, _ := NormalDigest.MarshalBinary()
+	if n := len(NormalDigest.Centroids(nil)) * 16 * 2 / 3; len(data) > n {
+		t.Errorf("unexpected encoding size, got %d want at most %d", len(data), n)
+	}
+}
+
+func TestTdigest_UnmarshalBinaryErrors(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddValues(1, 2, 3)
+	valid, _ := td.MarshalBinary()
+	valid = valid[:len(valid)-4]
+	concat := func(parts ...[]byte) []byte {
+		var data []byte
+		for _, p := range parts {
+			data = append(data, p...)
+		}
+		return data
+	}
+	one := appendUint64(nil, math.Float64bits(1))
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "short header", data: sealed(valid[:20])},
+		{name: "version", data: sealed(concat(binaryHeader(9, 100, 1, 3), []byte{0}))},
+		{name: "compression", data: sealed(concat(binaryHeader(1, 0, 1, 3), []byte{0}))},
+		{name: "truncated", data: sealed(valid[:len(valid)-1])},
+		{name: "trailing", data: sealed(concat(valid, []byte{0}))},
+		{name: "count", data: sealed(concat(binaryHeader(1, 100, 1, 1), []byte{2}, one, []byte{2}))},
+		{name: "nan mean", data: sealed(concat(binaryHeader(1, 100, 1, 3), []byte{1},
+			appendUint64(nil, math.Float64bits(math.NaN())), []byte{2}))},
+		{name: "negative weight", data: sealed(concat(binaryHeader(1, 100, 1, 3), []byte{1}, one, []byte{1},
+			appendUint64(nil, math.Float64bits(-1))))},
+		{name: "min", data: sealed(concat(binaryHeader(1, 100, 2, 3), []byte{1}, one, []byte{2}))},
+		{name: "weight tag", data: sealed(concat(binaryHeader(1, 100, 1, 1), []byte{1}, one, []byte{3}))},
+		{name: "mean overflow", data: sealed(concat(binaryHeader(1, 100, 1, 3), []byte{2}, one, []byte{2},
+			uvarint(math.MaxUint64), []byte{2}))},
+		{name: "varint overflow", data: sealed(concat(binaryHeader(1, 100, 1, 3), bytes.Repeat([]byte{0xff}, 10), []byte{1}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tdigest.NewWithCompression(10)
+			got.Add(42, 1)
+			err := got.UnmarshalBinary(tt.data)
+			if !errors.Is(err, tdigest.ErrInvalidBinary) {
+				t.Errorf("unexpected error %v", err)
+			}
+			if got.Compression != 10 || got.Quantile(0.5) != 42 {
+				t.Error("digest was modified on error")
+			}
+		})
+	}
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	td := tdigest.NewWithCompression(10)
+	td.AddValues(1, 2, 2, 3, 10)
+	data, _ := td.MarshalBinary()
+	f.Add(data)
+	empty, _ := tdigest.New().MarshalBinary()
+	f.Add(empty)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		td := tdigest.New()
+		if err := td.UnmarshalBinary(data); err != nil {
+			return
+		}
+		again, err := td.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := tdigest.New()
+		if err := got.UnmarshalBinary(again); err != nil {
+			t.Fatalf("marshaled digest does not unmarshal: %v", err)
+		}
+		if !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+			t.Error("centroids differ after round trip")
+		}
+		td.Quantile(0.5)
+		td.CDF(0)
+	})
+}
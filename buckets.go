@@ -0,0 +1,109 @@
+package tdigest
+
+import (
+	"fmt"
+	"math"
+)
+
+// ErrInvalidBuckets is returned when converting malformed histogram buckets.
+const ErrInvalidBuckets = Error("invalid histogram buckets")
+
+// ErrValueOutOfRange is returned when recording a value that does not fit
+// an int64.
+const ErrValueOutOfRange = Error("value out of the range of int64")
+
+// bucketSpread is the largest number of centroids the count of a bucket is
+// spread over.
+const bucketSpread = 10
+
+// Bucket counts the values of a histogram between Low and High, inclusive.
+// The bars of the Distribution of an HdrHistogram, for one, convert to
+// buckets directly.
+type Bucket struct {
+	Low, High float64
+	Count     float64
+}
+
+// FromBuckets builds a new digest with the given compression and options
+// from histogram buckets, spreading the count of every bucket evenly over
+// its range, since the values within a bucket are unknown. Buckets may be
+// given in any order, but their bounds must be finite, their counts finite
+// and not negative, and the compression between 1 and 100000.
+func FromBuckets(buckets []Bucket, compression float64, opts ...Option) (*TDigest, error) {
+	if !(compression >= 1 && compression <= maxDecodeCompression) {
+		return nil, fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidBuckets, float64(maxDecodeCompression))
+	}
+	var cl CentroidList
+	for i, b := range buckets {
+		if math.IsNaN(b.Low) || math.IsNaN(b.High) || math.IsInf(b.Low, 0) || math.IsInf(b.High, 0) || b.Low > b.High {
+			return nil, fmt.Errorf("%w: bucket %d: invalid bounds [%g, %g]", ErrInvalidBuckets, i, b.Low, b.High)
+		}
+		if !(b.Count >= 0) || math.IsInf(b.Count, 1) {
+			return nil, fmt.Errorf("%w: bucket %d: invalid count %g", ErrInvalidBuckets, i, b.Count)
+		}
+		cl = appendBucket(cl, b)
+	}
+	sortCentroids(cl)
+
+	t := NewWithCompression(compression, opts...)
+	t.AddCentroidList(cl)
+	return t, nil
+}
+
+// appendBucket appends centroids spreading the count of b evenly over its
+// range to cl.
+func appendBucket(cl CentroidList, b Bucket) CentroidList {
+	if b.Count == 0 {
+		return cl
+	}
+	n := bucketSpread
+	if c := math.Ceil(b.Count); c < float64(n) {
+		n = int(c)
+	}
+	if b.Low == b.High {
+		n = 1
+	}
+	width := (b.High - b.Low) / float64(n)
+	for j := 0; j < n; j++ {
+		cl = append(cl, Centroid{Mean: b.Low + (float64(j)+0.5)*width, Weight: b.Count / float64(n)})
+	}
+	return cl
+}
+
+// ValueRecorder records integer values, each a number of times, such as an
+// HdrHistogram from github.com/HdrHistogram/hdrhistogram-go.
+type ValueRecorder interface {
+	RecordValues(v, n int64) error
+}
+
+// RecordInto records the centroids of the digest into r, processing any
+// pending centroids first. Every centroid is recorded at its mean rounded to
+// the nearest integer, so values should be scaled to the unit of r first,
+// for example with MapValues. Weights are rounded so that the total count is
+// preserved. Errors of r are passed on, and values that do not fit an int64
+// are reported as ErrValueOutOfRange.
+func (t *TDigest) RecordInto(r ValueRecorder) error {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return err
+	}
+
+	var soFar, recorded float64
+	for _, c := range t.processed {
+		soFar += c.Weight
+		n := math.Round(soFar) - recorded
+		if n <= 0 {
+			continue
+		}
+		v := math.Round(c.Mean)
+		if !(v >= math.MinInt64 && v < math.MaxInt64) {
+			return fmt.Errorf("%w: %g", ErrValueOutOfRange, c.Mean)
+		}
+		if err := r.RecordValues(int64(v), int64(n)); err != nil {
+			return err
+		}
+		recorded += n
+	}
+	return nil
+}
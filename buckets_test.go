@@ -0,0 +1,82 @@
+package tdigest_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+// recorder collects recorded values like an HdrHistogram.
+type recorder map[int64]int64
+
+func (r recorder) RecordValues(v, n int64) error {
+	if v < 0 {
+		return errors.New("negative value")
+	}
+	r[v] += n
+	return nil
+}
+
+func TestTdigest_RecordInto(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.Add(1, 1.5)
+	td.Add(10, 1.5)
+	td.Add(100.4, 2)
+	r := recorder{}
+	if err := td.RecordInto(r); err != nil {
+		t.Fatal(err)
+	}
+	want := recorder{1: 2, 10: 1, 100: 2}
+	if len(r) != len(want) || r[1] != want[1] || r[10] != want[10] || r[100] != want[100] {
+		t.Errorf("unexpected values, got %v want %v", r, want)
+	}
+
+	td.Add(-5, 1)
+	if err := td.RecordInto(recorder{}); err == nil {
+		t.Error("expected error of the recorder")
+	}
+	td = tdigest.NewWithCompression(100)
+	td.Add(math.Inf(1), 1)
+	if err := td.RecordInto(recorder{}); !errors.Is(err, tdigest.ErrValueOutOfRange) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestFromBuckets(t *testing.T) {
+	// Uniform data in ten buckets.
+	var buckets []tdigest.Bucket
+	for i := 9; i >= 0; i-- {
+		buckets = append(buckets, tdigest.Bucket{Low: float64(i), High: float64(i + 1), Count: 1000})
+	}
+	buckets = append(buckets, tdigest.Bucket{Low: 5, High: 5, Count: 0})
+	td, err := tdigest.FromBuckets(buckets, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if td.Count() != 10000 {
+		t.Errorf("unexpected count %g", td.Count())
+	}
+	for _, q := range []float64{0.1, 0.25, 0.5, 0.75, 0.9} {
+		if got := td.Quantile(q); math.Abs(got-10*q) > 0.1 {
+			t.Errorf("quantile %g: got %g want %g", q, got, 10*q)
+		}
+	}
+
+	for _, b := range []tdigest.Bucket{
+		{Low: 2, High: 1, Count: 1},
+		{Low: math.NaN(), High: 1, Count: 1},
+		{Low: 0, High: math.Inf(1), Count: 1},
+		{Low: 0, High: 1, Count: -1},
+	} {
+		if _, err := tdigest.FromBuckets([]tdigest.Bucket{b}, 100); !errors.Is(err, tdigest.ErrInvalidBuckets) {
+			t.Errorf("%v: unexpected error %v", b, err)
+		}
+	}
+	for _, compression := range []float64{0, math.NaN(), 1e6} {
+		if _, err := tdigest.FromBuckets(nil, compression); !errors.Is(err, tdigest.ErrInvalidBuckets) {
+			t.Errorf("compression %g: unexpected error %v", compression, err)
+		}
+	}
+}
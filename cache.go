@@ -0,0 +1,49 @@
+package tdigest
+
+// WithQueryCache remembers the results of the last n distinct Quantile and
+// CDF queries, which are answered from the cache until the centroids change.
+// This suits monitoring systems that repeatedly query the same few quantiles
+// of a digest that is written in bursts. Queries still process pending
+// centroids first, so any write in between invalidates the cache.
+func WithQueryCache(n int) Option {
+	return func(t *TDigest) {
+		if n > 0 {
+			t.cache = make([]cacheEntry, n)
+		} else {
+			t.cache = nil
+		}
+		t.cacheNext = 0
+	}
+}
+
+// cacheEntry holds the result of a Quantile, or a CDF query, for arg.
+type cacheEntry struct {
+	generation uint64
+	cdf        bool
+	arg        float64
+	result     float64
+}
+
+// cached returns the cached result of a query, if any.
+func (t *TDigest) cached(cdf bool, arg float64) (float64, bool) {
+	for _, e := range t.cache {
+		if e.generation == t.generation && e.cdf == cdf && e.arg == arg {
+			return e.result, true
+		}
+	}
+	return 0, false
+}
+
+// store adds the result of a query to the cache, replacing the oldest entry.
+func (t *TDigest) store(cdf bool, arg, result float64) {
+	if len(t.cache) == 0 {
+		return
+	}
+	t.cache[t.cacheNext] = cacheEntry{
+		generation: t.generation,
+		cdf:        cdf,
+		arg:        arg,
+		result:     result,
+	}
+	t.cacheNext = (t.cacheNext + 1) % len(t.cache)
+}
@@ -0,0 +1,52 @@
+package tdigest_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestWithQueryCache(t *testing.T) {
+	td := tdigest.NewWithCompression(100, tdigest.WithQueryCache(4))
+	want := tdigest.NewWithCompression(100)
+	check := func(step string) {
+		t.Helper()
+		for _, q := range quantiles {
+			if got, w := td.Quantile(q), want.Quantile(q); math.Float64bits(got) != math.Float64bits(w) {
+				t.Errorf("%s: unexpected quantile %g, got %g want %g", step, q, got, w)
+			}
+			if got, w := td.CDF(q*20), want.CDF(q*20); math.Float64bits(got) != math.Float64bits(w) {
+				t.Errorf("%s: unexpected CDF %g, got %g want %g", step, q*20, got, w)
+			}
+		}
+	}
+
+	check("empty")
+	td.AddSlice(NormalData[:10000])
+	want.AddSlice(NormalData[:10000])
+	check("first batch")
+	check("repeated")
+	td.Add(100, 1)
+	want.Add(100, 1)
+	check("single add")
+	if err := td.Scale(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := want.Scale(2); err != nil {
+		t.Fatal(err)
+	}
+	check("scaled")
+	td.Reset()
+	want.Reset()
+	check("reset")
+
+	td.AddSlice(NormalData[:10000])
+	td.Quantile(0.5)
+	allocs := testing.AllocsPerRun(10, func() {
+		td.Quantile(0.5)
+	})
+	if allocs != 0 {
+		t.Errorf("unexpected allocations, got %g want 0", allocs)
+	}
+}
@@ -0,0 +1,58 @@
+package tdigest
+
+import "crypto/sha256"
+
+// Canonicalize processes any pending centroids and compresses the digest
+// once more over all its centroids, so that digests holding the same
+// centroids, however they were added, end up identical. Negative zeros are
+// replaced by positive ones. Digests in exact mode keep all their values.
+// In strict mode it returns ErrUnflushed, leaving the digest unchanged, if
+// the digest has pending centroids.
+func (t *TDigest) Canonicalize() error {
+	t.lock()
+	defer t.unlock()
+	return t.canonicalize()
+}
+
+func (t *TDigest) canonicalize() error {
+	if err := t.prepareRead(); err != nil {
+		return err
+	}
+	t.unshare()
+	if !t.exact && t.processed.Len() > 1 {
+		// Use the unprocessed list to hold the centroids while they are
+		// compressed again.
+		old := append(t.unprocessed, t.processed...)
+		t.processed.Clear()
+		soFar, limit := 0.0, 0.0
+		for _, c := range old {
+			soFar, limit = t.compress(c, soFar, limit)
+		}
+		t.unprocessed = old[:0]
+	}
+	for i := range t.processed {
+		if t.processed[i].Mean == 0 {
+			t.processed[i].Mean = 0
+		}
+	}
+	if t.min == 0 {
+		t.min = 0
+	}
+	if t.max == 0 {
+		t.max = 0
+	}
+	return nil
+}
+
+// Hash returns the SHA-256 of the binary encoding of the canonical form of
+// the digest, leaving the digest itself unchanged. Digests with equal
+// contents have equal hashes across processes and platforms, as long as
+// the binary encoding version does not change. In strict mode it returns
+// ErrUnflushed if the digest has pending centroids.
+func (t *TDigest) Hash() ([sha256.Size]byte, error) {
+	c := t.Clone()
+	if err := c.canonicalize(); err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(c.appendBinary(make([]byte, 0, maxBinaryHeaderSize+c.processed.Len()*maxBinaryCentroidSize/2+checksumSize))), nil
+}
@@ -0,0 +1,76 @@
+package tdigest_test
+
+import (
+	"crypto/sha256"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func hash(t *testing.T, td *tdigest.TDigest) [sha256.Size]byte {
+	t.Helper()
+	h, err := td.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestTdigest_Hash(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddSlice(NormalData[:10000])
+	before := td.Centroids(nil)
+	h := hash(t, td)
+	if !reflect.DeepEqual(td.Centroids(nil), before) {
+		t.Error("Hash modified the digest")
+	}
+	if hash(t, td) != h {
+		t.Error("hash is not stable")
+	}
+
+	data, _ := td.MarshalBinary()
+	decoded := tdigest.New()
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if hash(t, decoded) != h {
+		t.Error("decoded digest hashes differently")
+	}
+
+	pending := td.Clone()
+	pending.Add(1e6, 1)
+	processed := td.Clone()
+	processed.Add(1e6, 1)
+	processed.Flush()
+	if hash(t, pending) != hash(t, processed) {
+		t.Error("pending centroids change the hash")
+	}
+	if hash(t, pending) == h {
+		t.Error("different digests hash equally")
+	}
+
+	pos, neg := tdigest.NewWithCompression(10), tdigest.NewWithCompression(10)
+	pos.AddValues(0, 1)
+	neg.AddValues(math.Copysign(0, -1), 1)
+	if hash(t, pos) != hash(t, neg) {
+		t.Error("signed zeros hash differently")
+	}
+}
+
+func TestTdigest_Canonicalize(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddSlice(NormalData[:10000])
+	orig := td.Clone()
+	n := len(td.Centroids(nil))
+	if err := td.Canonicalize(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(td.Centroids(nil)); got > n {
+		t.Errorf("canonicalizing added centroids, got %d want at most %d", got, n)
+	}
+	if err := compareQuantiles(td, orig, 0.01); err != nil {
+		t.Error(err)
+	}
+}
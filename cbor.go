@@ -0,0 +1,290 @@
+package tdigest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidCBOR is returned when decoding a malformed CBOR encoding.
+const ErrInvalidCBOR = Error("invalid tdigest CBOR encoding")
+
+// CBORTag is the CBOR tag number marking an encoded digest, from the first
+// come first served range. It spells "tdig" in ASCII.
+const CBORTag = 0x74646967
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// Additional information of the CBOR floats.
+const (
+	cborFloat16 = 25
+	cborFloat32 = 26
+	cborFloat64 = 27
+)
+
+// MarshalCBOR encodes the digest as CBOR, processing any pending centroids
+// first. The encoding is a map tagged with CBORTag, holding the
+// "compression", the "min" and "max" unless the digest is empty, and the
+// "centroids" as an array of [mean, weight] arrays. Integral weights are
+// encoded as integers, and all other numbers as float64s.
+func (t *TDigest) MarshalCBOR() ([]byte, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, err
+	}
+
+	n := t.processed.Len()
+	buf := make([]byte, 0, 64+n*20)
+	buf = appendCBORHead(buf, cborTag, CBORTag)
+	if n == 0 {
+		buf = appendCBORHead(buf, cborMap, 2)
+	} else {
+		buf = appendCBORHead(buf, cborMap, 4)
+	}
+	buf = appendCBORText(buf, "compression")
+	buf = appendCBORFloat(buf, t.Compression)
+	if n > 0 {
+		buf = appendCBORText(buf, "min")
+		buf = appendCBORFloat(buf, t.min)
+		buf = appendCBORText(buf, "max")
+		buf = appendCBORFloat(buf, t.max)
+	}
+	buf = appendCBORText(buf, "centroids")
+	buf = appendCBORHead(buf, cborArray, uint64(n))
+	for _, c := range t.processed {
+		buf = appendCBORHead(buf, cborArray, 2)
+		buf = appendCBORFloat(buf, c.Mean)
+		if c.Weight == math.Trunc(c.Weight) && c.Weight < 1<<53 {
+			buf = appendCBORHead(buf, cborUint, uint64(c.Weight))
+		} else {
+			buf = appendCBORFloat(buf, c.Weight)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalCBOR replaces the data and compression of the digest by those
+// encoded by MarshalCBOR, keeping its other settings. The tag is optional,
+// the fields may come in any order, and numbers may be integers or floats of
+// any precision, but only definite lengths are supported and unknown fields
+// are rejected. Decoding leaves the digest unchanged on error.
+func (t *TDigest) UnmarshalCBOR(data []byte) error {
+	r := cborReader{data: data}
+	major, arg, err := r.head()
+	if err != nil {
+		return err
+	}
+	if major == cborTag {
+		if arg != CBORTag {
+			return fmt.Errorf("%w: unexpected tag %d", ErrInvalidCBOR, arg)
+		}
+		if major, arg, err = r.head(); err != nil {
+			return err
+		}
+	}
+	if major != cborMap {
+		return fmt.Errorf("%w: expected a map", ErrInvalidCBOR)
+	}
+
+	h := binaryHeader{min: math.NaN(), max: math.NaN()}
+	d := &binaryDecoder{invalid: ErrInvalidCBOR}
+	seen := make(map[string]bool)
+	for i := uint64(0); i < arg; i++ {
+		key, err := r.text()
+		if err != nil {
+			return err
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: duplicate field %q", ErrInvalidCBOR, key)
+		}
+		seen[key] = true
+		switch key {
+		case "compression":
+			h.compression, err = r.number()
+		case "min":
+			h.min, err = r.number()
+		case "max":
+			h.max, err = r.number()
+		case "centroids":
+			err = r.centroids(d)
+		default:
+			err = fmt.Errorf("%w: unknown field %q", ErrInvalidCBOR, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(r.data) > 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidCBOR, len(r.data))
+	}
+	if !(h.compression >= 1 && h.compression <= maxDecodeCompression) {
+		return fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidCBOR, float64(maxDecodeCompression))
+	}
+	return t.setDecoded(h, d)
+}
+
+// cborReader decodes the subset of CBOR used by MarshalCBOR.
+type cborReader struct {
+	data []byte
+	// ai is the additional information of the last head.
+	ai byte
+}
+
+// head reads the major type and argument of the next data item.
+func (r *cborReader) head() (major byte, arg uint64, err error) {
+	if len(r.data) == 0 {
+		return 0, 0, fmt.Errorf("%w: truncated", ErrInvalidCBOR)
+	}
+	major, r.ai = r.data[0]>>5, r.data[0]&0x1f
+	r.data = r.data[1:]
+	if r.ai < 24 {
+		return major, uint64(r.ai), nil
+	}
+	if r.ai > 27 {
+		return 0, 0, fmt.Errorf("%w: unsupported additional information %d", ErrInvalidCBOR, r.ai)
+	}
+	n := 1 << (r.ai - 24)
+	if len(r.data) < n {
+		return 0, 0, fmt.Errorf("%w: truncated", ErrInvalidCBOR)
+	}
+	for _, b := range r.data[:n] {
+		arg = arg<<8 | uint64(b)
+	}
+	r.data = r.data[n:]
+	return major, arg, nil
+}
+
+func (r *cborReader) text() (string, error) {
+	major, n, err := r.head()
+	if err != nil {
+		return "", err
+	}
+	if major != cborText {
+		return "", fmt.Errorf("%w: expected a text string", ErrInvalidCBOR)
+	}
+	if uint64(len(r.data)) < n {
+		return "", fmt.Errorf("%w: truncated", ErrInvalidCBOR)
+	}
+	s := string(r.data[:n])
+	r.data = r.data[n:]
+	return s, nil
+}
+
+func (r *cborReader) number() (float64, error) {
+	major, arg, err := r.head()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case major == cborUint:
+		return float64(arg), nil
+	case major == cborNegInt:
+		return -1 - float64(arg), nil
+	case major == cborSimple && r.ai == cborFloat16:
+		return float16to64(uint16(arg)), nil
+	case major == cborSimple && r.ai == cborFloat32:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case major == cborSimple && r.ai == cborFloat64:
+		return math.Float64frombits(arg), nil
+	}
+	return 0, fmt.Errorf("%w: expected a number", ErrInvalidCBOR)
+}
+
+func (r *cborReader) array() (uint64, error) {
+	major, n, err := r.head()
+	if err != nil {
+		return 0, err
+	}
+	if major != cborArray {
+		return 0, fmt.Errorf("%w: expected an array", ErrInvalidCBOR)
+	}
+	return n, nil
+}
+
+// centroids reads an array of [mean, weight] arrays into d.
+func (r *cborReader) centroids(d *binaryDecoder) error {
+	n, err := r.array()
+	if err != nil {
+		return err
+	}
+	// Every centroid takes at least three bytes, so do not trust larger
+	// counts with an allocation up front.
+	if max := uint64(len(r.data) / 3); n <= max {
+		d.centroids = make(CentroidList, 0, n)
+	}
+	for i := uint64(0); i < n; i++ {
+		if k, err := r.array(); err != nil {
+			return err
+		} else if k != 2 {
+			return fmt.Errorf("%w: centroid %d: expected [mean, weight]", ErrInvalidCBOR, i)
+		}
+		mean, err := r.number()
+		if err != nil {
+			return err
+		}
+		weight, err := r.number()
+		if err != nil {
+			return err
+		}
+		if err := d.add(mean, weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendCBORHead(buf []byte, major byte, arg uint64) []byte {
+	major <<= 5
+	switch {
+	case arg < 24:
+		return append(buf, major|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(buf, major|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return append(buf, major|25, byte(arg>>8), byte(arg))
+	case arg <= math.MaxUint32:
+		return appendUint32(append(buf, major|26), uint32(arg))
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], arg)
+	return append(append(buf, major|27), b[:]...)
+}
+
+func appendCBORText(buf []byte, s string) []byte {
+	return append(appendCBORHead(buf, cborText, uint64(len(s))), s...)
+}
+
+func appendCBORFloat(buf []byte, x float64) []byte {
+	return appendFloat64(append(buf, cborSimple<<5|cborFloat64), x)
+}
+
+// float16to64 converts an IEEE 754 half precision float.
+func float16to64(h uint16) float64 {
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+	var x float64
+	switch exp {
+	case 0:
+		x = math.Ldexp(mant, -24)
+	case 0x1f:
+		x = math.Inf(1)
+		if mant != 0 {
+			x = math.NaN()
+		}
+	default:
+		x = math.Ldexp(mant+0x400, exp-25)
+	}
+	if h&0x8000 != 0 {
+		x = -x
+	}
+	return x
+}
@@ -0,0 +1,122 @@
+package tdigest_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_MarshalCBOR(t *testing.T) {
+	single := tdigest.NewWithCompression(10)
+	single.Add(1.5, 2)
+	for _, td := range []*tdigest.TDigest{tdigest.NewWithCompression(100), single, NormalDigest} {
+		data, err := td.MarshalCBOR()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := tdigest.New()
+		if err := got.UnmarshalCBOR(data); err != nil {
+			t.Fatal(err)
+		}
+		if got.Compression != td.Compression || !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+			t.Error("digest differs after round trip")
+		}
+		if got.Quantile(0) != td.Quantile(0) && !math.IsNaN(td.Quantile(0)) {
+			t.Errorf("unexpected min, got %g want %g", got.Quantile(0), td.Quantile(0))
+		}
+	}
+
+	data, _ := single.MarshalCBOR()
+	want := "da74646967a4" +
+		"6b636f6d7072657373696f6e" + "fb4024000000000000" +
+		"636d696e" + "fb3ff8000000000000" +
+		"636d6178" + "fb3ff8000000000000" +
+		"6963656e74726f696473" + "81" + "82" + "fb3ff8000000000000" + "02"
+	if got := hex.EncodeToString(data); got != want {
+		t.Errorf("unexpected encoding\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestTdigest_UnmarshalCBOR(t *testing.T) {
+	// Untagged, reordered, with half and single precision floats and
+	// negative integers.
+	data := mustHex(t, "a4"+
+		"6963656e74726f696473"+"82"+"8220f93c00"+"82fa3fc0000001"+
+		"636d6178"+"f93e00"+
+		"636d696e"+"21"+
+		"6b636f6d7072657373696f6e"+"182a")
+	td := tdigest.New()
+	if err := td.UnmarshalCBOR(data); err != nil {
+		t.Fatal(err)
+	}
+	want := tdigest.CentroidList{{Mean: -1, Weight: 1}, {Mean: 1.5, Weight: 1}}
+	if td.Compression != 42 || !reflect.DeepEqual(td.Centroids(nil), want) {
+		t.Errorf("unexpected digest %g %v", td.Compression, td.Centroids(nil))
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "tag", data: "c1a0"},
+		{name: "not a map", data: "80"},
+		{name: "indefinite", data: "bf"},
+		{name: "truncated", data: "a16b636f6d7072657373696f6efb40"},
+		{name: "no compression", data: "a0"},
+		{name: "compression", data: "a16b636f6d7072657373696f6e00"},
+		{name: "unknown field", data: "a2" + "6b636f6d7072657373696f6e0a" + "6178" + "00"},
+		{name: "duplicate", data: "a2" + "6b636f6d7072657373696f6e0a" + "6b636f6d7072657373696f6e0a"},
+		{name: "centroid", data: "a2" + "6b636f6d7072657373696f6e0a" + "6963656e74726f696473" + "8181" + "01"},
+		{name: "weight", data: "a2" + "6b636f6d7072657373696f6e0a" + "6963656e74726f696473" + "81" + "820120"},
+		{name: "no min", data: "a2" + "6b636f6d7072657373696f6e0a" + "6963656e74726f696473" + "81" + "820101"},
+		{name: "count", data: "a2" + "6b636f6d7072657373696f6e0a" + "6963656e74726f696473" + "9b7fffffffffffffff"},
+		{name: "trailing", data: "a16b636f6d7072657373696f6e0a00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tdigest.NewWithCompression(10)
+			got.Add(42, 1)
+			err := got.UnmarshalCBOR(mustHex(t, tt.data))
+			if !errors.Is(err, tdigest.ErrInvalidCBOR) {
+				t.Errorf("unexpected error %v", err)
+			}
+			if got.Compression != 10 || got.Quantile(0.5) != 42 {
+				t.Error("digest was modified on error")
+			}
+		})
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func FuzzUnmarshalCBOR(f *testing.F) {
+	td := tdigest.NewWithCompression(10)
+	td.AddValues(1, 2, 2, 3, 10)
+	data, _ := td.MarshalCBOR()
+	f.Add(data)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		td := tdigest.New()
+		if err := td.UnmarshalCBOR(data); err != nil {
+			return
+		}
+		again, _ := td.MarshalCBOR()
+		got := tdigest.New()
+		if err := got.UnmarshalCBOR(again); err != nil {
+			t.Fatalf("marshaled digest does not unmarshal: %v", err)
+		}
+		if !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+			t.Error("centroids differ after round trip")
+		}
+	})
+}
@@ -2,12 +2,52 @@ package tdigest
 
 import (
 	"fmt"
-	"sort"
+	"math"
 )
 
 // ErrWeightLessThanZero is used when the weight is not able to be processed.
 const ErrWeightLessThanZero = Error("centroid weight cannot be less than zero")
 
+// ErrNaNValue is used when a sample with a NaN value is rejected.
+const ErrNaNValue = Error("value is NaN")
+
+// ErrNaNWeight is used when a sample with a NaN weight is rejected.
+const ErrNaNWeight = Error("weight is NaN")
+
+// ErrNonPositiveWeight is used when a sample with a zero or negative weight
+// is rejected.
+const ErrNonPositiveWeight = Error("weight must be positive")
+
+// ErrInfiniteWeight is used when a sample with an infinite weight is
+// rejected.
+const ErrInfiniteWeight = Error("weight is infinite")
+
+// ErrLengthMismatch is used when a batch of values and weights differ in
+// length.
+const ErrLengthMismatch = Error("values and weights have different lengths")
+
+// RejectedError reports how many samples of a batch were rejected as
+// invalid, while the rest of the batch was added.
+type RejectedError struct {
+	Rejected int
+	Total    int
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("rejected %d of %d samples", e.Rejected, e.Total)
+}
+
+// ErrInvalidScaleFactor is used when weights are scaled by a factor that is
+// not positive and finite.
+const ErrInvalidScaleFactor = Error("scale factor must be positive and finite")
+
+// ErrNotMonotonic is used when values mapped by a function expected to be
+// monotonic are out of order.
+const ErrNotMonotonic = Error("mapped values are not monotonic")
+
+// ErrMappedNaN is used when mapping values yields NaN.
+const ErrMappedNaN = Error("mapped value is NaN")
+
 // Error is a domain error encountered while processing tdigests
 type Error string
 
@@ -32,7 +72,11 @@ func (c *Centroid) Add(r Centroid) error {
 	}
 	if c.Weight != 0 {
 		c.Weight += r.Weight
-		c.Mean += r.Weight * (r.Mean - c.Mean) / c.Weight
+		// An infinite mean absorbs whatever is added to it, rather than
+		// turning into NaN.
+		if !math.IsInf(c.Mean, 0) {
+			c.Mean += r.Weight * (r.Mean - c.Mean) / c.Weight
+		}
 	} else {
 		c.Weight = r.Weight
 		c.Mean = r.Mean
@@ -52,9 +96,19 @@ func (l CentroidList) Len() int           { return len(l) }
 func (l CentroidList) Less(i, j int) bool { return l[i].Mean < l[j].Mean }
 func (l CentroidList) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
 
-// NewCentroidList creates a priority queue for the centroids
+// Weight returns the total weight of the centroids.
+func (l CentroidList) Weight() float64 {
+	var w float64
+	for _, c := range l {
+		w += c.Weight
+	}
+	return w
+}
+
+// NewCentroidList sorts the centroids by mean, in place, and returns them as
+// a CentroidList.
 func NewCentroidList(centroids []Centroid) CentroidList {
 	l := CentroidList(centroids)
-	sort.Sort(l)
+	sortCentroids(l)
 	return l
 }
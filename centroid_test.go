@@ -1,6 +1,8 @@
 package tdigest_test
 
 import (
+	"math"
+	"sort"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -54,6 +56,21 @@ func TestCentroid_Add(t *testing.T) {
 				Mean:   9.181818181818182,
 			},
 		},
+		{
+			name: "infinite mean",
+			c: tdigest.Centroid{
+				Weight: 1,
+				Mean:   math.Inf(1),
+			},
+			r: tdigest.Centroid{
+				Weight: 2,
+				Mean:   math.Inf(1),
+			},
+			want: tdigest.Centroid{
+				Weight: 3,
+				Mean:   math.Inf(1),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -120,3 +137,63 @@ func TestNewCentroidList(t *testing.T) {
 		})
 	}
 }
+
+func TestNewCentroidList_Sorted(t *testing.T) {
+	const n = 10000
+	tests := []struct {
+		name string
+		mean func(i int) float64
+	}{
+		{name: "random", mean: func(i int) float64 { return NormalData[i] }},
+		{name: "sorted", mean: func(i int) float64 { return float64(i) }},
+		{name: "reversed", mean: func(i int) float64 { return float64(n - i) }},
+		{name: "equal", mean: func(i int) float64 { return 1 }},
+		{name: "few distinct", mean: func(i int) float64 { return float64(i % 7) }},
+		{name: "organ pipe", mean: func(i int) float64 {
+			if i < n/2 {
+				return float64(i)
+			}
+			return float64(n - i)
+		}},
+		{name: "sawtooth", mean: func(i int) float64 { return float64(i % 100) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			centroids := make([]tdigest.Centroid, n)
+			var weight float64
+			for i := range centroids {
+				centroids[i] = tdigest.Centroid{Mean: tt.mean(i), Weight: float64(i + 1)}
+				weight += centroids[i].Weight
+			}
+			l := tdigest.NewCentroidList(centroids)
+			if !sort.IsSorted(l) {
+				t.Fatal("centroids are not sorted")
+			}
+			var got float64
+			for _, c := range l {
+				got += c.Weight
+			}
+			if got != weight {
+				t.Errorf("centroids were lost, got weight %g want %g", got, weight)
+			}
+		})
+	}
+}
+
+func TestCentroidList_Weight(t *testing.T) {
+	tests := []struct {
+		name string
+		l    tdigest.CentroidList
+		want float64
+	}{
+		{name: "empty", want: 0},
+		{name: "weights", l: tdigest.CentroidList{{Mean: 1, Weight: 2}, {Mean: 3, Weight: 0.5}}, want: 2.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.l.Weight(); got != tt.want {
+				t.Errorf("unexpected weight, got %g want %g", got, tt.want)
+			}
+		})
+	}
+}
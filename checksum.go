@@ -0,0 +1,69 @@
+package tdigest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// ErrChecksum is returned when decoding a binary encoding whose checksum
+// does not match its contents.
+const ErrChecksum = Error("tdigest binary checksum mismatch")
+
+// checksumSize is the size of the checksum ending the binary encoding.
+const checksumSize = 4
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// appendChecksum appends the checksum of buf[start:] to buf.
+func appendChecksum(buf []byte, start int) []byte {
+	return appendUint32(buf, crc32.Checksum(buf[start:], crcTable))
+}
+
+// verifyChecksum checks the checksum ending an encoding, before any of its
+// fields are decoded.
+func verifyChecksum(data []byte) error {
+	n := len(data) - checksumSize
+	if n < 0 {
+		return fmt.Errorf("%w: truncated", ErrInvalidBinary)
+	}
+	if crc32.Checksum(data[:n], crcTable) != binary.BigEndian.Uint32(data[n:]) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// checksumReader computes the checksum of the data read through it.
+type checksumReader struct {
+	r   binaryReader
+	crc uint32
+	b   [1]byte
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.crc = crc32.Update(r.crc, crcTable, p[:n])
+	return n, err
+}
+
+func (r *checksumReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.b[0] = b
+		r.crc = crc32.Update(r.crc, crcTable, r.b[:])
+	}
+	return b, err
+}
+
+// verify reads the checksum following the data read so far.
+func (r *checksumReader) verify() error {
+	var buf [checksumSize]byte
+	if _, err := io.ReadFull(r.r, buf[:]); err != nil {
+		return readError(err)
+	}
+	if binary.BigEndian.Uint32(buf[:]) != r.crc {
+		return ErrChecksum
+	}
+	return nil
+}
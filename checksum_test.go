@@ -0,0 +1,37 @@
+package tdigest_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_UnmarshalBinaryChecksum(t *testing.T) {
+	td := tdigest.NewWithCompression(10)
+	td.AddValues(1, 2, 2, 3, 10)
+	valid, _ := td.MarshalBinary()
+
+	// Flipping any bit is detected before the fields are decoded.
+	for i := 0; i < len(valid); i++ {
+		for bit := uint(0); bit < 8; bit++ {
+			data := append([]byte(nil), valid...)
+			data[i] ^= 1 << bit
+			got := tdigest.New()
+			if err := got.UnmarshalBinary(data); err != tdigest.ErrChecksum {
+				t.Errorf("byte %d bit %d: unexpected error %v", i, bit, err)
+			}
+			err := got.UnmarshalBinaryFrom(bytes.NewReader(data))
+			if err != tdigest.ErrChecksum && !errors.Is(err, tdigest.ErrInvalidBinary) {
+				t.Errorf("byte %d bit %d: unexpected streaming error %v", i, bit, err)
+			}
+		}
+	}
+
+	for _, data := range [][]byte{valid[:len(valid)-1], append(valid[:len(valid):len(valid)], 0), valid[:6]} {
+		if err := tdigest.New().UnmarshalBinary(data); err != tdigest.ErrChecksum {
+			t.Errorf("unexpected error for %d bytes: %v", len(data), err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package tdigest
+
+// Clone returns a deep copy of the digest, including its configuration and
+// any pending centroids, apart from the lock set by WithLocker and the
+// watches set by WatchQuantile. The digest t is left unchanged.
+func (t *TDigest) Clone() *TDigest {
+	t.lock()
+	defer t.unlock()
+	return t.clone()
+}
+
+func (t *TDigest) clone() *TDigest {
+	c := &TDigest{
+		maxProcessed:   t.maxProcessed,
+		maxUnprocessed: t.maxUnprocessed,
+	}
+	c.allocate()
+	t.cloneInto(c)
+	return c
+}
+
+// CloneInto makes dst a deep copy of the digest like Clone, reusing the
+// buffers of dst where they are large enough. This avoids allocating when
+// snapshotting many digests repeatedly into the same destinations. The
+// digest t is left unchanged, and dst keeps its own lock, if any, and its
+// own watches, see WatchQuantile. dst must not be in use by other
+// goroutines.
+func (t *TDigest) CloneInto(dst *TDigest) {
+	if dst == t {
+		return
+	}
+	t.lock()
+	defer t.unlock()
+	t.cloneInto(dst)
+}
+
+func (t *TDigest) cloneInto(dst *TDigest) {
+	dst.unshare()
+	processed, unprocessed, cumulative, cache, stats := dst.processed, dst.unprocessed, dst.cumulative, dst.cache, dst.stats
+	locker, watches := dst.locker, dst.watches
+	*dst = *t
+	dst.locker, dst.watches = locker, watches
+	dst.processed = append(processed[:0], t.processed...)
+	dst.unprocessed = append(unprocessed[:0], t.unprocessed...)
+	dst.cumulative = append(cumulative[:0], t.cumulative...)
+	dst.shared = false
+	dst.stats = nil
+	if t.stats != nil {
+		if stats == nil {
+			stats = new(atomicStats)
+		}
+		dst.stats = stats
+		dst.publishStats()
+	}
+	if t.cache != nil {
+		dst.cache = append(cache[:0], t.cache...)
+	}
+}
@@ -0,0 +1,54 @@
+package tdigest_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_Clone(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddSlice(NormalData[:10000])
+	// Leave some centroids pending.
+	td.AddValues(1, 2, 3)
+
+	c := td.Clone()
+	if c.Compression != td.Compression {
+		t.Errorf("unexpected compression, got %g want %g", c.Compression, td.Compression)
+	}
+	if err := compareQuantiles(c, td, 0); err != nil {
+		t.Errorf("clone differs: %s", err.Error())
+	}
+	if !reflect.DeepEqual(c.Centroids(nil), td.Centroids(nil)) {
+		t.Error("clone centroids differ")
+	}
+
+	c.Add(1000, 1)
+	if c.Count() == td.Count() {
+		t.Error("adding to the clone modified the original")
+	}
+}
+
+func TestTdigest_CloneInto(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddSlice(NormalData[:10000])
+	td.Flush()
+
+	dst := tdigest.New()
+	dst.AddSlice(UniformData[:1000])
+	td.CloneInto(dst)
+	if dst.Compression != td.Compression {
+		t.Errorf("unexpected compression, got %g want %g", dst.Compression, td.Compression)
+	}
+	if err := compareQuantiles(dst, td, 0); err != nil {
+		t.Errorf("clone differs: %s", err.Error())
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		td.CloneInto(dst)
+	})
+	if allocs != 0 {
+		t.Errorf("unexpected allocations, got %g want 0", allocs)
+	}
+}
@@ -0,0 +1,89 @@
+// Command tdigest-qq emits quantile-quantile plot data for two digests.
+//
+// The digests are read from files in the text encoding produced by
+// tdigest.FormatText. For each of n evenly spaced quantiles the quantile of
+// both digests is written, as CSV or JSON, ready for plotting e.g. a canary
+// against a baseline distribution.
+//
+// Usage:
+//
+//	tdigest-qq [-n 99] [-format csv|json] baseline.txt canary.txt
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/influxdata/tdigest"
+)
+
+type point struct {
+	Q float64 `json:"q"`
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func main() {
+	n := flag.Int("n", 99, "number of quantiles to emit")
+	format := flag.String("format", "csv", "output format, csv or json")
+	flag.Parse()
+	if flag.NArg() != 2 || *n < 1 {
+		fmt.Fprintln(os.Stderr, "usage: tdigest-qq [-n 99] [-format csv|json] x.txt y.txt")
+		os.Exit(2)
+	}
+
+	x := loadDigest(flag.Arg(0))
+	y := loadDigest(flag.Arg(1))
+	points := make([]point, *n)
+	for i := range points {
+		q := float64(i+1) / float64(*n+1)
+		points[i] = point{Q: q, X: x.Quantile(q), Y: y.Quantile(q)}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	switch *format {
+	case "csv":
+		writeCSV(w, points)
+	case "json":
+		if err := json.NewEncoder(w).Encode(points); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func loadDigest(name string) *tdigest.TDigest {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	td, err := tdigest.ParseText(string(b))
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+	return td
+}
+
+func writeCSV(w *bufio.Writer, points []point) {
+	w.WriteString("q,x,y\n")
+	buf := make([]byte, 0, 64)
+	for _, p := range points {
+		buf = strconv.AppendFloat(buf[:0], p.Q, 'g', -1, 64)
+		buf = append(buf, ',')
+		buf = strconv.AppendFloat(buf, p.X, 'g', -1, 64)
+		buf = append(buf, ',')
+		buf = strconv.AppendFloat(buf, p.Y, 'g', -1, 64)
+		buf = append(buf, '\n')
+		w.Write(buf)
+	}
+}
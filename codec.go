@@ -0,0 +1,106 @@
+package tdigest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownCodec is returned when decoding an encoding compressed with a
+// codec that has not been registered.
+const ErrUnknownCodec = Error("unknown tdigest codec")
+
+// ErrInvalidCodecName is returned when compressing with a codec whose name
+// is empty or longer than 255 bytes.
+const ErrInvalidCodecName = Error("codec name must be 1 to 255 bytes")
+
+// Codec compresses binary encodings of digests. Implementations typically
+// wrap a general purpose compressor such as snappy or zstd.
+type Codec interface {
+	// Name identifies the codec in compressed encodings. It must be
+	// non-empty, at most 255 bytes, and never change.
+	Name() string
+	// Compress appends the compressed src to dst.
+	Compress(dst, src []byte) ([]byte, error)
+	// Decompress appends the decompressed src to dst.
+	Decompress(dst, src []byte) ([]byte, error)
+}
+
+// compressedMagic starts compressed encodings. Uncompressed encodings start
+// with a zero byte.
+const compressedMagic = "TDZ"
+
+// maxDecompressedPrealloc caps the buffer allocated for decompressing before
+// the codec has checked the data.
+const maxDecompressedPrealloc = 1 << 20
+
+var codecs = struct {
+	sync.RWMutex
+	m map[string]Codec
+}{m: make(map[string]Codec)}
+
+// RegisterCodec makes a codec available to UnmarshalBinary for decoding
+// compressed encodings, replacing any codec registered under the same name.
+func RegisterCodec(c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.m[c.Name()] = c
+}
+
+func lookupCodec(name string) Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.m[name]
+}
+
+// MarshalBinaryCompressed encodes the digest like MarshalBinary, compressed
+// with c. The encoding starts with "TDZ", the length of the name of the
+// codec as a byte, the name, and the length of the uncompressed encoding as a
+// uvarint, followed by the compressed encoding. UnmarshalBinary decodes it
+// once c has been registered with RegisterCodec.
+func (t *TDigest) MarshalBinaryCompressed(c Codec) ([]byte, error) {
+	name := c.Name()
+	if name == "" || len(name) > 255 {
+		return nil, ErrInvalidCodecName
+	}
+	data, err := t.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf := append([]byte(compressedMagic), byte(len(name)))
+	buf = append(buf, name...)
+	buf = appendUvarint(buf, uint64(len(data)))
+	return c.Compress(buf, data)
+}
+
+// decompressBinary returns the uncompressed encoding of data, which starts
+// with compressedMagic.
+func decompressBinary(data []byte) ([]byte, error) {
+	data = data[len(compressedMagic):]
+	if len(data) == 0 || len(data) < 1+int(data[0]) {
+		return nil, fmt.Errorf("%w: truncated", ErrInvalidBinary)
+	}
+	name := string(data[1 : 1+data[0]])
+	data = data[1+len(name):]
+	size, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: invalid length", ErrInvalidBinary)
+	}
+	c := lookupCodec(name)
+	if c == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCodec, name)
+	}
+
+	prealloc := size
+	if prealloc > maxDecompressedPrealloc {
+		prealloc = maxDecompressedPrealloc
+	}
+	out, err := c.Decompress(make([]byte, 0, prealloc), data[n:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBinary, name, err)
+	}
+	if uint64(len(out)) != size {
+		return nil, fmt.Errorf("%w: %s: decompressed %d bytes, want %d", ErrInvalidBinary, name, len(out), size)
+	}
+	return out, nil
+}
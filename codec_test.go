@@ -0,0 +1,93 @@
+package tdigest_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+// flateCodec compresses with DEFLATE.
+type flateCodec struct{ name string }
+
+func (c flateCodec) Name() string { return c.name }
+
+func (c flateCodec) Compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	w, err := flate.NewWriter(buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c flateCodec) Decompress(dst, src []byte) ([]byte, error) {
+	data, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(src)))
+	return append(dst, data...), err
+}
+
+func TestTdigest_MarshalBinaryCompressed(t *testing.T) {
+	codec := flateCodec{name: "flate"}
+	tdigest.RegisterCodec(codec)
+
+	data, err := NormalDigest.MarshalBinaryCompressed(codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := NormalDigest.MarshalBinary()
+	if len(data) >= len(plain) {
+		t.Errorf("compressed encoding is not smaller, got %d want below %d", len(data), len(plain))
+	}
+	got := tdigest.New()
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.Compression != NormalDigest.Compression || !reflect.DeepEqual(got.Centroids(nil), NormalDigest.Centroids(nil)) {
+		t.Error("digest differs after round trip")
+	}
+
+	// The length of a small encoding follows "TDZ\x05flate" in one byte.
+	wrongLength := mustCompress(t, codec)
+	wrongLength[9]--
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{name: "unknown codec", data: mustCompress(t, flateCodec{name: "unregistered"}), want: tdigest.ErrUnknownCodec},
+		{name: "truncated name", data: data[:6], want: tdigest.ErrInvalidBinary},
+		{name: "truncated", data: data[:len(data)-10], want: tdigest.ErrInvalidBinary},
+		{name: "length", data: wrongLength, want: tdigest.ErrInvalidBinary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tdigest.New().UnmarshalBinary(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("unexpected error %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := NormalDigest.MarshalBinaryCompressed(flateCodec{}); err != tdigest.ErrInvalidCodecName {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func mustCompress(t *testing.T, c tdigest.Codec) []byte {
+	td := tdigest.NewWithCompression(10)
+	td.AddValues(1, 2, 3)
+	data, err := td.MarshalBinaryCompressed(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,85 @@
+package tdigest
+
+import "sync"
+
+// Collector owns a digest and adds samples to it on a dedicated goroutine,
+// so that producers only pay for a channel send. Samples are applied in the
+// order they are added. A Collector must be closed to stop its goroutine.
+type Collector struct {
+	ch        chan collectorMsg
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// collectorMsg is either a sample, or a function to run on the digest.
+type collectorMsg struct {
+	c Centroid
+	f func(*TDigest)
+}
+
+// NewCollector starts a collector adding samples to t, which must not be
+// used elsewhere until the collector is closed. Up to buffer samples are
+// queued before Add blocks.
+func NewCollector(t *TDigest, buffer int) *Collector {
+	c := &Collector{
+		ch:   make(chan collectorMsg, buffer),
+		done: make(chan struct{}),
+	}
+	go c.run(t)
+	return c
+}
+
+func (c *Collector) run(t *TDigest) {
+	defer close(c.done)
+	for m := range c.ch {
+		if m.f != nil {
+			m.f(t)
+			continue
+		}
+		t.AddCentroid(m.c)
+	}
+}
+
+// Add queues a value x with a weight w to be added to the digest. It blocks
+// while the queue is full, and must not be called after Close.
+func (c *Collector) Add(x, w float64) {
+	c.ch <- collectorMsg{c: Centroid{Mean: x, Weight: w}}
+}
+
+// Do runs f on the digest once all samples queued before the call have been
+// added, and waits for it to return. f must not retain the digest.
+func (c *Collector) Do(f func(t *TDigest)) {
+	done := make(chan struct{})
+	c.ch <- collectorMsg{f: func(t *TDigest) {
+		f(t)
+		close(done)
+	}}
+	<-done
+}
+
+// Snapshot returns an immutable snapshot of the digest, including all
+// samples queued before the call. In strict mode, it returns ErrUnflushed
+// while the digest has pending centroids, see TDigest.Flush.
+func (c *Collector) Snapshot() (*FrozenDigest, error) {
+	var f *FrozenDigest
+	var err error
+	c.Do(func(t *TDigest) {
+		f, err = t.Freeze()
+	})
+	return f, err
+}
+
+// Flush waits until all samples queued before the call have been added.
+func (c *Collector) Flush() {
+	c.Do(func(*TDigest) {})
+}
+
+// Close stops accepting samples, waits until all queued samples have been
+// added, and stops the collector goroutine. The digest may be used directly
+// once Close returns. Close may be called more than once.
+func (c *Collector) Close() {
+	c.closeOnce.Do(func() {
+		close(c.ch)
+	})
+	<-c.done
+}
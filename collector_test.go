@@ -0,0 +1,51 @@
+package tdigest_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestCollector(t *testing.T) {
+	td := tdigest.NewWithCompression(1000)
+	c := tdigest.NewCollector(td, 1024)
+
+	const producers = 4
+	var wg sync.WaitGroup
+	n := len(NormalData) / producers
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func(xs []float64) {
+			defer wg.Done()
+			for _, x := range xs {
+				c.Add(x, 1)
+			}
+		}(NormalData[i*n : (i+1)*n])
+	}
+	wg.Wait()
+
+	snap, err := c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := snap.Count(), float64(len(NormalData)); got != want {
+		t.Errorf("unexpected snapshot count, got %g want %g", got, want)
+	}
+
+	c.Add(1000, 1)
+	c.Flush()
+	var count float64
+	c.Do(func(td *tdigest.TDigest) {
+		count = td.Count()
+	})
+	if want := float64(len(NormalData) + 1); count != want {
+		t.Errorf("unexpected count, got %g want %g", count, want)
+	}
+
+	c.Close()
+	c.Close()
+	if got := td.Quantile(1); got != 1000 {
+		t.Errorf("unexpected max, got %g want 1000", got)
+	}
+}
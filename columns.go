@@ -0,0 +1,85 @@
+package tdigest
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ErrInvalidColumns is returned when building a digest from malformed
+// columns.
+const ErrInvalidColumns = Error("invalid tdigest columns")
+
+// Keys of the metadata returned by Columns.
+const (
+	ColumnsCompressionKey = "tdigest.compression"
+	ColumnsMinKey         = "tdigest.min"
+	ColumnsMaxKey         = "tdigest.max"
+)
+
+// Columns returns the means and weights of the centroids as two columns,
+// and the compression, min and max as metadata, processing any pending
+// centroids first. The columns map directly onto float64 arrays and the
+// metadata onto field or schema metadata of columnar formats such as Apache
+// Arrow and Parquet. The min and max are omitted for an empty digest. In
+// strict mode it returns ErrUnflushed if the digest has pending centroids.
+func (t *TDigest) Columns() (means, weights []float64, metadata map[string]string, err error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	n := t.processed.Len()
+	means = make([]float64, n)
+	weights = make([]float64, n)
+	for i, c := range t.processed {
+		means[i] = c.Mean
+		weights[i] = c.Weight
+	}
+	metadata = map[string]string{ColumnsCompressionKey: formatTextFloat(t.Compression)}
+	if n > 0 {
+		metadata[ColumnsMinKey] = formatTextFloat(t.min)
+		metadata[ColumnsMaxKey] = formatTextFloat(t.max)
+	}
+	return means, weights, metadata, nil
+}
+
+// FromColumns builds a digest from the columns and metadata returned by
+// Columns, applying the given options. It is as strict as ParseText.
+func FromColumns(means, weights []float64, metadata map[string]string, opts ...Option) (*TDigest, error) {
+	if len(means) != len(weights) {
+		return nil, fmt.Errorf("%w: %d means but %d weights", ErrInvalidColumns, len(means), len(weights))
+	}
+	h := binaryHeader{min: math.NaN(), max: math.NaN()}
+	fields := []struct {
+		key string
+		x   *float64
+	}{{ColumnsCompressionKey, &h.compression}, {ColumnsMinKey, &h.min}, {ColumnsMaxKey, &h.max}}
+	for _, f := range fields {
+		s, ok := metadata[f.key]
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid %s %q", ErrInvalidColumns, f.key, s)
+		}
+		*f.x = v
+	}
+	if !(h.compression >= 1 && h.compression <= maxDecodeCompression) {
+		return nil, fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidColumns, float64(maxDecodeCompression))
+	}
+
+	d := &binaryDecoder{centroids: make(CentroidList, 0, len(means)), invalid: ErrInvalidColumns}
+	for i, mean := range means {
+		if err := d.add(mean, weights[i]); err != nil {
+			return nil, err
+		}
+	}
+	t := NewWithCompression(h.compression, opts...)
+	if err := t.setDecoded(h, d); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,68 @@
+package tdigest_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_Columns(t *testing.T) {
+	for _, td := range []*tdigest.TDigest{tdigest.NewWithCompression(100), NormalDigest} {
+		means, weights, metadata, err := td.Columns()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := tdigest.FromColumns(means, weights, metadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Compression != td.Compression || !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+			t.Error("digest differs after round trip")
+		}
+		if got.Count() > 0 && (got.Quantile(0) != td.Quantile(0) || got.Quantile(1) != td.Quantile(1)) {
+			t.Error("min or max differs after round trip")
+		}
+	}
+
+	means, _, metadata, _ := NormalDigest.Columns()
+	means[0] = 42
+	if NormalDigest.Centroids(nil)[0].Mean == 42 {
+		t.Error("columns share memory with the digest")
+	}
+	if want := "1000"; metadata[tdigest.ColumnsCompressionKey] != want {
+		t.Errorf("unexpected compression metadata, got %q want %q", metadata[tdigest.ColumnsCompressionKey], want)
+	}
+}
+
+func TestFromColumnsErrors(t *testing.T) {
+	meta := func(kv ...string) map[string]string {
+		m := make(map[string]string)
+		for i := 0; i < len(kv); i += 2 {
+			m[kv[i]] = kv[i+1]
+		}
+		return m
+	}
+	valid := meta(tdigest.ColumnsCompressionKey, "10", tdigest.ColumnsMinKey, "1", tdigest.ColumnsMaxKey, "3")
+	tests := []struct {
+		name     string
+		means    []float64
+		weights  []float64
+		metadata map[string]string
+	}{
+		{name: "lengths", means: []float64{1, 2}, weights: []float64{1}, metadata: valid},
+		{name: "no compression", metadata: meta()},
+		{name: "compression", metadata: meta(tdigest.ColumnsCompressionKey, "ten")},
+		{name: "unsorted", means: []float64{2, 1}, weights: []float64{1, 1}, metadata: valid},
+		{name: "weight", means: []float64{1}, weights: []float64{0}, metadata: valid},
+		{name: "no min", means: []float64{1}, weights: []float64{1}, metadata: meta(tdigest.ColumnsCompressionKey, "10")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tdigest.FromColumns(tt.means, tt.weights, tt.metadata); !errors.Is(err, tdigest.ErrInvalidColumns) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
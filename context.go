@@ -0,0 +1,46 @@
+package tdigest
+
+import "context"
+
+// contextCheckInterval is the number of values added between checks of the
+// context in AddAllContext.
+const contextCheckInterval = 1 << 12
+
+// AddAllContext adds each of the values xs with a weight of one, like
+// AddSlice, checking ctx between chunks of values. It returns the number of
+// values consumed from xs, and the error of ctx if it was done before all
+// values were added.
+func (t *TDigest) AddAllContext(ctx context.Context, xs []float64) (int, error) {
+	n := 0
+	for n < len(xs) {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		end := n + contextCheckInterval
+		if end > len(xs) {
+			end = len(xs)
+		}
+		t.AddSlice(xs[n:end])
+		n = end
+	}
+	return n, nil
+}
+
+// AddStreamContext adds each value received from ch with a weight of one,
+// until ch is closed or ctx is done. It returns the number of values received,
+// and the error of ctx if it was done before ch was closed.
+func (t *TDigest) AddStreamContext(ctx context.Context, ch <-chan float64) (int, error) {
+	n := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		case x, ok := <-ch:
+			if !ok {
+				return n, nil
+			}
+			t.Add(x, 1)
+			n++
+		}
+	}
+}
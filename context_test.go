@@ -0,0 +1,62 @@
+package tdigest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_AddAllContext(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	n, err := td.AddAllContext(context.Background(), NormalData[:100000])
+	if err != nil || n != 100000 {
+		t.Errorf("unexpected result, got %d, %v want 100000, nil", n, err)
+	}
+	want := tdigest.NewWithCompression(100)
+	want.AddSlice(NormalData[:100000])
+	if err := compareQuantiles(td, want, 0.001); err != nil {
+		t.Errorf("digest differs: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	td.Reset()
+	n, err = td.AddAllContext(ctx, NormalData[:100000])
+	if err != context.Canceled || n != 0 {
+		t.Errorf("unexpected result, got %d, %v want 0, %v", n, err, context.Canceled)
+	}
+	if got := td.Count(); got != 0 {
+		t.Errorf("unexpected count, got %g want 0", got)
+	}
+}
+
+func TestTdigest_AddStreamContext(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	ch := make(chan float64)
+	go func() {
+		for _, x := range NormalData[:1000] {
+			ch <- x
+		}
+		close(ch)
+	}()
+	n, err := td.AddStreamContext(context.Background(), ch)
+	if err != nil || n != 1000 {
+		t.Errorf("unexpected result, got %d, %v want 1000, nil", n, err)
+	}
+	if got := td.Count(); got != 1000 {
+		t.Errorf("unexpected count, got %g want 1000", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch = make(chan float64)
+	go func() {
+		ch <- 1
+		ch <- 2
+		cancel()
+	}()
+	n, err = td.AddStreamContext(ctx, ch)
+	if err != context.Canceled || n != 2 {
+		t.Errorf("unexpected result, got %d, %v want 2, %v", n, err, context.Canceled)
+	}
+}
@@ -0,0 +1,129 @@
+package tdigest
+
+import (
+	"fmt"
+	"math"
+)
+
+// ErrInvalidDDSketch is returned when converting a malformed DDSketch.
+const ErrInvalidDDSketch = Error("invalid DDSketch")
+
+// DDSketch holds the bucket counts of a DDSketch with a logarithmic index
+// mapping, as used by DataDog/sketches-go. A positive value x falls into the
+// bucket with index floor(log(x) / log(gamma)), where gamma is
+// (1 + RelativeAccuracy) / (1 - RelativeAccuracy), and negative values into
+// the bucket of their absolute value in Negative. The counts can be added to
+// the stores of a sketches-go sketch, or read from them, index by index.
+type DDSketch struct {
+	RelativeAccuracy float64
+	Positive         map[int]float64
+	Negative         map[int]float64
+	Zero             float64
+}
+
+// ddMapping is the logarithmic index mapping of a DDSketch.
+type ddMapping struct {
+	relativeAccuracy, multiplier float64
+}
+
+func newDDMapping(relativeAccuracy float64) (ddMapping, error) {
+	if !(relativeAccuracy > 0 && relativeAccuracy < 1) {
+		return ddMapping{}, fmt.Errorf("%w: relative accuracy must be between 0 and 1", ErrInvalidDDSketch)
+	}
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return ddMapping{relativeAccuracy: relativeAccuracy, multiplier: 1 / math.Log(gamma)}, nil
+}
+
+func (m ddMapping) index(x float64) int {
+	return int(math.Floor(math.Log(x) * m.multiplier))
+}
+
+// value returns the value of the bucket with relative error at most the
+// relative accuracy for all values falling into it.
+func (m ddMapping) value(index int) float64 {
+	return math.Exp(float64(index)/m.multiplier) * (1 + m.relativeAccuracy)
+}
+
+// ToDDSketch converts the digest into a DDSketch with the given relative
+// accuracy, processing any pending centroids first. The whole weight of
+// every centroid goes into the bucket of its mean, so quantiles of the
+// sketch are off by at most the relative accuracy in value, on top of the
+// rank error of the digest, which grows with the weight of the centroids.
+// Infinite means cannot be bucketed and are reported as ErrInvalidDDSketch.
+func (t *TDigest) ToDDSketch(relativeAccuracy float64) (*DDSketch, error) {
+	m, err := newDDMapping(relativeAccuracy)
+	if err != nil {
+		return nil, err
+	}
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, err
+	}
+
+	s := &DDSketch{
+		RelativeAccuracy: relativeAccuracy,
+		Positive:         make(map[int]float64),
+		Negative:         make(map[int]float64),
+	}
+	for i, c := range t.processed {
+		switch {
+		case math.IsInf(c.Mean, 0):
+			return nil, fmt.Errorf("%w: centroid %d: infinite mean", ErrInvalidDDSketch, i)
+		case c.Mean > 0:
+			s.Positive[m.index(c.Mean)] += c.Weight
+		case c.Mean < 0:
+			s.Negative[m.index(-c.Mean)] += c.Weight
+		default:
+			s.Zero += c.Weight
+		}
+	}
+	return s, nil
+}
+
+// FromDDSketch converts a DDSketch into a new digest with the given
+// compression and options. Every non-empty bucket becomes a centroid at the
+// value of the bucket, which is within the relative accuracy of all the
+// values that fell into it. Counts must be finite and not negative, and the
+// compression between 1 and 100000.
+func FromDDSketch(s *DDSketch, compression float64, opts ...Option) (*TDigest, error) {
+	if !(compression >= 1 && compression <= maxDecodeCompression) {
+		return nil, fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidDDSketch, float64(maxDecodeCompression))
+	}
+	m, err := newDDMapping(s.RelativeAccuracy)
+	if err != nil {
+		return nil, err
+	}
+	if !(s.Zero >= 0) || math.IsInf(s.Zero, 1) {
+		return nil, fmt.Errorf("%w: invalid zero count %g", ErrInvalidDDSketch, s.Zero)
+	}
+	cl := make(CentroidList, 0, len(s.Negative)+1+len(s.Positive))
+	if cl, err = m.appendBuckets(cl, s.Negative, -1); err != nil {
+		return nil, err
+	}
+	if s.Zero > 0 {
+		cl = append(cl, Centroid{Mean: 0, Weight: s.Zero})
+	}
+	if cl, err = m.appendBuckets(cl, s.Positive, 1); err != nil {
+		return nil, err
+	}
+	sortCentroids(cl)
+
+	t := NewWithCompression(compression, opts...)
+	t.AddCentroidList(cl)
+	return t, nil
+}
+
+// appendBuckets appends a centroid for every non-empty bucket to cl, with
+// the sign of the values of the buckets.
+func (m ddMapping) appendBuckets(cl CentroidList, counts map[int]float64, sign float64) (CentroidList, error) {
+	for index, count := range counts {
+		if !(count >= 0) || math.IsInf(count, 1) {
+			return nil, fmt.Errorf("%w: bucket %d: invalid count %g", ErrInvalidDDSketch, index, count)
+		}
+		if count > 0 {
+			cl = append(cl, Centroid{Mean: sign * m.value(index), Weight: count})
+		}
+	}
+	return cl, nil
+}
@@ -0,0 +1,79 @@
+package tdigest_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_ToDDSketch(t *testing.T) {
+	td := tdigest.NewWithCompression(1000)
+	td.AddSlice(NormalData[:100000])
+	const accuracy = 0.01
+	s, err := td.ToDDSketch(accuracy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := s.Zero
+	for _, c := range s.Positive {
+		total += c
+	}
+	for _, c := range s.Negative {
+		total += c
+	}
+	if total != td.Count() {
+		t.Errorf("unexpected total count, got %g want %g", total, td.Count())
+	}
+
+	got, err := tdigest.FromDDSketch(s, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []float64{0.01, 0.1, 0.5, 0.9, 0.99} {
+		want := td.Quantile(q)
+		if x := got.Quantile(q); math.Abs(x-want) > 2*accuracy*math.Abs(want)+0.05 {
+			t.Errorf("quantile %g: got %g want %g", q, x, want)
+		}
+	}
+
+	neg := tdigest.NewWithCompression(100)
+	neg.AddValues(-8, -1, 0, 2)
+	s, _ = neg.ToDDSketch(accuracy)
+	if s.Zero != 1 || len(s.Negative) != 2 || len(s.Positive) != 1 {
+		t.Errorf("unexpected buckets, zero %g, %d positive, %d negative", s.Zero, len(s.Positive), len(s.Negative))
+	}
+	got, _ = tdigest.FromDDSketch(s, 100)
+	for q, want := range map[float64]float64{0: -8, 1: 2} {
+		if x := got.Quantile(q); math.Abs(x-want) > accuracy*math.Abs(want) {
+			t.Errorf("quantile %g: got %g want %g", q, x, want)
+		}
+	}
+}
+
+func TestDDSketchErrors(t *testing.T) {
+	inf := tdigest.NewWithCompression(100)
+	inf.AddValues(1, math.Inf(1))
+	if _, err := inf.ToDDSketch(0.01); !errors.Is(err, tdigest.ErrInvalidDDSketch) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := tdigest.New().ToDDSketch(1); !errors.Is(err, tdigest.ErrInvalidDDSketch) {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, s := range []*tdigest.DDSketch{
+		{RelativeAccuracy: 0},
+		{RelativeAccuracy: 0.01, Zero: -1},
+		{RelativeAccuracy: 0.01, Positive: map[int]float64{3: math.NaN()}},
+		{RelativeAccuracy: 0.01, Negative: map[int]float64{3: math.Inf(1)}},
+	} {
+		if _, err := tdigest.FromDDSketch(s, 100); !errors.Is(err, tdigest.ErrInvalidDDSketch) {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	for _, compression := range []float64{0, math.NaN(), 1e6} {
+		if _, err := tdigest.FromDDSketch(&tdigest.DDSketch{RelativeAccuracy: 0.01}, compression); !errors.Is(err, tdigest.ErrInvalidDDSketch) {
+			t.Errorf("compression %g: unexpected error %v", compression, err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package tdigest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Protobuf field numbers of the DDSketch, IndexMapping and Store messages of
+// DataDog/sketches-go.
+const (
+	ddProtoMapping        = 1
+	ddProtoPositiveValues = 2
+	ddProtoNegativeValues = 3
+	ddProtoZeroCount      = 4
+
+	ddProtoGamma         = 1
+	ddProtoIndexOffset   = 2
+	ddProtoInterpolation = 3
+
+	ddProtoBinCounts                = 1
+	ddProtoContiguousBinCounts      = 2
+	ddProtoContiguousBinIndexOffset = 3
+)
+
+// MarshalProto encodes the sketch as the DDSketch protobuf message of
+// DataDog/sketches-go, with a logarithmic index mapping without
+// interpolation or index offset, and the bucket counts as bin count maps.
+func (s *DDSketch) MarshalProto() ([]byte, error) {
+	if _, err := newDDMapping(s.RelativeAccuracy); err != nil {
+		return nil, err
+	}
+	gamma := (1 + s.RelativeAccuracy) / (1 - s.RelativeAccuracy)
+	mapping := appendProtoDouble(nil, ddProtoGamma, gamma)
+
+	buf := appendProtoBytes(nil, ddProtoMapping, mapping)
+	for _, store := range []struct {
+		field  uint64
+		counts map[int]float64
+	}{{ddProtoPositiveValues, s.Positive}, {ddProtoNegativeValues, s.Negative}} {
+		if len(store.counts) == 0 {
+			continue
+		}
+		indexes := make([]int, 0, len(store.counts))
+		for i := range store.counts {
+			if i < math.MinInt32 || i > math.MaxInt32 {
+				return nil, fmt.Errorf("%w: bucket index %d out of range", ErrInvalidDDSketch, i)
+			}
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		var msg, entry []byte
+		for _, i := range indexes {
+			entry = appendUvarint(entry[:0], 1<<3|protoVarint)
+			entry = appendUvarint(entry, uint64(uint32(int32(i)<<1^int32(i)>>31)))
+			entry = appendProtoDouble(entry, 2, store.counts[i])
+			msg = appendProtoBytes(msg, ddProtoBinCounts, entry)
+		}
+		buf = appendProtoBytes(buf, store.field, msg)
+	}
+	if s.Zero != 0 {
+		buf = appendProtoDouble(buf, ddProtoZeroCount, s.Zero)
+	}
+	return buf, nil
+}
+
+// UnmarshalProto decodes a DDSketch protobuf message of
+// DataDog/sketches-go into the sketch. Only logarithmic index mappings
+// without interpolation or index offset, the default of sketches-go, are
+// supported. Malformed protobuf is reported as ErrInvalidProto, and other
+// errors as ErrInvalidDDSketch.
+func (s *DDSketch) UnmarshalProto(data []byte) error {
+	var gamma, offset float64
+	var interpolation uint64
+	d := DDSketch{Positive: make(map[int]float64), Negative: make(map[int]float64)}
+	for len(data) > 0 {
+		var field, wire uint64
+		var msg []byte
+		var err error
+		if field, wire, data, err = readProtoKey(data); err != nil {
+			return err
+		}
+		switch {
+		case field == ddProtoMapping && wire == protoBytes:
+			if msg, data, err = readProtoBytes(data); err == nil {
+				gamma, offset, interpolation, err = readDDProtoMapping(msg)
+			}
+		case field == ddProtoPositiveValues && wire == protoBytes:
+			if msg, data, err = readProtoBytes(data); err == nil {
+				err = readDDProtoStore(msg, d.Positive)
+			}
+		case field == ddProtoNegativeValues && wire == protoBytes:
+			if msg, data, err = readProtoBytes(data); err == nil {
+				err = readDDProtoStore(msg, d.Negative)
+			}
+		case field == ddProtoZeroCount && wire == protoFixed64:
+			d.Zero, data, err = readProtoDouble(data)
+		default:
+			data, err = skipProtoField(data, wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if offset != 0 || interpolation != 0 {
+		return fmt.Errorf("%w: unsupported index mapping", ErrInvalidDDSketch)
+	}
+	if !(gamma > 1) || math.IsInf(gamma, 1) {
+		return fmt.Errorf("%w: invalid gamma %g", ErrInvalidDDSketch, gamma)
+	}
+	d.RelativeAccuracy = (gamma - 1) / (gamma + 1)
+	*s = d
+	return nil
+}
+
+func readDDProtoMapping(data []byte) (gamma, offset float64, interpolation uint64, err error) {
+	for len(data) > 0 {
+		var field, wire uint64
+		if field, wire, data, err = readProtoKey(data); err != nil {
+			return 0, 0, 0, err
+		}
+		switch {
+		case field == ddProtoGamma && wire == protoFixed64:
+			gamma, data, err = readProtoDouble(data)
+		case field == ddProtoIndexOffset && wire == protoFixed64:
+			offset, data, err = readProtoDouble(data)
+		case field == ddProtoInterpolation && wire == protoVarint:
+			interpolation, data, err = readProtoVarint(data)
+		default:
+			data, err = skipProtoField(data, wire)
+		}
+		if err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	return gamma, offset, interpolation, nil
+}
+
+// readDDProtoStore adds the bin counts of a Store message to counts.
+func readDDProtoStore(data []byte, counts map[int]float64) error {
+	var contiguous []float64
+	var contiguousOffset int32
+	for len(data) > 0 {
+		var field, wire, v uint64
+		var entry []byte
+		var err error
+		if field, wire, data, err = readProtoKey(data); err != nil {
+			return err
+		}
+		switch {
+		case field == ddProtoBinCounts && wire == protoBytes:
+			if entry, data, err = readProtoBytes(data); err == nil {
+				err = readDDProtoBinCount(entry, counts)
+			}
+		case field == ddProtoContiguousBinCounts && (wire == protoFixed64 || wire == protoBytes):
+			contiguous, data, err = readProtoDoubles(contiguous, data, wire)
+		case field == ddProtoContiguousBinIndexOffset && wire == protoVarint:
+			v, data, err = readProtoVarint(data)
+			contiguousOffset = unzigzag32(v)
+		default:
+			data, err = skipProtoField(data, wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for i, count := range contiguous {
+		counts[int(contiguousOffset)+i] += count
+	}
+	return nil
+}
+
+// readDDProtoBinCount adds a map entry of bin counts to counts.
+func readDDProtoBinCount(data []byte, counts map[int]float64) error {
+	var index int32
+	var count float64
+	for len(data) > 0 {
+		var field, wire, v uint64
+		var err error
+		if field, wire, data, err = readProtoKey(data); err != nil {
+			return err
+		}
+		switch {
+		case field == 1 && wire == protoVarint:
+			v, data, err = readProtoVarint(data)
+			index = unzigzag32(v)
+		case field == 2 && wire == protoFixed64:
+			count, data, err = readProtoDouble(data)
+		default:
+			data, err = skipProtoField(data, wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	counts[int(index)] += count
+	return nil
+}
+
+func readProtoVarint(data []byte) (uint64, []byte, error) {
+	v, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, nil, fmt.Errorf("%w: invalid varint", ErrInvalidProto)
+	}
+	return v, data[n:], nil
+}
+
+func unzigzag32(v uint64) int32 {
+	return int32(uint32(v)>>1) ^ -int32(uint32(v)&1)
+}
+
+func appendProtoBytes(buf []byte, field uint64, b []byte) []byte {
+	buf = appendUvarint(buf, field<<3|protoBytes)
+	buf = appendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
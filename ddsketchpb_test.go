@@ -0,0 +1,78 @@
+package tdigest_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestDDSketch_MarshalProto(t *testing.T) {
+	s, err := NormalDigest.ToDDSketch(0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Zero = 3
+	data, err := s.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got tdigest.DDSketch
+	if err := got.UnmarshalProto(data); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got.RelativeAccuracy-s.RelativeAccuracy) > 1e-12 {
+		t.Errorf("unexpected relative accuracy, got %g want %g", got.RelativeAccuracy, s.RelativeAccuracy)
+	}
+	got.RelativeAccuracy = s.RelativeAccuracy
+	if !reflect.DeepEqual(&got, s) {
+		t.Error("sketch differs after round trip")
+	}
+
+	small := &tdigest.DDSketch{RelativeAccuracy: 0.5, Positive: map[int]float64{-1: 2}}
+	data, _ = small.MarshalProto()
+	// The mapping with gamma 3, and the positive store with key -1 as a
+	// zigzag varint.
+	want := "0a09" + "090000000000000840" + "120d" + "0a0b" + "0801" + "110000000000000040"
+	if got := hex.EncodeToString(data); got != want {
+		t.Errorf("unexpected encoding\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestDDSketch_UnmarshalProto(t *testing.T) {
+	// Contiguous bin counts starting at index -2, and a zero count.
+	data := mustHex(t, "0a09"+"090000000000000040"+
+		"1a14"+"1210"+"000000000000f03f"+"0000000000000840"+"1803"+
+		"21000000000000e03f")
+	var s tdigest.DDSketch
+	if err := s.UnmarshalProto(data); err != nil {
+		t.Fatal(err)
+	}
+	want := tdigest.DDSketch{
+		RelativeAccuracy: 1.0 / 3,
+		Positive:         map[int]float64{},
+		Negative:         map[int]float64{-2: 1, -1: 3},
+		Zero:             0.5,
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("unexpected sketch %+v", s)
+	}
+
+	for _, tt := range []struct {
+		data string
+		want error
+	}{
+		{data: "", want: tdigest.ErrInvalidDDSketch},
+		{data: "0a09" + "090000000000000040" + "0a09" + "110000000000000040", want: tdigest.ErrInvalidDDSketch},
+		{data: "0a0b" + "090000000000000040" + "1801", want: tdigest.ErrInvalidDDSketch},
+		{data: "0a09" + "090000000000000040" + "1a05", want: tdigest.ErrInvalidProto},
+	} {
+		var s tdigest.DDSketch
+		if err := s.UnmarshalProto(mustHex(t, tt.data)); !errors.Is(err, tt.want) {
+			t.Errorf("%s: unexpected error %v, want %v", tt.data, err, tt.want)
+		}
+	}
+}
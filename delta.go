@@ -0,0 +1,58 @@
+package tdigest
+
+// Delta returns a new digest approximating the distribution of the samples
+// added between two cumulative snapshots of the same digest, prev and the
+// later curr, with the compression of curr. Every centroid of curr covers
+// the range up to the midpoints to its neighbours; the weight prev holds in
+// that range, estimated from its CDF, is subtracted from the centroid, and
+// the remaining weights, where positive, are scaled so that they add up to
+// the difference of the counts. The result is empty if curr holds no more
+// weight than prev. Neither digest is changed, apart from processing any
+// pending centroids. In strict mode, Delta returns ErrUnflushed if either
+// digest has pending centroids.
+func Delta(prev, curr *TDigest) (*TDigest, error) {
+	curr.lock()
+	err := curr.prepareRead()
+	cl := append(CentroidList(nil), curr.processed...)
+	d := NewWithCompression(curr.Compression)
+	total := curr.processedWeight
+	curr.unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	prev.lock()
+	defer prev.unlock()
+	if err := prev.prepareRead(); err != nil {
+		return nil, err
+	}
+	s := prev.summary()
+	prevCount := s.weight
+	total -= prevCount
+	if !(total > 0) || len(cl) == 0 {
+		return d, nil
+	}
+
+	kept := cl[:0]
+	var sum, below float64
+	for i, c := range cl {
+		above := 1.0
+		if i+1 < len(cl) && prevCount > 0 {
+			above = s.cdf((c.Mean + cl[i+1].Mean) / 2)
+		}
+		c.Weight -= (above - below) * prevCount
+		below = above
+		if c.Weight > 0 {
+			kept = append(kept, c)
+			sum += c.Weight
+		}
+	}
+	if sum == 0 {
+		return d, nil
+	}
+	for i := range kept {
+		kept[i].Weight *= total / sum
+	}
+	d.AddCentroidList(kept)
+	return d, nil
+}
@@ -0,0 +1,37 @@
+package tdigest_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestDelta(t *testing.T) {
+	prev := tdigest.NewWithCompression(1000)
+	prev.AddSlice(NormalData[:50000])
+	curr := prev.Clone()
+	curr.AddSlice(UniformData[:50000])
+	want := tdigest.NewWithCompression(1000)
+	want.AddSlice(UniformData[:50000])
+
+	d, err := tdigest.Delta(prev, curr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Count(); math.Abs(got-50000) > 1e-6 {
+		t.Errorf("unexpected count, got %g want 50000", got)
+	}
+	for _, q := range []float64{0.1, 0.25, 0.5, 0.75, 0.9} {
+		if got, w := d.Quantile(q), want.Quantile(q); math.Abs(got-w) > 2 {
+			t.Errorf("quantile %g: got %g want %g", q, got, w)
+		}
+	}
+
+	if d, _ := tdigest.Delta(tdigest.New(), curr); math.Abs(d.Count()-curr.Count()) > 1e-6 || d.Quantile(0.5) != curr.Quantile(0.5) {
+		t.Error("delta from an empty digest differs from the current one")
+	}
+	if d, _ := tdigest.Delta(curr, curr); d.Count() != 0 {
+		t.Errorf("unexpected count of delta between equal digests, got %g want 0", d.Count())
+	}
+}
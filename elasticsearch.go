@@ -0,0 +1,106 @@
+package tdigest
+
+import (
+	"fmt"
+	"math"
+)
+
+// ErrInvalidElasticsearch is returned when decoding a malformed
+// Elasticsearch TDigestState.
+const ErrInvalidElasticsearch = Error("invalid Elasticsearch TDigestState encoding")
+
+// ErrElasticsearchUnsupported is returned when encoding a digest that cannot
+// be represented as an Elasticsearch TDigestState.
+const ErrElasticsearchUnsupported = Error("digest cannot be represented as an Elasticsearch TDigestState")
+
+// MarshalElasticsearch encodes the digest as the TDigestState that
+// Elasticsearch and OpenSearch exchange for percentiles aggregations, in the
+// layout of their StreamOutput: the compression as a double, the number of
+// centroids as a VInt, then the mean of each centroid as a double and its
+// count as a VLong. This is the layout used by OpenSearch and by
+// Elasticsearch before 8.9, which also writes the digest type in newer
+// transport versions. Since counts are integers, other weights are reported
+// as ErrElasticsearchUnsupported.
+func (t *TDigest) MarshalElasticsearch() ([]byte, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, err
+	}
+	if _, err := t.integralCount(ErrElasticsearchUnsupported); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, 8+5+t.processed.Len()*(8+5))
+	buf = appendFloat64(buf, t.Compression)
+	buf = appendUvarint(buf, uint64(t.processed.Len()))
+	for _, c := range t.processed {
+		buf = appendFloat64(buf, c.Mean)
+		buf = appendUvarint(buf, uint64(c.Weight))
+	}
+	return buf, nil
+}
+
+// UnmarshalElasticsearch replaces the data and compression of the digest by
+// those of a TDigestState encoded by MarshalElasticsearch, keeping its other
+// settings. Since the state lacks them, the min and max are taken to be the
+// means of the first and last centroids. Decoding leaves the digest
+// unchanged on error.
+func (t *TDigest) UnmarshalElasticsearch(data []byte) error {
+	if len(data) < 8 {
+		return fmt.Errorf("%w: truncated", ErrInvalidElasticsearch)
+	}
+	h := binaryHeader{compression: readFloat64(data)}
+	data = data[8:]
+	if !(h.compression >= 1 && h.compression <= maxDecodeCompression) {
+		return fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidElasticsearch, float64(maxDecodeCompression))
+	}
+	n, k := readJavaVarint(data, 5)
+	if k <= 0 || n > math.MaxInt32 {
+		return fmt.Errorf("%w: invalid number of centroids", ErrInvalidElasticsearch)
+	}
+	data = data[k:]
+
+	// Every centroid takes at least nine bytes.
+	capacity := n
+	if max := uint64(len(data) / 9); capacity > max {
+		capacity = max
+	}
+	d := &binaryDecoder{centroids: make(CentroidList, 0, capacity), invalid: ErrInvalidElasticsearch}
+	for i := uint64(0); i < n; i++ {
+		if len(data) < 8 {
+			return fmt.Errorf("%w: truncated", ErrInvalidElasticsearch)
+		}
+		mean := readFloat64(data)
+		count, k := readJavaVarint(data[8:], 9)
+		if k <= 0 {
+			return fmt.Errorf("%w: centroid %d: invalid count", ErrInvalidElasticsearch, i)
+		}
+		if err := d.add(mean, float64(count)); err != nil {
+			return err
+		}
+		data = data[8+k:]
+	}
+	if len(data) > 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidElasticsearch, len(data))
+	}
+	if n := d.centroids.Len(); n > 0 {
+		h.min, h.max = d.centroids[0].Mean, d.centroids[n-1].Mean
+	}
+	return t.setDecoded(h, d)
+}
+
+// readJavaVarint reads a variable length integer of at most max bytes, as
+// written by the writeVInt and writeVLong methods of Elasticsearch, and
+// returns it with the number of bytes read, or 0 if data is truncated or the
+// integer too long.
+func readJavaVarint(data []byte, max int) (uint64, int) {
+	var v uint64
+	for i := 0; i < max && i < len(data); i++ {
+		v |= uint64(data[i]&0x7f) << (7 * uint(i))
+		if data[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,68 @@
+package tdigest_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_MarshalElasticsearch(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.Add(1, 1)
+	td.Add(2, 200)
+	data, err := td.MarshalElasticsearch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "4059000000000000" + "02" + "3ff0000000000000" + "01" + "4000000000000000" + "c801"
+	if got := hex.EncodeToString(data); got != want {
+		t.Errorf("unexpected encoding\ngot  %s\nwant %s", got, want)
+	}
+
+	for _, td := range []*tdigest.TDigest{tdigest.NewWithCompression(100), td, NormalDigest} {
+		data, err := td.MarshalElasticsearch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := tdigest.New()
+		if err := got.UnmarshalElasticsearch(data); err != nil {
+			t.Fatal(err)
+		}
+		if got.Compression != td.Compression || !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+			t.Error("digest differs after round trip")
+		}
+	}
+
+	weighted := tdigest.NewWithCompression(100)
+	weighted.Add(1, 0.5)
+	if _, err := weighted.MarshalElasticsearch(); !errors.Is(err, tdigest.ErrElasticsearchUnsupported) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestTdigest_UnmarshalElasticsearchErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"40590000",
+		"0000000000000000" + "00",
+		"4059000000000000",
+		"4059000000000000" + "ffffffff0f",
+		"4059000000000000" + "01" + "3ff0000000000000",
+		"4059000000000000" + "01" + "3ff0000000000000" + "00",
+		"4059000000000000" + "01" + "3ff0000000000000" + "ffffffffffffffffff01",
+		"4059000000000000" + "02" + "4000000000000000" + "01" + "3ff0000000000000" + "01",
+		"4059000000000000" + "00" + "00",
+	} {
+		got := tdigest.NewWithCompression(10)
+		got.Add(42, 1)
+		if err := got.UnmarshalElasticsearch(mustHex(t, s)); !errors.Is(err, tdigest.ErrInvalidElasticsearch) {
+			t.Errorf("%s: unexpected error %v", s, err)
+		}
+		if got.Compression != 10 || got.Quantile(0.5) != 42 {
+			t.Errorf("%s: digest was modified on error", s)
+		}
+	}
+}
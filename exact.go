@@ -0,0 +1,72 @@
+package tdigest
+
+import "sort"
+
+// WithExactThreshold keeps every sample as its own centroid until the total
+// weight of the digest exceeds n, e.g. four times the compression. Until
+// then, Quantile and CDF are answered exactly: Quantile returns the smallest
+// sample whose rank is at least q times Count, and CDF returns the fraction
+// of the weight at or below x. Once the threshold is exceeded the samples are
+// compressed as usual, and the digest stays approximate until it is reset.
+//
+// Merging a digest which is no longer exact also makes the result
+// approximate.
+func WithExactThreshold(n int) Option {
+	return func(t *TDigest) {
+		t.exactThreshold = float64(n)
+	}
+}
+
+// Exact reports whether the digest still holds every sample added to it, in
+// which case queries are answered exactly. See WithExactThreshold.
+func (t *TDigest) Exact() bool {
+	t.lock()
+	defer t.unlock()
+	return t.isExact()
+}
+
+func (t *TDigest) isExact() bool {
+	return t.exact && t.processedWeight+t.unprocessedWeight <= t.exactThreshold
+}
+
+// keepExact stores the sorted unprocessed centroids as the processed ones
+// without compressing them, as long as the digest may remain exact. It
+// reports whether it did so.
+func (t *TDigest) keepExact() bool {
+	if !t.exact {
+		return false
+	}
+	if t.processedWeight+t.unprocessedWeight > t.exactThreshold {
+		t.exact = false
+		return false
+	}
+	t.processed, t.unprocessed = t.unprocessed, t.processed[:0]
+	t.processedWeight += t.unprocessedWeight
+	t.unprocessedWeight = 0
+	t.unsorted = false
+	return true
+}
+
+// exactQuantile returns the smallest value whose rank is at least q times
+// the total weight.
+func (s summary) exactQuantile(q float64) float64 {
+	index := q * s.weight
+	i := sort.Search(s.centroids.Len(), func(i int) bool {
+		return s.cumulative[i]+s.centroids[i].Weight/2 >= index
+	})
+	if i == s.centroids.Len() {
+		i--
+	}
+	return s.centroids[i].Mean
+}
+
+// exactCDF returns the fraction of the weight at or below x.
+func (s summary) exactCDF(x float64) float64 {
+	i := sort.Search(s.centroids.Len(), func(i int) bool {
+		return s.centroids[i].Mean > x
+	})
+	if i == 0 {
+		return 0
+	}
+	return (s.cumulative[i-1] + s.centroids[i-1].Weight/2) / s.weight
+}
@@ -0,0 +1,92 @@
+package tdigest_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestWithExactThreshold(t *testing.T) {
+	td := tdigest.NewWithCompression(10, tdigest.WithExactThreshold(100))
+	// Far more samples than centroids a compression of 10 would keep.
+	for i := 100; i > 0; i-- {
+		td.Add(float64(i), 1)
+	}
+	if !td.Exact() {
+		t.Fatal("expected digest to be exact")
+	}
+	if got := len(td.Centroids(nil)); got != 100 {
+		t.Errorf("unexpected number of centroids, got %d want 100", got)
+	}
+
+	quantiles := []struct {
+		q, want float64
+	}{
+		{0, 1},
+		{0.01, 1},
+		{0.015, 2},
+		{0.5, 50},
+		{0.99, 99},
+		{0.999, 100},
+		{1, 100},
+	}
+	for _, tt := range quantiles {
+		if got := td.Quantile(tt.q); got != tt.want {
+			t.Errorf("unexpected quantile %g, got %g want %g", tt.q, got, tt.want)
+		}
+	}
+	cdfs := []struct {
+		x, want float64
+	}{
+		{0, 0},
+		{1, 0.01},
+		{50.5, 0.5},
+		{100, 1},
+	}
+	for _, tt := range cdfs {
+		if got := td.CDF(tt.x); got != tt.want {
+			t.Errorf("unexpected CDF %g, got %g want %g", tt.x, got, tt.want)
+		}
+	}
+
+	td.AddSorted([]float64{101})
+	if td.Exact() {
+		t.Fatal("expected digest to be approximate past the threshold")
+	}
+	if got := len(td.Centroids(nil)); got >= 100 {
+		t.Errorf("expected centroids to be compressed, got %d", got)
+	}
+	if got := td.Count(); got != 101 {
+		t.Errorf("unexpected count, got %g want 101", got)
+	}
+
+	td.Reset()
+	if !td.Exact() {
+		t.Error("expected reset digest to be exact")
+	}
+}
+
+func TestWithExactThreshold_Merge(t *testing.T) {
+	exact := tdigest.New(tdigest.WithExactThreshold(10))
+	exact.AddValues(1, 2, 3)
+	td := tdigest.New(tdigest.WithExactThreshold(10))
+	td.AddValues(4, 5)
+	td.Merge(exact)
+	if !td.Exact() {
+		t.Error("expected merge of exact digests to be exact")
+	}
+	if got := td.Quantile(0.5); got != 3 {
+		t.Errorf("unexpected median, got %g want 3", got)
+	}
+
+	td.Merge(tdigest.New())
+	if !td.Exact() {
+		t.Error("expected merge of an empty digest to keep the digest exact")
+	}
+	approx := tdigest.New()
+	approx.Add(6, 1)
+	td.Merge(approx)
+	if td.Exact() {
+		t.Error("expected merge of an approximate digest to be approximate")
+	}
+}
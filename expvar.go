@@ -0,0 +1,66 @@
+package tdigest
+
+import (
+	"expvar"
+	"math"
+	"strconv"
+)
+
+// ExpvarDigest is an expvar.Var publishing the count, extremes, mean and
+// some quantiles of a digest as a JSON object, computed anew on every call
+// to String, such as
+//
+//	{"count": 4, "min": 1, "max": 4, "mean": 2.5, "quantiles": {"0.5": 2.5}}
+//
+// Values that JSON cannot represent, such as the extremes of an empty
+// digest, are null. In strict mode, the object only holds an error message,
+// as in {"error": "..."}, while the digest has pending centroids. Since
+// expvar calls String from its HTTP handler, a digest that is written
+// concurrently must be created with WithLocker.
+type ExpvarDigest struct {
+	t         *TDigest
+	quantiles []float64
+}
+
+var _ expvar.Var = (*ExpvarDigest)(nil)
+
+// NewExpvar returns an expvar.Var publishing the given quantiles of t, for
+// use with expvar.Publish.
+func NewExpvar(t *TDigest, quantiles ...float64) *ExpvarDigest {
+	return &ExpvarDigest{t: t, quantiles: append([]float64(nil), quantiles...)}
+}
+
+// String returns the statistics of the digest as a JSON object.
+func (v *ExpvarDigest) String() string {
+	r, err := v.t.quantileReport(v.quantiles)
+	if err != nil {
+		return `{"error": ` + strconv.Quote(err.Error()) + "}"
+	}
+	buf := append([]byte(nil), `{"count": `...)
+	buf = appendJSONFloat(buf, r.count)
+	buf = append(buf, `, "min": `...)
+	buf = appendJSONFloat(buf, r.min)
+	buf = append(buf, `, "max": `...)
+	buf = appendJSONFloat(buf, r.max)
+	buf = append(buf, `, "mean": `...)
+	buf = appendJSONFloat(buf, r.mean)
+	buf = append(buf, `, "quantiles": {`...)
+	for i, q := range v.quantiles {
+		if i > 0 {
+			buf = append(buf, ", "...)
+		}
+		buf = append(buf, '"')
+		buf = strconv.AppendFloat(buf, q, 'g', -1, 64)
+		buf = append(buf, `": `...)
+		buf = appendJSONFloat(buf, r.quantiles[i])
+	}
+	return string(append(buf, "}}"...))
+}
+
+// appendJSONFloat appends x as a JSON number, or null if it is not finite.
+func appendJSONFloat(buf []byte, x float64) []byte {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return append(buf, "null"...)
+	}
+	return strconv.AppendFloat(buf, x, 'g', -1, 64)
+}
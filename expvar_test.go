@@ -0,0 +1,30 @@
+package tdigest_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestExpvarDigest(t *testing.T) {
+	td := tdigest.NewWithCompression(100, tdigest.WithLocker(new(sync.Mutex)))
+	v := tdigest.NewExpvar(td, 0.5, 0.99)
+	want := `{"count": 0, "min": null, "max": null, "mean": null, "quantiles": {"0.5": null, "0.99": null}}`
+	if got := v.String(); got != want {
+		t.Errorf("unexpected empty value, got %s want %s", got, want)
+	}
+
+	td.AddValues(1, 2, 3, 4)
+	want = `{"count": 4, "min": 1, "max": 4, "mean": 2.5, "quantiles": {"0.5": 2.5, "0.99": 4}}`
+	if got := v.String(); got != want {
+		t.Errorf("unexpected value, got %s want %s", got, want)
+	}
+
+	td.AddSlice(NormalData)
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+}
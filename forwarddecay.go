@@ -0,0 +1,148 @@
+package tdigest
+
+import (
+	"math"
+	"time"
+)
+
+// ErrDecayMismatch is returned when merging forward decayed digests with
+// different half-lives.
+const ErrDecayMismatch = Error("digests have different decay half-lives")
+
+// maxForwardExponent bounds the growth of forward decayed weights: once a
+// sample would be weighted by more than 2^64, the landmark is moved forward
+// and the digest scaled down accordingly.
+const maxForwardExponent = 64 * math.Ln2
+
+// ForwardDecayTDigest is a digest of exponentially time-decayed samples,
+// using forward decay (Cormode et al., "Forward Decay: A Practical Time
+// Decay Model for Streaming Systems"). Rather than periodically decaying
+// everything added so far, every sample is weighted up by how long after a
+// fixed landmark time it is added, so that a sample counts half as much as
+// one added a half-life later. Since quantiles only depend on relative
+// weights, recent data dominates smoothly without a global decay pass, and
+// digests with the same half-life merge correctly.
+//
+// Like TDigest, a ForwardDecayTDigest is not safe for concurrent use.
+type ForwardDecayTDigest struct {
+	t        *TDigest
+	halfLife time.Duration
+	rate     float64
+	landmark time.Time
+}
+
+// NewForwardDecay initializes a new forward decayed distribution with the
+// given compression, options and half-life, which must be positive, and
+// landmark, which is typically the time it is created.
+func NewForwardDecay(c float64, halfLife time.Duration, landmark time.Time, opts ...Option) *ForwardDecayTDigest {
+	return &ForwardDecayTDigest{
+		t:        NewWithCompression(c, opts...),
+		halfLife: halfLife,
+		rate:     math.Ln2 / halfLife.Seconds(),
+		landmark: landmark,
+	}
+}
+
+// AddAt adds a value x with a weight w, observed at time ts, to the
+// distribution. Samples may be added out of order, but those far older
+// than the landmark may carry too little weight to be kept.
+func (f *ForwardDecayTDigest) AddAt(x, w float64, ts time.Time) {
+	exp := f.exponent(ts)
+	if exp > maxForwardExponent {
+		f.rescale(ts)
+		exp = 0
+	}
+	scaled := w * math.Exp(exp)
+	if scaled == 0 && w > 0 {
+		return
+	}
+	f.t.Add(x, scaled)
+}
+
+// exponent returns the natural logarithm of the growth factor of a sample
+// observed at ts.
+func (f *ForwardDecayTDigest) exponent(ts time.Time) float64 {
+	return f.rate * ts.Sub(f.landmark).Seconds()
+}
+
+// rescale moves the landmark to ts, scaling down the weights of the
+// centroids to match.
+func (f *ForwardDecayTDigest) rescale(ts time.Time) {
+	factor := math.Exp(-f.exponent(ts))
+	f.landmark = ts
+	cl, complete := scaledCentroids(f.t, factor)
+	if complete {
+		f.t.Scale(factor)
+		return
+	}
+	f.t.Reset()
+	f.t.AddCentroidList(cl)
+}
+
+// scaledCentroids returns the centroids of t with their weights multiplied
+// by factor, without those whose weight underflows to zero, and whether
+// none did.
+func scaledCentroids(t *TDigest, factor float64) (CentroidList, bool) {
+	t.lock()
+	t.process()
+	cl := append(CentroidList(nil), t.processed...)
+	t.unlock()
+	kept := cl[:0]
+	for _, c := range cl {
+		if c.Weight *= factor; c.Weight > 0 {
+			kept = append(kept, c)
+		}
+	}
+	return kept, len(kept) == len(cl)
+}
+
+// Merge merges the supplied digest into this one. Both must have the same
+// half-life, or ErrDecayMismatch is returned; their landmarks may differ.
+// f2 is left unchanged, apart from processing any pending centroids.
+func (f *ForwardDecayTDigest) Merge(f2 *ForwardDecayTDigest) error {
+	if f.halfLife != f2.halfLife {
+		return ErrDecayMismatch
+	}
+	if f2.landmark.After(f.landmark) {
+		f.rescale(f2.landmark)
+	}
+	// The weights of f2 are relative to its landmark, no later than that of
+	// f, so they shrink by the growth between the two.
+	factor := math.Exp(f.exponent(f2.landmark))
+	cl, complete := scaledCentroids(f2.t, factor)
+	if !complete {
+		f.t.AddCentroidList(cl)
+		return nil
+	}
+	// Merging a scaled copy keeps the exact extremes of f2.
+	t2 := f2.t.Clone()
+	if err := t2.Scale(factor); err != nil {
+		return err
+	}
+	return f.t.MergeErr(t2)
+}
+
+// Quantile returns the (approximate) quantile of the decayed distribution,
+// which does not depend on the time it is asked at.
+func (f *ForwardDecayTDigest) Quantile(q float64) float64 {
+	return f.t.Quantile(q)
+}
+
+// CDF returns the cumulative distribution function of the decayed
+// distribution for a given value x.
+func (f *ForwardDecayTDigest) CDF(x float64) float64 {
+	return f.t.CDF(x)
+}
+
+// CountAt returns the total decayed weight of the distribution as of time
+// ts: the sum of the weights of all samples, each halved for every
+// half-life between when it was observed and ts.
+func (f *ForwardDecayTDigest) CountAt(ts time.Time) float64 {
+	return f.t.Count() * math.Exp(-f.exponent(ts))
+}
+
+// Digest returns a new digest holding the decayed distribution, with
+// weights relative to the landmark.
+func (f *ForwardDecayTDigest) Digest() *TDigest {
+	return f.t.Clone()
+}
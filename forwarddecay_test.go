@@ -0,0 +1,72 @@
+package tdigest_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestForwardDecayTDigest(t *testing.T) {
+	start := time.Unix(1e9, 0)
+	f := tdigest.NewForwardDecay(100, time.Minute, start)
+	// Samples observed a half-life apart count twice as much as older ones.
+	f.AddAt(1, 1, start)
+	f.AddAt(2, 1, start.Add(time.Minute))
+	want := tdigest.NewWithCompression(100)
+	want.Add(1, 1)
+	want.Add(2, 2)
+	if got, w := f.CDF(1.5), want.CDF(1.5); math.Abs(got-w) > 1e-9 {
+		t.Errorf("unexpected CDF, got %g want %g", got, w)
+	}
+	if got, want := f.CountAt(start.Add(2*time.Minute)), 0.75; math.Abs(got-want) > 1e-9 {
+		t.Errorf("unexpected decayed count, got %g want %g", got, want)
+	}
+
+	// Adding far beyond the landmark moves it, without overflowing.
+	late := start.Add(1000 * time.Minute)
+	f.AddAt(3, 1, late)
+	if got := f.CountAt(late); math.Abs(got-1) > 1e-9 {
+		t.Errorf("unexpected count after rescaling, got %g want 1", got)
+	}
+	if got := f.Quantile(0.5); got != 3 {
+		t.Errorf("unexpected median after rescaling, got %g want 3", got)
+	}
+}
+
+func TestForwardDecayTDigest_Merge(t *testing.T) {
+	start := time.Unix(1e9, 0)
+	for _, offset := range []time.Duration{time.Hour, -time.Hour} {
+		a := tdigest.NewForwardDecay(100, time.Minute, start)
+		b := tdigest.NewForwardDecay(100, time.Minute, start.Add(offset))
+		want := tdigest.NewForwardDecay(100, time.Minute, start)
+		for i := 0; i < 100; i++ {
+			ts := start.Add(time.Duration(i) * time.Second)
+			a.AddAt(float64(i), 1, ts)
+			want.AddAt(float64(i), 1, ts)
+			ts = ts.Add(30 * time.Second)
+			b.AddAt(float64(-i), 1, ts)
+			want.AddAt(float64(-i), 1, ts)
+		}
+		if err := a.Merge(b); err != nil {
+			t.Fatal(err)
+		}
+		now := start.Add(2 * time.Minute)
+		if got, w := a.CountAt(now), want.CountAt(now); math.Abs(got-w) > 1e-9*w {
+			t.Errorf("landmark %v: unexpected count after merge, got %g want %g", offset, got, w)
+		}
+		for _, q := range []float64{0, 0.1, 0.5, 0.9, 1} {
+			if got, w := a.Quantile(q), want.Quantile(q); math.Abs(got-w) > 1 {
+				t.Errorf("landmark %v: quantile %g: got %g want %g", offset, q, got, w)
+			}
+		}
+	}
+
+	a := tdigest.NewForwardDecay(100, time.Minute, start)
+	c := tdigest.NewForwardDecay(100, time.Hour, start)
+	if err := a.Merge(c); !errors.Is(err, tdigest.ErrDecayMismatch) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package tdigest
+
+// FrozenDigest is an immutable snapshot of a digest, returned by Freeze.
+// Its centroids and cumulative weights are computed once, so queries never
+// modify it and it is safe for concurrent use by multiple goroutines.
+type FrozenDigest struct {
+	compression float64
+	s           summary
+}
+
+// Freeze processes any pending centroids and returns an immutable snapshot
+// of the digest. The digest t can be modified afterwards without affecting
+// the snapshot. In strict mode, Freeze returns ErrUnflushed if the digest
+// has pending centroids.
+func (t *TDigest) Freeze() (*FrozenDigest, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, err
+	}
+	s := t.summary()
+	s.centroids = append(CentroidList(nil), s.centroids...)
+	s.cumulative = append([]float64(nil), s.cumulative...)
+	return &FrozenDigest{
+		compression: t.Compression,
+		s:           s,
+	}, nil
+}
+
+// Compression returns the compression of the digest the snapshot was taken
+// from.
+func (f *FrozenDigest) Compression() float64 {
+	return f.compression
+}
+
+// Quantile returns the (approximate) quantile of the distribution like
+// TDigest.Quantile.
+func (f *FrozenDigest) Quantile(q float64) float64 {
+	return f.s.quantile(q)
+}
+
+// CDF returns the cumulative distribution function for a given value x like
+// TDigest.CDF.
+func (f *FrozenDigest) CDF(x float64) float64 {
+	return f.s.cdf(x)
+}
+
+// Count returns the total weight of the distribution.
+func (f *FrozenDigest) Count() float64 {
+	return f.s.weight
+}
+
+// Centroids appends the centroids of the snapshot to cl and returns it.
+func (f *FrozenDigest) Centroids(cl CentroidList) CentroidList {
+	return append(cl, f.s.centroids...)
+}
+
+// Thaw returns a new mutable digest holding the data of the snapshot, with
+// the same compression.
+func (f *FrozenDigest) Thaw(opts ...Option) *TDigest {
+	t := NewWithCompression(f.compression, opts...)
+	t.AddCentroidList(f.s.centroids)
+	if f.s.weight > 0 {
+		t.min, t.max = f.s.min, f.s.max
+	}
+	return t
+}
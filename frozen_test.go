@@ -0,0 +1,67 @@
+package tdigest_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_Freeze(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddSlice(NormalData[:10000])
+	f, err := td.Freeze()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, q := range quantiles {
+		if got, want := f.Quantile(q), td.Quantile(q); got != want {
+			t.Errorf("unexpected quantile %g, got %g want %g", q, got, want)
+		}
+		x := td.Quantile(q)
+		if got, want := f.CDF(x), td.CDF(x); got != want {
+			t.Errorf("unexpected CDF %g, got %g want %g", x, got, want)
+		}
+	}
+	if got, want := f.Count(), td.Count(); got != want {
+		t.Errorf("unexpected count, got %g want %g", got, want)
+	}
+
+	// The snapshot is unaffected by later writes.
+	median := f.Quantile(0.5)
+	td.AddSlice(UniformData[:10000])
+	if got := f.Quantile(0.5); got != median {
+		t.Errorf("snapshot changed, got median %g want %g", got, median)
+	}
+
+	thawed := f.Thaw()
+	if thawed.Compression != f.Compression() {
+		t.Errorf("unexpected compression, got %g want %g", thawed.Compression, f.Compression())
+	}
+	if got := thawed.Quantile(0.5); got != median {
+		t.Errorf("unexpected thawed median, got %g want %g", got, median)
+	}
+}
+
+func TestFrozenDigest_Concurrent(t *testing.T) {
+	f, err := NormalDigest.Freeze()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := f.Quantile(0.99)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if got := f.Quantile(0.99); got != want {
+					t.Errorf("unexpected quantile, got %g want %g", got, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
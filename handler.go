@@ -0,0 +1,186 @@
+package tdigest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxQueryBuckets is the largest number of histogram buckets served.
+const maxQueryBuckets = 10000
+
+// QueryHandler is an http.Handler serving queries against registered
+// digests. Mounted under a prefix with http.StripPrefix, it serves
+//
+//	GET /                list the names of the digests as JSON
+//	GET /{name}          count, extremes and the requested queries as JSON
+//	GET /{name}/binary   the binary encoding of the digest, for merging
+//
+// where the queries are given by the parameters q for quantiles and x for
+// CDF values, both repeatable, and buckets for a histogram like
+// HistogramTable, as in /latency?q=0.5&q=0.99. Values that JSON cannot
+// represent, such as the extremes of an empty digest, are null. The zero
+// value is ready to use.
+type QueryHandler struct {
+	mu      sync.RWMutex
+	digests map[string]func() *TDigest
+}
+
+// Register serves t under the given name, replacing any digest registered
+// under it before. A digest that is written concurrently must be created
+// with WithLocker.
+func (h *QueryHandler) Register(name string, t *TDigest) {
+	h.RegisterFunc(name, func() *TDigest { return t })
+}
+
+// RegisterFunc serves the digest returned by f under the given name,
+// replacing any digest registered under it before. f is called for every
+// request, and may return a new digest, e.g. ShardedTDigest.Digest, or nil
+// if the digest does not exist.
+func (h *QueryHandler) RegisterFunc(name string, f func() *TDigest) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.digests == nil {
+		h.digests = make(map[string]func() *TDigest)
+	}
+	h.digests[name] = f
+}
+
+// Unregister stops serving the digest with the given name.
+func (h *QueryHandler) Unregister(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.digests, name)
+}
+
+func (h *QueryHandler) digest(name string) *TDigest {
+	h.mu.RLock()
+	f := h.digests[name]
+	h.mu.RUnlock()
+	if f == nil {
+		return nil
+	}
+	return f()
+}
+
+func (h *QueryHandler) names() []string {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.digests))
+	for name := range h.digests {
+		names = append(names, name)
+	}
+	h.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// jsonFloat is a float64 marshaled as null if JSON cannot represent it.
+type jsonFloat float64
+
+func (x jsonFloat) MarshalJSON() ([]byte, error) {
+	return appendJSONFloat(nil, float64(x)), nil
+}
+
+type queryBucket struct {
+	Lower jsonFloat `json:"lower"`
+	Upper jsonFloat `json:"upper"`
+	Count jsonFloat `json:"count"`
+}
+
+type queryResult struct {
+	Name      string               `json:"name"`
+	Count     jsonFloat            `json:"count"`
+	Min       jsonFloat            `json:"min"`
+	Max       jsonFloat            `json:"max"`
+	Quantiles map[string]jsonFloat `json:"quantiles,omitempty"`
+	CDF       map[string]jsonFloat `json:"cdf,omitempty"`
+	Histogram []queryBucket        `json:"histogram,omitempty"`
+}
+
+func (h *QueryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if path == "" {
+		writeQueryJSON(w, map[string][]string{"digests": h.names()})
+		return
+	}
+	name, binary := path, false
+	if strings.HasSuffix(path, "/binary") {
+		name, binary = strings.TrimSuffix(path, "/binary"), true
+	}
+	t := h.digest(name)
+	if t == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if binary {
+		data, err := t.MarshalBinary()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(data)
+		return
+	}
+
+	query := r.URL.Query()
+	res := queryResult{Name: name}
+	for _, p := range query["q"] {
+		q, err := strconv.ParseFloat(p, 64)
+		if err != nil || !(q >= 0 && q <= 1) {
+			http.Error(w, "invalid quantile "+strconv.Quote(p), http.StatusBadRequest)
+			return
+		}
+		if res.Quantiles == nil {
+			res.Quantiles = make(map[string]jsonFloat)
+		}
+		res.Quantiles[p] = jsonFloat(t.Quantile(q))
+	}
+	for _, p := range query["x"] {
+		x, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			http.Error(w, "invalid value "+strconv.Quote(p), http.StatusBadRequest)
+			return
+		}
+		if res.CDF == nil {
+			res.CDF = make(map[string]jsonFloat)
+		}
+		res.CDF[p] = jsonFloat(t.CDF(x))
+	}
+	if p := query.Get("buckets"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > maxQueryBuckets {
+			http.Error(w, "invalid number of buckets "+strconv.Quote(p), http.StatusBadRequest)
+			return
+		}
+		hs, err := histogramBuckets(t, n)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, b := range hs {
+			res.Histogram = append(res.Histogram, queryBucket{Lower: jsonFloat(b.Low), Upper: jsonFloat(b.High), Count: jsonFloat(b.Count)})
+		}
+	}
+	s := t.Stats()
+	res.Count, res.Min, res.Max = jsonFloat(s.Count), jsonFloat(s.Min), jsonFloat(s.Max)
+	writeQueryJSON(w, res)
+}
+
+func writeQueryJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
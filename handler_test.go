@@ -0,0 +1,89 @@
+package tdigest_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestQueryHandler(t *testing.T) {
+	td := tdigest.NewWithCompression(100, tdigest.WithLocker(new(sync.Mutex)))
+	td.AddValues(1, 2, 3, 4)
+	sharded := tdigest.NewSharded(100, 2)
+	strict := tdigest.NewWithCompression(100, tdigest.WithStrict())
+	strict.AddValues(1, 2)
+	var h tdigest.QueryHandler
+	h.Register("latency", td)
+	h.RegisterFunc("sharded", sharded.Digest)
+	h.Register("strict", strict)
+	srv := httptest.NewServer(http.StripPrefix("/digests", &h))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		method string
+		code   int
+		want   string
+	}{
+		{name: "list", path: "/digests/", code: 200, want: `{"digests":["latency","sharded","strict"]}`},
+		{name: "stats", path: "/digests/latency", code: 200, want: `{"name":"latency","count":4,"min":1,"max":4}`},
+		{
+			name: "queries",
+			path: "/digests/latency?q=0.5&q=1&x=2.5&buckets=2",
+			code: 200,
+			want: `{"name":"latency","count":4,"min":1,"max":4,"quantiles":{"0.5":2.5,"1":4},"cdf":{"2.5":0.5},` +
+				`"histogram":[{"lower":1,"upper":2.5,"count":2},{"lower":2.5,"upper":4,"count":2}]}`,
+		},
+		{name: "empty", path: "/digests/sharded?q=0.5", code: 200, want: `{"name":"sharded","count":0,"min":null,"max":null,"quantiles":{"0.5":null}}`},
+		{name: "unknown", path: "/digests/unknown", code: 404},
+		{name: "quantile", path: "/digests/latency?q=2", code: 400},
+		{name: "value", path: "/digests/latency?x=a", code: 400},
+		{name: "buckets", path: "/digests/latency?buckets=0", code: 400},
+		{name: "unflushed", path: "/digests/strict?buckets=2", code: 500},
+		{name: "method", path: "/digests/latency", method: "POST", code: 405},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			req, _ := http.NewRequest(method, srv.URL+tt.path, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.code {
+				t.Fatalf("unexpected status, got %d want %d", resp.StatusCode, tt.code)
+			}
+			if tt.want == "" {
+				return
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			if got := strings.TrimSpace(string(body)); got != tt.want {
+				t.Errorf("unexpected body, got %s want %s", got, tt.want)
+			}
+		})
+	}
+
+	resp, err := http.Get(srv.URL + "/digests/latency/binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got := tdigest.New()
+	if err := got.UnmarshalBinaryFrom(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+		t.Error("centroids differ after fetching the binary encoding")
+	}
+}
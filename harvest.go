@@ -0,0 +1,27 @@
+package tdigest
+
+// Harvest returns the accumulated digest and resets t, in one step under
+// the lock set by WithLocker, so that no sample added concurrently is lost
+// or counted twice, unlike calling Clone and Reset in turn. This suits
+// delta-based reporting, which flushes the samples of every interval. The
+// returned digest has the configuration of t, apart from the lock.
+func (t *TDigest) Harvest() *TDigest {
+	t.lock()
+	defer t.unlock()
+	h := t.clone()
+	t.reset()
+	return h
+}
+
+// HarvestInto makes dst a copy of the accumulated digest and resets t like
+// Harvest, reusing the buffers of dst like CloneInto. dst keeps its own
+// lock, if any, and must not be in use by other goroutines.
+func (t *TDigest) HarvestInto(dst *TDigest) {
+	if dst == t {
+		return
+	}
+	t.lock()
+	defer t.unlock()
+	t.cloneInto(dst)
+	t.reset()
+}
@@ -0,0 +1,61 @@
+package tdigest_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_Harvest(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddSlice(UniformData[:1000])
+	want := td.Clone()
+	got := td.Harvest()
+	if !reflect.DeepEqual(got.Centroids(nil), want.Centroids(nil)) {
+		t.Error("harvested centroids differ")
+	}
+	if got.Compression != 100 || got.Quantile(0) != want.Quantile(0) || got.Quantile(1) != want.Quantile(1) {
+		t.Error("harvested digest differs")
+	}
+	if td.Count() != 0 {
+		t.Errorf("unexpected count after harvest, got %g want 0", td.Count())
+	}
+
+	td.Add(1, 1)
+	td.HarvestInto(got)
+	if got.Count() != 1 || got.Quantile(0.5) != 1 || td.Count() != 0 {
+		t.Error("unexpected digests after HarvestInto")
+	}
+}
+
+func TestTdigest_HarvestConcurrent(t *testing.T) {
+	td := tdigest.NewWithCompression(100, tdigest.WithLocker(new(sync.Mutex)))
+	const writers, n = 4, 10000
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				td.Add(float64(j), 1)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	var total float64
+	go func() {
+		defer close(done)
+		h := tdigest.NewWithCompression(100)
+		for total < writers*n {
+			td.HarvestInto(h)
+			total += h.Count()
+		}
+	}()
+	wg.Wait()
+	<-done
+	if total != writers*n {
+		t.Errorf("unexpected harvested count, got %g want %d", total, writers*n)
+	}
+}
@@ -0,0 +1,35 @@
+package tdigest
+
+// CompressStats describes a compression of the centroids of a digest, for
+// monitoring how aggressively digests are compacted.
+type CompressStats struct {
+	// Before is the number of centroids before compressing, both the
+	// processed ones and those added since the last compression.
+	Before int
+	// After is the number of centroids after compressing.
+	After int
+	// Weight is the total weight of the digest. Compressing never drops
+	// weight, but samples may have been rejected, see RejectedCount.
+	Weight float64
+}
+
+// WithCompressHook calls f after every compression of the centroids, e.g. to
+// export the ratio of centroids before and after as a metric when tuning
+// the compression. f is called with the lock set by WithLocker held, so it
+// must not use the digest, and should return quickly. Compression happens
+// when the buffer of incoming centroids fills up and before queries, so f is
+// not called while the digest keeps samples exactly.
+func WithCompressHook(f func(CompressStats)) Option {
+	return func(t *TDigest) {
+		t.onCompress = f
+	}
+}
+
+// compressed reports a compression of before centroids to the hook and the
+// watches of the digest.
+func (t *TDigest) compressed(before int) {
+	if t.onCompress != nil {
+		t.onCompress(CompressStats{Before: before, After: t.processed.Len(), Weight: t.processedWeight})
+	}
+	t.checkWatches()
+}
@@ -0,0 +1,35 @@
+package tdigest_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestWithCompressHook(t *testing.T) {
+	var calls []tdigest.CompressStats
+	td := tdigest.NewWithCompression(100, tdigest.WithCompressHook(func(s tdigest.CompressStats) {
+		calls = append(calls, s)
+	}))
+	td.AddSlice(UniformData[:10000])
+	td.Quantile(0.5)
+	if len(calls) == 0 {
+		t.Fatal("hook not called")
+	}
+	for i, s := range calls {
+		if s.After > s.Before || s.After == 0 {
+			t.Errorf("call %d: unexpected centroids, %d before and %d after", i, s.Before, s.After)
+		}
+	}
+	last := calls[len(calls)-1]
+	if last.Weight != 10000 || last.After != len(td.Centroids(nil)) {
+		t.Errorf("unexpected last call %+v", last)
+	}
+
+	// Queries of a processed digest do not compress again.
+	n := len(calls)
+	td.Quantile(0.9)
+	if len(calls) != n {
+		t.Errorf("unexpected calls, got %d want %d", len(calls), n)
+	}
+}
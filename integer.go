@@ -0,0 +1,176 @@
+package tdigest
+
+import (
+	"math"
+	"sort"
+)
+
+// IntTDigest is a digest of integer values, such as response sizes or
+// status codes, that stays exact while it holds few distinct values. Until
+// more than a limit of distinct values are added, it counts every value,
+// and Quantile and CDF are answered exactly, like those of a digest within
+// its exact threshold (see WithExactThreshold). Beyond the limit, the counts
+// are moved into a TDigest, which answers all queries from then on.
+//
+// Like TDigest, an IntTDigest is not safe for concurrent use.
+type IntTDigest struct {
+	compression float64
+	opts        []Option
+	limit       int
+
+	// counts holds the count of every value while the digest is exact, and
+	// keys its values, sorted, once a query needs them.
+	counts map[int64]uint64
+	keys   []int64
+	total  uint64
+
+	// t holds the samples once the digest is no longer exact.
+	t *TDigest
+}
+
+// NewInt initializes a new integer distribution that counts up to limit
+// distinct values exactly, and otherwise uses a digest with the given
+// compression and options.
+func NewInt(c float64, limit int, opts ...Option) *IntTDigest {
+	return &IntTDigest{
+		compression: c,
+		opts:        opts,
+		limit:       limit,
+		counts:      make(map[int64]uint64),
+	}
+}
+
+// Add adds a value x, n times.
+func (d *IntTDigest) Add(x int64, n uint64) {
+	if n == 0 {
+		return
+	}
+	if d.t != nil {
+		d.t.Add(float64(x), float64(n))
+		return
+	}
+	if _, ok := d.counts[x]; !ok {
+		d.keys = nil
+	}
+	d.counts[x] += n
+	d.total += n
+	if len(d.counts) > d.limit {
+		d.approximate()
+	}
+}
+
+// approximate moves the counts into a digest.
+func (d *IntTDigest) approximate() {
+	d.t = NewWithCompression(d.compression, d.opts...)
+	d.t.AddCentroidList(d.centroids())
+	// Flush, so that the digest can be read in strict mode.
+	d.t.Flush()
+	d.counts, d.keys, d.total = nil, nil, 0
+}
+
+// sortedKeys returns the counted values in ascending order.
+func (d *IntTDigest) sortedKeys() []int64 {
+	if d.keys == nil {
+		d.keys = make([]int64, 0, len(d.counts))
+		for x := range d.counts {
+			d.keys = append(d.keys, x)
+		}
+		sort.Slice(d.keys, func(i, j int) bool { return d.keys[i] < d.keys[j] })
+	}
+	return d.keys
+}
+
+// centroids returns the counts as centroids, sorted by mean.
+func (d *IntTDigest) centroids() CentroidList {
+	keys := d.sortedKeys()
+	cl := make(CentroidList, len(keys))
+	for i, x := range keys {
+		cl[i] = Centroid{Mean: float64(x), Weight: float64(d.counts[x])}
+	}
+	return cl
+}
+
+// Exact reports whether the digest still counts every value, in which case
+// queries are answered exactly.
+func (d *IntTDigest) Exact() bool {
+	return d.t == nil
+}
+
+// Count returns the total number of values added.
+func (d *IntTDigest) Count() float64 {
+	if d.t != nil {
+		return d.t.Count()
+	}
+	return float64(d.total)
+}
+
+// Quantile returns the quantile of the distribution: while the digest is
+// exact, the smallest value whose rank is at least q times Count.
+func (d *IntTDigest) Quantile(q float64) float64 {
+	if d.t != nil {
+		return d.t.Quantile(q)
+	}
+	if q < 0 || q > 1 || d.total == 0 {
+		return math.NaN()
+	}
+	index := q * float64(d.total)
+	var soFar uint64
+	keys := d.sortedKeys()
+	for _, x := range keys {
+		soFar += d.counts[x]
+		if float64(soFar) >= index {
+			return float64(x)
+		}
+	}
+	return float64(keys[len(keys)-1])
+}
+
+// CDF returns the cumulative distribution function for a given value x:
+// while the digest is exact, the fraction of the values at or below x. Like
+// TDigest.CDF, it returns 0 for an empty digest.
+func (d *IntTDigest) CDF(x float64) float64 {
+	if d.t != nil {
+		return d.t.CDF(x)
+	}
+	if d.total == 0 {
+		return 0
+	}
+	var soFar uint64
+	for _, k := range d.sortedKeys() {
+		if float64(k) > x {
+			break
+		}
+		soFar += d.counts[k]
+	}
+	return float64(soFar) / float64(d.total)
+}
+
+// Merge merges the values of d2 into d. The result stays exact if both
+// digests are exact and hold no more than limit distinct values in total.
+// d2 is left unchanged, apart from processing any pending centroids. In
+// strict mode, it returns the errors of TDigest.MergeErr.
+func (d *IntTDigest) Merge(d2 *IntTDigest) error {
+	if d2.t == nil {
+		for x, n := range d2.counts {
+			d.Add(x, n)
+		}
+		return nil
+	}
+	if d.t == nil {
+		d.approximate()
+	}
+	return d.t.MergeErr(d2.t)
+}
+
+// Digest returns a new digest holding the distribution, with the
+// compression and options of d. While d is exact, every distinct value is
+// added to the digest as one centroid, which the digest compresses like any
+// other, so it only keeps them all while few enough for its compression.
+func (d *IntTDigest) Digest() *TDigest {
+	if d.t != nil {
+		return d.t.Clone()
+	}
+	t := NewWithCompression(d.compression, d.opts...)
+	t.AddCentroidList(d.centroids())
+	return t
+}
@@ -0,0 +1,113 @@
+package tdigest_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestIntTDigest_Exact(t *testing.T) {
+	d := tdigest.NewInt(100, 4)
+	if !math.IsNaN(d.Quantile(0.5)) || d.CDF(0) != 0 {
+		t.Error("expected a NaN quantile and a CDF of 0 for an empty digest")
+	}
+	d.Add(200, 90)
+	d.Add(404, 6)
+	d.Add(500, 3)
+	d.Add(503, 1)
+	d.Add(200, 0)
+	if !d.Exact() {
+		t.Fatal("expected an exact digest")
+	}
+	if got := d.Count(); got != 100 {
+		t.Errorf("unexpected count, got %g want 100", got)
+	}
+	for _, tt := range []struct{ q, want float64 }{
+		{q: 0, want: 200},
+		{q: 0.9, want: 200},
+		{q: 0.91, want: 404},
+		{q: 0.96, want: 404},
+		{q: 0.97, want: 500},
+		{q: 0.995, want: 503},
+		{q: 1, want: 503},
+	} {
+		if got := d.Quantile(tt.q); got != tt.want {
+			t.Errorf("unexpected quantile %g, got %g want %g", tt.q, got, tt.want)
+		}
+	}
+	for _, tt := range []struct{ x, want float64 }{
+		{x: 100, want: 0},
+		{x: 200, want: 0.9},
+		{x: 450, want: 0.96},
+		{x: 503, want: 1},
+	} {
+		if got := d.CDF(tt.x); got != tt.want {
+			t.Errorf("unexpected CDF(%g), got %g want %g", tt.x, got, tt.want)
+		}
+	}
+	if td := d.Digest(); td.Count() != 100 || len(td.Centroids(nil)) != 4 {
+		t.Errorf("unexpected digest, count %g with %d centroids", td.Count(), len(td.Centroids(nil)))
+	}
+}
+
+func TestIntTDigest_Fallback(t *testing.T) {
+	d := tdigest.NewInt(100, 1000)
+	values := make([]float64, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		x := int64(i * 7919 % 2000)
+		d.Add(x, 1)
+		values = append(values, float64(x))
+		if want := i < 1000; d.Exact() != want {
+			t.Fatalf("unexpected Exact after %d values, got %v want %v", i+1, d.Exact(), want)
+		}
+	}
+	if got := d.Count(); got != 10000 {
+		t.Errorf("unexpected count, got %g want 10000", got)
+	}
+	sort.Float64s(values)
+	for _, q := range []float64{0.01, 0.5, 0.99} {
+		want := values[int(q*float64(len(values)))]
+		if got := d.Quantile(q); math.Abs(got-want) > 20 {
+			t.Errorf("unexpected quantile %g, got %g want about %g", q, got, want)
+		}
+	}
+	if got := d.Quantile(0); got != 0 {
+		t.Errorf("unexpected min, got %g want 0", got)
+	}
+}
+
+func TestIntTDigest_Merge(t *testing.T) {
+	a, b := tdigest.NewInt(100, 3), tdigest.NewInt(100, 3)
+	a.Add(1, 2)
+	a.Add(2, 1)
+	b.Add(2, 1)
+	b.Add(3, 4)
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if !a.Exact() || a.Count() != 8 || a.Quantile(0.5) != 2 || a.CDF(2) != 0.5 {
+		t.Errorf("unexpected merge, exact %v count %g median %g", a.Exact(), a.Count(), a.Quantile(0.5))
+	}
+	if b.Count() != 5 {
+		t.Error("merge modified its argument")
+	}
+
+	b.Add(4, 1)
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Exact() || a.Count() != 14 {
+		t.Errorf("unexpected merge beyond the limit, exact %v count %g", a.Exact(), a.Count())
+	}
+
+	c := tdigest.NewInt(100, 1)
+	c.Add(5, 1)
+	if err := c.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if c.Exact() || c.Count() != 7 || c.Quantile(1) != 5 {
+		t.Errorf("unexpected merge of an inexact digest, exact %v count %g", c.Exact(), c.Count())
+	}
+}
@@ -0,0 +1,107 @@
+package tdigest
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	lineMeasurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	lineKeyEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+// quantileReport holds the statistics of a digest that are exported as
+// metrics.
+type quantileReport struct {
+	count, sum, min, max, mean float64
+	quantiles                  []float64
+}
+
+// quantileReport computes the count, sum, extremes, mean and given quantiles
+// of the digest, processing any pending centroids first. All but the count
+// and sum are NaN if the digest is empty. In strict mode it returns
+// ErrUnflushed if the digest has pending centroids.
+func (t *TDigest) quantileReport(qs []float64) (quantileReport, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return quantileReport{}, err
+	}
+	s := t.summary()
+
+	r := quantileReport{count: s.weight, min: s.min, max: s.max, quantiles: make([]float64, len(qs))}
+	for _, c := range s.centroids {
+		r.sum += c.Mean * c.Weight
+	}
+	r.mean = r.sum / s.weight
+	if s.weight == 0 {
+		r.min, r.max, r.mean = math.NaN(), math.NaN(), math.NaN()
+	}
+	for i, q := range qs {
+		r.quantiles[i] = s.quantile(q)
+	}
+	return r, nil
+}
+
+// WriteLineProtocol writes the count, minimum, maximum, mean and the given
+// quantiles of the digest to w as a single line of InfluxDB line protocol,
+// with the given measurement name and tags. The fields are named count,
+// min, max, mean, and p followed by the percentile for each quantile, such
+// as p99 for 0.99 or p99.9 for 0.999. Tags are sorted by key and those with
+// an empty value omitted. Fields that are not finite, such as the extremes
+// of an empty digest, are omitted too, since line protocol cannot represent
+// them. The timestamp is in nanoseconds, and left out if ts is zero, so that
+// the server assigns one. In strict mode it returns ErrUnflushed, writing
+// nothing, if the digest has pending centroids.
+func (t *TDigest) WriteLineProtocol(w io.Writer, measurement string, tags map[string]string, quantiles []float64, ts time.Time) error {
+	r, err := t.quantileReport(quantiles)
+	if err != nil {
+		return err
+	}
+
+	buf := []byte(lineMeasurementEscaper.Replace(measurement))
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf = append(buf, ',')
+		buf = append(buf, lineKeyEscaper.Replace(k)...)
+		buf = append(buf, '=')
+		buf = append(buf, lineKeyEscaper.Replace(tags[k])...)
+	}
+
+	sep := byte(' ')
+	field := func(name string, x float64) {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return
+		}
+		buf = append(buf, sep)
+		buf = append(buf, lineKeyEscaper.Replace(name)...)
+		buf = append(buf, '=')
+		buf = strconv.AppendFloat(buf, x, 'g', -1, 64)
+		sep = ','
+	}
+	field("count", r.count)
+	field("min", r.min)
+	field("max", r.max)
+	field("mean", r.mean)
+	for i, q := range quantiles {
+		field("p"+strconv.FormatFloat(q*100, 'g', 10, 64), r.quantiles[i])
+	}
+
+	if !ts.IsZero() {
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, ts.UnixNano(), 10)
+	}
+	buf = append(buf, '\n')
+	_, err = w.Write(buf)
+	return err
+}
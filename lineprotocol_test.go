@@ -0,0 +1,59 @@
+package tdigest_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_WriteLineProtocol(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddValues(1, 2, 3, 4)
+	empty := tdigest.NewWithCompression(100)
+	tests := []struct {
+		name        string
+		td          *tdigest.TDigest
+		measurement string
+		tags        map[string]string
+		quantiles   []float64
+		ts          time.Time
+		want        string
+	}{
+		{
+			name:        "digest",
+			td:          td,
+			measurement: "latency",
+			tags:        map[string]string{"service": "api", "host": "a"},
+			quantiles:   []float64{0.5, 0.999},
+			ts:          time.Unix(1, 5),
+			want:        "latency,host=a,service=api count=4,min=1,max=4,mean=2.5,p50=2.5,p99.9=4 1000000005\n",
+		},
+		{
+			name:        "escaped",
+			td:          td,
+			measurement: "request latency,ms",
+			tags:        map[string]string{"a b": "c=d,e", "empty": ""},
+			want:        `request\ latency\,ms,a\ b=c\=d\,e count=4,min=1,max=4,mean=2.5` + "\n",
+		},
+		{
+			name:        "empty",
+			td:          empty,
+			measurement: "latency",
+			quantiles:   []float64{0.5},
+			want:        "latency count=0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := tt.td.WriteLineProtocol(&b, tt.measurement, tt.tags, tt.quantiles, tt.ts); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("unexpected line, got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package tdigest
+
+import "sync"
+
+// WithLocker makes the digest hold l while it is accessed, so that it can be
+// shared by multiple goroutines, e.g. by passing a new sync.Mutex. A nil l
+// disables locking, which is the default.
+//
+// Every method of the digest holds the lock while it reads or modifies the
+// digest, as do the functions taking digests, such as FormatText,
+// QuantileAcross and ParallelMergeAll. The exceptions are ResetWithOptions,
+// which replaces the lock itself, and the Compression field, neither of
+// which may be used while the digest is shared. ForEachCentroid, the
+// hook set by WithCompressHook and the functions passed to WatchQuantile
+// are called with the lock held, so they must not use the digest.
+//
+// Methods involving two digests, such as Merge, hold the locks of both,
+// that of the receiver first. Merging two digests into each other
+// concurrently, as in a.Merge(b) and b.Merge(a), can therefore deadlock.
+func WithLocker(l sync.Locker) Option {
+	return func(t *TDigest) {
+		t.locker = l
+	}
+}
+
+func (t *TDigest) lock() {
+	if t.locker != nil {
+		t.locker.Lock()
+	}
+}
+
+func (t *TDigest) unlock() {
+	if t.locker != nil {
+		t.locker.Unlock()
+	}
+}
@@ -0,0 +1,88 @@
+package tdigest_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestWithLocker(t *testing.T) {
+	td := tdigest.NewWithCompression(1000, tdigest.WithLocker(new(sync.Mutex)))
+	other := tdigest.NewWithCompression(1000, tdigest.WithLocker(new(sync.Mutex)))
+	other.AddSlice(NormalData[:1000])
+
+	const workers = 4
+	var wg sync.WaitGroup
+	n := (len(NormalData) - 1000) / workers
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(xs []float64) {
+			defer wg.Done()
+			for j := 0; j < len(xs); j += 250 {
+				for _, x := range xs[j : j+250] {
+					td.Add(x, 1)
+				}
+				td.Quantile(0.5)
+				td.CDF(10)
+				td.Count()
+				td.SampleCount()
+				td.RejectedCount()
+				td.Rejected(tdigest.RejectNaNValue)
+			}
+		}(NormalData[1000+i*n : 1000+(i+1)*n])
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		td.Merge(other)
+	}()
+	wg.Wait()
+
+	if got, want := td.Count(), float64(len(NormalData)); got != want {
+		t.Errorf("unexpected count, got %g want %g", got, want)
+	}
+	if err := compareQuantiles(td, NormalDigest, 0.001); err != nil {
+		t.Errorf("digest differs: %s", err.Error())
+	}
+}
+
+func TestWithLocker_Methods(t *testing.T) {
+	td := tdigest.NewWithCompression(100, tdigest.WithLocker(new(sync.Mutex)))
+	other := tdigest.NewWithCompression(100, tdigest.WithLocker(new(sync.Mutex)))
+	methods := []func(){
+		func() { td.Scale(1) },
+		func() { td.MapValues(func(x float64) float64 { return x }, true) },
+		func() { td.Split(0.5) },
+		func() { td.Freeze() },
+		func() { td.Snapshot() },
+		func() { td.ShrinkToFit() },
+		func() { td.Exact() },
+		func() { tdigest.FormatText(td) },
+		func() { td.Clone() },
+		func() { td.PeekQuantile(0.5) },
+		func() { td.MemoryFootprint() },
+		func() { tdigest.QuantileAcross(0.5, td, other) },
+		func() { tdigest.ParallelMergeAll([]*tdigest.TDigest{td, other}, 2) },
+	}
+
+	// Run with the race detector to catch methods that skip the lock.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for _, x := range UniformData[:20000] {
+			td.Add(x, 1)
+			other.Add(x, 1)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			for _, m := range methods {
+				m()
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,122 @@
+package tdigest
+
+import (
+	"fmt"
+	"math"
+)
+
+// ErrInvalidLogLinear is returned when converting malformed log-linear bins,
+// or values outside their range.
+const ErrInvalidLogLinear = Error("invalid log-linear histogram bins")
+
+// LogLinearBin is a bin of an OpenHistogram (Circonus circllhist) log-linear
+// histogram. Positive bins count the values in [Val/10 * 10^Exp,
+// (Val+1)/10 * 10^Exp), with Val between 10 and 99, negative bins the
+// negative of those values, with Val between -99 and -10, and the bin with a
+// Val of zero counts zeros.
+type LogLinearBin struct {
+	Val, Exp int8
+	Count    uint64
+}
+
+// bounds returns the smallest and largest absolute value of the bin.
+func (b LogLinearBin) bounds() (lower, upper float64) {
+	val := math.Abs(float64(b.Val))
+	scale := math.Pow10(int(b.Exp) - 1)
+	return val * scale, (val + 1) * scale
+}
+
+func (b LogLinearBin) valid() bool {
+	return b.Val == 0 || b.Val >= 10 && b.Val <= 99 || b.Val >= -99 && b.Val <= -10
+}
+
+// logLinearBin returns the bin of the finite value x.
+func logLinearBin(x float64) (LogLinearBin, bool) {
+	if x == 0 {
+		return LogLinearBin{}, true
+	}
+	a := math.Abs(x)
+	exp := int(math.Floor(math.Log10(a)))
+	val := math.Floor(a / math.Pow10(exp-1))
+	// Correct the rounding of the logarithm near powers of ten.
+	if val >= 100 {
+		exp++
+		val = math.Floor(a / math.Pow10(exp-1))
+	} else if val < 10 {
+		exp--
+		val = math.Floor(a / math.Pow10(exp-1))
+	}
+	if exp < math.MinInt8 || exp > math.MaxInt8 || val < 10 || val > 99 {
+		return LogLinearBin{}, false
+	}
+	if x < 0 {
+		val = -val
+	}
+	return LogLinearBin{Val: int8(val), Exp: int8(exp)}, true
+}
+
+// ToLogLinear converts the digest into the bins of an OpenHistogram
+// log-linear histogram, processing any pending centroids first. The weight
+// of every centroid goes into the bin of its mean, with weights rounded so
+// that the total count is preserved. Bins are in ascending order of value.
+// Means whose bin is out of range, such as infinities, are reported as
+// ErrInvalidLogLinear.
+func (t *TDigest) ToLogLinear() ([]LogLinearBin, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, err
+	}
+
+	var bins []LogLinearBin
+	var soFar float64
+	var recorded uint64
+	for i, c := range t.processed {
+		b, ok := logLinearBin(c.Mean)
+		if !ok {
+			return nil, fmt.Errorf("%w: centroid %d: mean %g out of range", ErrInvalidLogLinear, i, c.Mean)
+		}
+		soFar += c.Weight
+		n := uint64(math.Round(soFar)) - recorded
+		if n == 0 {
+			continue
+		}
+		recorded += n
+		if k := len(bins) - 1; k >= 0 && bins[k].Val == b.Val && bins[k].Exp == b.Exp {
+			bins[k].Count += n
+			continue
+		}
+		b.Count = n
+		bins = append(bins, b)
+	}
+	return bins, nil
+}
+
+// FromLogLinear builds a new digest with the given compression and options
+// from the bins of an OpenHistogram log-linear histogram, in any order,
+// spreading the count of every bin evenly over its range like FromBuckets.
+// The compression must be between 1 and 100000.
+func FromLogLinear(bins []LogLinearBin, compression float64, opts ...Option) (*TDigest, error) {
+	if !(compression >= 1 && compression <= maxDecodeCompression) {
+		return nil, fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidLogLinear, float64(maxDecodeCompression))
+	}
+	var cl CentroidList
+	for i, b := range bins {
+		if !b.valid() {
+			return nil, fmt.Errorf("%w: bin %d: invalid value %d", ErrInvalidLogLinear, i, b.Val)
+		}
+		bucket := Bucket{Count: float64(b.Count)}
+		if b.Val != 0 {
+			bucket.Low, bucket.High = b.bounds()
+			if b.Val < 0 {
+				bucket.Low, bucket.High = -bucket.High, -bucket.Low
+			}
+		}
+		cl = appendBucket(cl, bucket)
+	}
+	sortCentroids(cl)
+
+	t := NewWithCompression(compression, opts...)
+	t.AddCentroidList(cl)
+	return t, nil
+}
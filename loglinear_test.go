@@ -0,0 +1,75 @@
+package tdigest_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_ToLogLinear(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddValues(-1.25, 0, 0.5, 1.23, 1.29, 1000, 1000)
+	got, err := td.ToLogLinear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tdigest.LogLinearBin{
+		{Val: -12, Exp: 0, Count: 1},
+		{Val: 0, Exp: 0, Count: 1},
+		{Val: 50, Exp: -1, Count: 1},
+		{Val: 12, Exp: 0, Count: 2},
+		{Val: 10, Exp: 3, Count: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected bins, got %v want %v", got, want)
+	}
+
+	inf := tdigest.NewWithCompression(100)
+	inf.AddValues(1, math.Inf(1))
+	if _, err := inf.ToLogLinear(); !errors.Is(err, tdigest.ErrInvalidLogLinear) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestFromLogLinear(t *testing.T) {
+	td := tdigest.NewWithCompression(1000)
+	td.AddSlice(NormalData[:100000])
+	bins, err := td.ToLogLinear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tdigest.FromLogLinear(bins, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got.Count()-td.Count()) > 1e-6 {
+		t.Errorf("unexpected count, got %g want %g", got.Count(), td.Count())
+	}
+	// Bins have two significant digits, so are within 10% of their values.
+	for _, q := range []float64{0.01, 0.1, 0.5, 0.9, 0.99} {
+		want := td.Quantile(q)
+		if x := got.Quantile(q); math.Abs(x-want) > 0.1*math.Abs(want) {
+			t.Errorf("quantile %g: got %g want %g", q, x, want)
+		}
+	}
+
+	neg, err := tdigest.FromLogLinear([]tdigest.LogLinearBin{{Val: -20, Exp: 1, Count: 1}, {Count: 1}}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if min, max := neg.Quantile(0), neg.Quantile(1); min < -21 || min > -20 || max != 0 {
+		t.Errorf("unexpected range [%g, %g]", min, max)
+	}
+
+	if _, err := tdigest.FromLogLinear([]tdigest.LogLinearBin{{Val: 5, Count: 1}}, 100); !errors.Is(err, tdigest.ErrInvalidLogLinear) {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, compression := range []float64{0, math.NaN(), 1e6} {
+		if _, err := tdigest.FromLogLinear(nil, compression); !errors.Is(err, tdigest.ErrInvalidLogLinear) {
+			t.Errorf("compression %g: unexpected error %v", compression, err)
+		}
+	}
+}
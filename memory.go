@@ -0,0 +1,31 @@
+package tdigest
+
+import "unsafe"
+
+// ShrinkToFit processes any pending centroids and releases the capacity of
+// the internal buffers beyond what the digest currently holds. It is meant
+// for digests that are done ingesting and will only be queried or stored;
+// adding to the digest afterwards grows the buffers again as needed.
+func (t *TDigest) ShrinkToFit() {
+	t.lock()
+	defer t.unlock()
+	t.process()
+	t.updateCumulative()
+	t.processed = append(CentroidList(nil), t.processed...)
+	t.unprocessed = nil
+	t.cumulative = append([]float64(nil), t.cumulative...)
+	t.shared = false
+}
+
+// MemoryFootprint returns the number of bytes currently held by the digest,
+// including the capacity of its buffers and the digest itself. Unlike
+// ByteSizeForCompression, which estimates the size for a given compression,
+// it reflects the actual state of the digest, e.g. after ShrinkToFit.
+func (t *TDigest) MemoryFootprint() int {
+	t.lock()
+	defer t.unlock()
+	return int(unsafe.Sizeof(*t)) +
+		(cap(t.processed)+cap(t.unprocessed))*int(unsafe.Sizeof(Centroid{})) +
+		cap(t.cumulative)*int(unsafe.Sizeof(float64(0))) +
+		cap(t.cache)*int(unsafe.Sizeof(cacheEntry{}))
+}
@@ -0,0 +1,46 @@
+package tdigest_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_ShrinkToFit(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddSlice(NormalData[:10000])
+	want := td.Clone()
+
+	td.ShrinkToFit()
+	if err := compareQuantiles(td, want, 0); err != nil {
+		t.Errorf("digest changed: %s", err.Error())
+	}
+
+	// The digest keeps working once the buffers have been released.
+	td.AddSlice(NormalData[10000:20000])
+	want.AddSlice(NormalData[10000:20000])
+	if err := compareQuantiles(td, want, 0); err != nil {
+		t.Errorf("digest differs after adding: %s", err.Error())
+	}
+}
+
+func TestTdigest_MemoryFootprint(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	empty := td.MemoryFootprint()
+	// Two buffers of 200 and 801 centroids of 16 bytes each.
+	if min := 1001 * 16; empty < min {
+		t.Errorf("footprint too small, got %d want at least %d", empty, min)
+	}
+
+	td.AddSlice(NormalData[:10000])
+	td.Quantile(0.5)
+	if got := td.MemoryFootprint(); got <= empty {
+		t.Errorf("expected footprint to grow once queried, got %d want more than %d", got, empty)
+	}
+
+	td.ShrinkToFit()
+	n := len(td.Centroids(nil))
+	if got, max := td.MemoryFootprint(), 1000+n*24; got > max {
+		t.Errorf("footprint too large after shrinking, got %d want at most %d", got, max)
+	}
+}
@@ -0,0 +1,125 @@
+package tdigest
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyMiddleware records the durations of HTTP requests, in seconds,
+// into a digest for all requests and one per route. Its digests are
+// sharded, so that concurrent requests rarely contend, and may be queried
+// while requests are recorded.
+type LatencyMiddleware struct {
+	compression float64
+	opts        []Option
+	route       func(r *http.Request) string
+	all         *ShardedTDigest
+
+	mu     sync.RWMutex
+	routes map[string]*ShardedTDigest
+}
+
+// NewLatencyMiddleware returns a middleware recording into digests created
+// with the given compression and options. The route function names the
+// route of a request once it has been served, so it can use what the
+// handler set, such as the matched pattern of a ServeMux; requests for
+// which it returns an empty name, and all requests if it is nil, are only
+// recorded into the digest for all requests. Route names should come from
+// a small set, since every route keeps a digest for the lifetime of the
+// middleware.
+func NewLatencyMiddleware(compression float64, route func(r *http.Request) string, opts ...Option) *LatencyMiddleware {
+	return &LatencyMiddleware{
+		compression: compression,
+		opts:        opts,
+		route:       route,
+		all:         NewSharded(compression, 0, opts...),
+		routes:      make(map[string]*ShardedTDigest),
+	}
+}
+
+// Wrap returns a handler calling next and recording the duration of every
+// request, including those that panic.
+func (m *LatencyMiddleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		defer func() {
+			m.Observe(m.routeOf(r), time.Since(start))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *LatencyMiddleware) routeOf(r *http.Request) string {
+	if m.route == nil {
+		return ""
+	}
+	return m.route(r)
+}
+
+// Observe records a request duration for the given route, which may be
+// empty, as if it had been served through Wrap.
+func (m *LatencyMiddleware) Observe(route string, d time.Duration) {
+	s := d.Seconds()
+	m.all.Add(s, 1)
+	if route != "" {
+		m.routeDigest(route).Add(s, 1)
+	}
+}
+
+// Merge merges the supplied digest of request durations for the given
+// route, which may be empty, e.g. one fetched from another instance, into
+// the digests of the middleware. t2 must not be in use by other goroutines.
+// In strict mode, it returns the errors of TDigest.MergeErr.
+func (m *LatencyMiddleware) Merge(route string, t2 *TDigest) error {
+	if err := m.all.Merge(t2); err != nil {
+		return err
+	}
+	if route != "" {
+		return m.routeDigest(route).Merge(t2)
+	}
+	return nil
+}
+
+// routeDigest returns the digest of a route, creating it if needed.
+func (m *LatencyMiddleware) routeDigest(route string) *ShardedTDigest {
+	m.mu.RLock()
+	s := m.routes[route]
+	m.mu.RUnlock()
+	if s != nil {
+		return s
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s = m.routes[route]; s == nil {
+		s = NewSharded(m.compression, 0, m.opts...)
+		m.routes[route] = s
+	}
+	return s
+}
+
+// All returns the digest of all requests.
+func (m *LatencyMiddleware) All() *ShardedTDigest {
+	return m.all
+}
+
+// Route returns the digest of the given route, or nil if no request has
+// been recorded for it.
+func (m *LatencyMiddleware) Route(route string) *ShardedTDigest {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.routes[route]
+}
+
+// Routes returns the names of the routes with recorded requests, sorted.
+func (m *LatencyMiddleware) Routes() []string {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.routes))
+	for name := range m.routes {
+		names = append(names, name)
+	}
+	m.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,77 @@
+package tdigest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestLatencyMiddleware(t *testing.T) {
+	m := tdigest.NewLatencyMiddleware(100, func(r *http.Request) string {
+		return r.Header.Get("Route")
+	})
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			r.Header.Set("Route", "slow")
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fast", nil))
+			}
+		}()
+	}
+	wg.Wait()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+
+	if got := m.All().Count(); got != 81 {
+		t.Errorf("unexpected count of all requests, got %g want 81", got)
+	}
+	if got := m.Routes(); !reflect.DeepEqual(got, []string{"slow"}) {
+		t.Errorf("unexpected routes %v", got)
+	}
+	slow := m.Route("slow")
+	if slow == nil || slow.Count() != 1 || slow.Quantile(0.5) < 0.01 {
+		t.Error("slow request not recorded")
+	}
+	if m.Route("fast") != nil {
+		t.Error("unexpected digest for unnamed route")
+	}
+
+	remote := tdigest.NewWithCompression(100)
+	remote.AddValues(1, 2)
+	if err := m.Merge("slow", remote); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Route("slow").Count(); got != 3 {
+		t.Errorf("unexpected count after merge, got %g want 3", got)
+	}
+	if got := m.All().Quantile(1); got != 2 {
+		t.Errorf("unexpected maximum after merge, got %g want 2", got)
+	}
+}
+
+func TestLatencyMiddlewarePanic(t *testing.T) {
+	m := tdigest.NewLatencyMiddleware(100, nil)
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	func() {
+		defer func() { recover() }()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	if got := m.All().Count(); got != 1 {
+		t.Errorf("unexpected count, got %g want 1", got)
+	}
+}
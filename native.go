@@ -0,0 +1,138 @@
+package tdigest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ErrNativeHistogramUnsupported is returned when converting a digest that
+// cannot be represented as a native histogram.
+const ErrNativeHistogramUnsupported = Error("digest cannot be represented as a native histogram")
+
+// Schemas supported by Prometheus native histograms.
+const (
+	minNativeSchema = -4
+	maxNativeSchema = 8
+)
+
+// NativeHistogram is a Prometheus native histogram with integer counts, with
+// the fields of the histogram messages of client_golang and remote write.
+// Its buckets have exponentially growing widths: with the schema s, bucket
+// i counts the values in (base^(i-1), base^i], where base is 2^(2^-s), or
+// the negative of those values for the negative buckets. The non-empty
+// buckets are grouped into spans of consecutive buckets, and their counts
+// are stored as differences to the previous count.
+type NativeHistogram struct {
+	Schema         int32
+	ZeroThreshold  float64
+	ZeroCount      uint64
+	Count          uint64
+	Sum            float64
+	PositiveSpans  []BucketSpan
+	PositiveDeltas []int64
+	NegativeSpans  []BucketSpan
+	NegativeDeltas []int64
+}
+
+// BucketSpan is a run of Length consecutive buckets of a native histogram,
+// starting Offset buckets after the end of the previous span, or at bucket
+// Offset for the first span.
+type BucketSpan struct {
+	Offset int32
+	Length uint32
+}
+
+// ToNativeHistogram converts the digest into a native histogram with the
+// given schema, between -4 and 8, processing any pending centroids first.
+// Centroids with a mean of at most zeroThreshold in absolute value go into
+// the zero bucket. The weight of every centroid goes into the bucket of its
+// mean, with weights rounded so that the total count is preserved. Infinite
+// means are reported as ErrNativeHistogramUnsupported.
+func (t *TDigest) ToNativeHistogram(schema int32, zeroThreshold float64) (*NativeHistogram, error) {
+	if schema < minNativeSchema || schema > maxNativeSchema {
+		return nil, fmt.Errorf("%w: schema %d", ErrNativeHistogramUnsupported, schema)
+	}
+	if !(zeroThreshold >= 0) || math.IsInf(zeroThreshold, 1) {
+		return nil, fmt.Errorf("%w: zero threshold %g", ErrNativeHistogramUnsupported, zeroThreshold)
+	}
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, err
+	}
+
+	h := &NativeHistogram{Schema: schema, ZeroThreshold: zeroThreshold}
+	positive, negative := make(map[int32]uint64), make(map[int32]uint64)
+	var soFar float64
+	for i, c := range t.processed {
+		if math.IsInf(c.Mean, 0) {
+			return nil, fmt.Errorf("%w: centroid %d: infinite mean", ErrNativeHistogramUnsupported, i)
+		}
+		soFar += c.Weight
+		h.Sum += c.Mean * c.Weight
+		n := uint64(math.Round(soFar)) - h.Count
+		if n == 0 {
+			continue
+		}
+		h.Count += n
+		switch {
+		case math.Abs(c.Mean) <= zeroThreshold:
+			h.ZeroCount += n
+		case c.Mean > 0:
+			positive[nativeIndex(c.Mean, schema)] += n
+		default:
+			negative[nativeIndex(-c.Mean, schema)] += n
+		}
+	}
+	h.PositiveSpans, h.PositiveDeltas = nativeBuckets(positive)
+	h.NegativeSpans, h.NegativeDeltas = nativeBuckets(negative)
+	return h, nil
+}
+
+// nativeIndex returns the index of the bucket of the positive value x.
+func nativeIndex(x float64, schema int32) int32 {
+	frac, exp := math.Frexp(x)
+	if schema <= 0 {
+		// Buckets are powers of two, so the exponent decides.
+		if frac == 0.5 {
+			exp--
+		}
+		offset := (1 << uint(-schema)) - 1
+		return int32((exp + offset) >> uint(-schema))
+	}
+	// frac is in [0.5, 1), so its logarithm is in [-1, 0).
+	scale := float64(int(1) << uint(schema))
+	return int32(exp<<uint(schema)) + int32(math.Ceil(math.Log2(frac)*scale))
+}
+
+// nativeBuckets returns the spans and deltas of the given bucket counts.
+func nativeBuckets(counts map[int32]uint64) ([]BucketSpan, []int64) {
+	if len(counts) == 0 {
+		return nil, nil
+	}
+	indexes := make([]int, 0, len(counts))
+	for i := range counts {
+		indexes = append(indexes, int(i))
+	}
+	sort.Ints(indexes)
+
+	var spans []BucketSpan
+	deltas := make([]int64, 0, len(indexes))
+	var prevIndex int
+	var prevCount int64
+	for k, i := range indexes {
+		switch {
+		case k == 0:
+			spans = append(spans, BucketSpan{Offset: int32(i), Length: 1})
+		case i == prevIndex+1:
+			spans[len(spans)-1].Length++
+		default:
+			spans = append(spans, BucketSpan{Offset: int32(i - prevIndex - 1), Length: 1})
+		}
+		count := int64(counts[int32(i)])
+		deltas = append(deltas, count-prevCount)
+		prevIndex, prevCount = i, count
+	}
+	return spans, deltas
+}
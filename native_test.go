@@ -0,0 +1,77 @@
+package tdigest_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_ToNativeHistogram(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddValues(-3, 0.0001, 1, 1.5, 2, 3, 4, 100)
+	h, err := td.ToNativeHistogram(0, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &tdigest.NativeHistogram{
+		Schema:         0,
+		ZeroThreshold:  0.001,
+		ZeroCount:      1,
+		Count:          8,
+		Sum:            108.5001,
+		PositiveSpans:  []tdigest.BucketSpan{{Offset: 0, Length: 3}, {Offset: 4, Length: 1}},
+		PositiveDeltas: []int64{1, 1, 0, -1},
+		NegativeSpans:  []tdigest.BucketSpan{{Offset: 2, Length: 1}},
+		NegativeDeltas: []int64{1},
+	}
+	if math.Abs(h.Sum-want.Sum) < 1e-9 {
+		h.Sum = want.Sum
+	}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("unexpected histogram\ngot  %+v\nwant %+v", h, want)
+	}
+
+	// Buckets of the same values at a finer schema.
+	h, _ = td.ToNativeHistogram(1, 0.001)
+	wantSpans := []tdigest.BucketSpan{{Offset: 0, Length: 1}, {Offset: 1, Length: 1}, {Offset: 1, Length: 1}, {Offset: 9, Length: 1}}
+	if !reflect.DeepEqual(h.PositiveSpans, wantSpans) || !reflect.DeepEqual(h.PositiveDeltas, []int64{1, 1, 0, -1}) {
+		t.Errorf("unexpected buckets %v %v", h.PositiveSpans, h.PositiveDeltas)
+	}
+
+	// Counts are preserved for large digests.
+	h, err = NormalDigest.ToNativeHistogram(3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if float64(h.Count) != NormalDigest.Count() {
+		t.Errorf("unexpected count, got %d want %g", h.Count, NormalDigest.Count())
+	}
+	var total int64
+	for _, deltas := range [][]int64{h.PositiveDeltas, h.NegativeDeltas} {
+		count := int64(0)
+		for _, d := range deltas {
+			count += d
+			total += count
+		}
+	}
+	if uint64(total)+h.ZeroCount != h.Count {
+		t.Errorf("buckets add up to %d, want %d", uint64(total)+h.ZeroCount, h.Count)
+	}
+}
+
+func TestTdigest_ToNativeHistogramErrors(t *testing.T) {
+	inf := tdigest.NewWithCompression(100)
+	inf.AddValues(1, math.Inf(1))
+	for _, err := range []error{
+		func() error { _, err := inf.ToNativeHistogram(0, 0); return err }(),
+		func() error { _, err := tdigest.New().ToNativeHistogram(9, 0); return err }(),
+		func() error { _, err := tdigest.New().ToNativeHistogram(0, -1); return err }(),
+	} {
+		if !errors.Is(err, tdigest.ErrNativeHistogramUnsupported) {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package tdigest
+
+import "math"
+
+// ErrNonFiniteValue is used when an infinite value is rejected.
+const ErrNonFiniteValue = Error("value is infinite")
+
+// errDropped is used internally for samples dropped silently by policy.
+const errDropped = Error("sample dropped")
+
+// NonFinitePolicy controls how a digest handles NaN and infinite values.
+// Whatever the policy, NaN values are never added to a digest.
+type NonFinitePolicy int
+
+const (
+	// NonFiniteKeepInf adds infinite values like any other value, and
+	// ignores NaN values, which are reported by AddErr. This is the default.
+	NonFiniteKeepInf NonFinitePolicy = iota
+	// NonFiniteDrop silently ignores NaN and infinite values, which are
+	// still counted, see NonFiniteCount.
+	NonFiniteDrop
+	// NonFiniteClamp replaces infinite values by the current minimum or
+	// maximum of the digest. Infinite values added to an empty digest, and
+	// NaN values, are ignored and reported by AddErr.
+	NonFiniteClamp
+	// NonFiniteReject ignores NaN and infinite values, and reports them from
+	// AddErr and AddWeightedSlice.
+	NonFiniteReject
+)
+
+// WithNonFinitePolicy sets how the digest handles NaN and infinite values.
+func WithNonFinitePolicy(p NonFinitePolicy) Option {
+	return func(t *TDigest) {
+		t.nonFinite = p
+	}
+}
+
+// NonFiniteCount returns the number of NaN and infinite values ignored
+// since the digest was created or last reset.
+func (t *TDigest) NonFiniteCount() uint64 {
+	t.lock()
+	defer t.unlock()
+	return t.rejected[RejectNaNValue] + t.rejected[RejectInfiniteValue]
+}
+
+// nonFiniteValue applies the policy of the digest to the NaN or infinite
+// value x, given the current minimum and maximum. It returns the value to
+// add instead, or an error if the value is to be dropped.
+// Values that are dropped are counted as rejected.
+func (t *TDigest) nonFiniteValue(x, min, max float64) (float64, error) {
+	y, err := t.applyNonFinite(x, min, max)
+	if err != nil {
+		if math.IsNaN(x) {
+			t.rejected[RejectNaNValue]++
+		} else {
+			t.rejected[RejectInfiniteValue]++
+		}
+	}
+	return y, err
+}
+
+func (t *TDigest) applyNonFinite(x, min, max float64) (float64, error) {
+	switch t.nonFinite {
+	case NonFiniteDrop:
+		return x, errDropped
+	}
+	if math.IsNaN(x) {
+		return x, ErrNaNValue
+	}
+	switch t.nonFinite {
+	case NonFiniteClamp:
+		if min > max {
+			return x, ErrNonFiniteValue
+		}
+		if x > 0 {
+			return max, nil
+		}
+		return min, nil
+	case NonFiniteReject:
+		return x, ErrNonFiniteValue
+	}
+	return x, nil
+}
@@ -0,0 +1,80 @@
+package tdigest_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestWithNonFinitePolicy(t *testing.T) {
+	inf, nan := math.Inf(1), math.NaN()
+	tests := []struct {
+		name          string
+		policy        tdigest.NonFinitePolicy
+		wantErrs      []error
+		wantCount     float64
+		wantMax       float64
+		wantNonFinite uint64
+	}{
+		{
+			name:          "keep infinite",
+			policy:        tdigest.NonFiniteKeepInf,
+			wantErrs:      []error{nil, tdigest.ErrNaNValue, nil},
+			wantCount:     7,
+			wantMax:       inf,
+			wantNonFinite: 3,
+		},
+		{
+			name:          "drop",
+			policy:        tdigest.NonFiniteDrop,
+			wantErrs:      []error{nil, nil, nil},
+			wantCount:     3,
+			wantMax:       3,
+			wantNonFinite: 7,
+		},
+		{
+			name:          "clamp",
+			policy:        tdigest.NonFiniteClamp,
+			wantErrs:      []error{tdigest.ErrNonFiniteValue, tdigest.ErrNaNValue, nil},
+			wantCount:     6,
+			wantMax:       3,
+			wantNonFinite: 4,
+		},
+		{
+			name:          "reject",
+			policy:        tdigest.NonFiniteReject,
+			wantErrs:      []error{tdigest.ErrNonFiniteValue, tdigest.ErrNaNValue, tdigest.ErrNonFiniteValue},
+			wantCount:     3,
+			wantMax:       3,
+			wantNonFinite: 7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := tdigest.New(tdigest.WithNonFinitePolicy(tt.policy))
+			// +Inf into an empty digest, NaN, then -Inf into a non-empty
+			// digest, interleaved with regular values.
+			errs := []error{td.AddErr(inf, 1), td.AddErr(nan, 1)}
+			td.AddSlice([]float64{1, 2, 3})
+			errs = append(errs, td.AddErr(math.Inf(-1), 1))
+			td.AddSlice([]float64{nan, inf})
+			td.AddSorted([]float64{inf, nan})
+
+			for i, err := range errs {
+				if err != tt.wantErrs[i] {
+					t.Errorf("unexpected error %d, got %v want %v", i, err, tt.wantErrs[i])
+				}
+			}
+			if got := td.Count(); got != tt.wantCount {
+				t.Errorf("unexpected count, got %g want %g", got, tt.wantCount)
+			}
+			if got := td.Quantile(1); got != tt.wantMax {
+				t.Errorf("unexpected max, got %g want %g", got, tt.wantMax)
+			}
+			if got := td.NonFiniteCount(); got != tt.wantNonFinite {
+				t.Errorf("unexpected non-finite count, got %d want %d", got, tt.wantNonFinite)
+			}
+		})
+	}
+}
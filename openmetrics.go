@@ -0,0 +1,80 @@
+package tdigest
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var openMetricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteOpenMetricsSummary writes the digest to w as an OpenMetrics summary
+// metric family with the given name and labels: a TYPE line, a sample for
+// each of the given quantiles, and the _sum and _count samples. The name and
+// label names must be valid metric and label names, and must not include
+// the quantile label. Labels are sorted by name. The caller writes the
+// terminating "# EOF" line once all families of the exposition are written.
+// In strict mode it returns ErrUnflushed, writing nothing, if the digest has
+// pending centroids.
+func (t *TDigest) WriteOpenMetricsSummary(w io.Writer, name string, labels map[string]string, quantiles []float64) error {
+	r, err := t.quantileReport(quantiles)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var ls []byte
+	for _, k := range names {
+		ls = append(ls, k...)
+		ls = append(ls, `="`...)
+		ls = append(ls, openMetricsLabelEscaper.Replace(labels[k])...)
+		ls = append(ls, `",`...)
+	}
+
+	buf := append([]byte("# TYPE "), name...)
+	buf = append(buf, " summary\n"...)
+	sample := func(suffix string, quantile string, x float64) {
+		buf = append(buf, name...)
+		buf = append(buf, suffix...)
+		if len(ls) > 0 || quantile != "" {
+			buf = append(buf, '{')
+			buf = append(buf, ls...)
+			if quantile != "" {
+				buf = append(buf, `quantile="`...)
+				buf = append(buf, quantile...)
+				buf = append(buf, `"}`...)
+			} else {
+				buf[len(buf)-1] = '}'
+			}
+		}
+		buf = append(buf, ' ')
+		buf = appendOpenMetricsFloat(buf, x)
+		buf = append(buf, '\n')
+	}
+	for i, q := range quantiles {
+		sample("", strconv.FormatFloat(q, 'g', -1, 64), r.quantiles[i])
+	}
+	sample("_sum", "", r.sum)
+	sample("_count", "", r.count)
+	_, err = w.Write(buf)
+	return err
+}
+
+// appendOpenMetricsFloat appends x in the OpenMetrics number format.
+func appendOpenMetricsFloat(buf []byte, x float64) []byte {
+	switch {
+	case math.IsNaN(x):
+		return append(buf, "NaN"...)
+	case math.IsInf(x, 1):
+		return append(buf, "+Inf"...)
+	case math.IsInf(x, -1):
+		return append(buf, "-Inf"...)
+	}
+	return strconv.AppendFloat(buf, x, 'g', -1, 64)
+}
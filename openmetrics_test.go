@@ -0,0 +1,54 @@
+package tdigest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_WriteOpenMetricsSummary(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddValues(1, 2, 3, 4)
+	tests := []struct {
+		name      string
+		td        *tdigest.TDigest
+		labels    map[string]string
+		quantiles []float64
+		want      string
+	}{
+		{
+			name:      "digest",
+			td:        td,
+			labels:    map[string]string{"path": `/a"b\`, "code": "200"},
+			quantiles: []float64{0.5, 0.99},
+			want: `# TYPE latency summary
+latency{code="200",path="/a\"b\\",quantile="0.5"} 2.5
+latency{code="200",path="/a\"b\\",quantile="0.99"} 4
+latency_sum{code="200",path="/a\"b\\"} 10
+latency_count{code="200",path="/a\"b\\"} 4
+`,
+		},
+		{
+			name:      "empty",
+			td:        tdigest.NewWithCompression(100),
+			quantiles: []float64{0.5},
+			want: `# TYPE latency summary
+latency{quantile="0.5"} NaN
+latency_sum 0
+latency_count 0
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := tt.td.WriteOpenMetricsSummary(&b, "latency", tt.labels, tt.quantiles); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("unexpected exposition, got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
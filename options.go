@@ -0,0 +1,61 @@
+package tdigest
+
+import "math"
+
+// Option configures optional behavior of a digest at construction.
+type Option func(*TDigest)
+
+// WithCompression sets the compression of the digest, overriding the one
+// given to the constructor; see NewWithCompression.
+func WithCompression(c float64) Option {
+	return func(t *TDigest) {
+		t.Compression = c
+	}
+}
+
+// WithBufferSizes sets the number of processed centroids retained before the
+// digest is compressed, and the number of incoming centroids buffered, like
+// the sizes of a Profile. A size that is not positive is derived from the
+// compression instead. Changing the compression later, e.g. by
+// ResetWithCompression, derives both sizes from the compression again.
+func WithBufferSizes(processed, unprocessed int) Option {
+	return func(t *TDigest) {
+		t.maxProcessed, t.maxUnprocessed = 0, 0
+		if processed > 0 {
+			t.maxProcessed = processed
+		}
+		if unprocessed > 0 {
+			t.maxUnprocessed = unprocessed
+		}
+	}
+}
+
+// WithBufferFactors sets the sizes of the processed and unprocessed centroid
+// buffers as multiples of the compression, which default to 2 and 8. Smaller
+// factors reduce the memory held by each digest, at the expense of
+// compressing more often, and hence throughput. The processed factor is
+// raised to at least 1, so that a compressed digest always fits. Factors that
+// are not positive keep their default, and sizes set explicitly by a Profile
+// or WithBufferSizes take precedence.
+func WithBufferFactors(processed, unprocessed float64) Option {
+	return func(t *TDigest) {
+		if processed > 0 {
+			t.processedFactor = math.Max(processed, 1)
+		}
+		if unprocessed > 0 {
+			t.unprocessedFactor = unprocessed
+		}
+	}
+}
+
+// WithDeferredCompaction stops the digest from compressing its centroids
+// when the buffer of incoming centroids fills up, so that adding never
+// incurs the latency of processing. Instead the buffer grows until the
+// digest is processed by Flush, or by a query. Callers must flush the digest
+// regularly to bound its memory, e.g. from a background goroutine holding
+// the same lock as the writers.
+func WithDeferredCompaction() Option {
+	return func(t *TDigest) {
+		t.deferred = true
+	}
+}
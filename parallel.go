@@ -0,0 +1,215 @@
+package tdigest
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+// minParallelSize is the smallest number of inputs per goroutine for which
+// clustering in parallel is worthwhile.
+const minParallelSize = 1 << 14
+
+// NewFromSamplesParallel initializes a new distribution with custom
+// compression holding the given samples, each with a weight of one. The
+// samples are split across GOMAXPROCS goroutines, each building a partial
+// digest, which are merged into the result.
+func NewFromSamplesParallel(c float64, xs []float64) *TDigest {
+	t := NewWithCompression(c)
+	t.AddSliceParallel(xs, 0)
+	return t
+}
+
+// BuildFromSlice initializes a new distribution with the default compression
+// holding the given samples, each with a weight of one, using up to
+// parallelism goroutines like AddSliceParallel.
+func BuildFromSlice(xs []float64, parallelism int, opts ...Option) *TDigest {
+	t := New(opts...)
+	t.AddSliceParallel(xs, parallelism)
+	return t
+}
+
+// AddSliceParallel adds a large slice of values, like AddSlice, but first
+// clusters contiguous stripes of the slice into partial digests using up to
+// parallelism goroutines, then merges those. A parallelism below one uses
+// GOMAXPROCS. Slices too small to benefit are added sequentially.
+//
+// The partial digests share the configuration of t, such as its non-finite
+// value policy, apart from its lock, hooks and watches. Infinite values are
+// clamped by NonFiniteClamp to the extremes seen so far by their stripe.
+func (t *TDigest) AddSliceParallel(xs []float64, parallelism int) {
+	shards := t.buildShards(len(xs), parallelism, func(td *TDigest, lo, hi int) {
+		td.AddSlice(xs[lo:hi])
+	})
+	if shards == nil {
+		t.AddSlice(xs)
+		return
+	}
+	t.mergeShards(shards)
+}
+
+// AddCentroidListParallel adds a large list of centroids, like
+// AddCentroidList, but first clusters contiguous stripes of the list into
+// partial digests using up to parallelism goroutines, then merges those.
+// A parallelism below one uses GOMAXPROCS. Lists too small to benefit are
+// added sequentially.
+//
+// The result is as accurate as adding the centroids one at a time, but is
+// not bit-for-bit identical since centroids are clustered in a different
+// order. The partial digests are configured like those of AddSliceParallel.
+func (t *TDigest) AddCentroidListParallel(c CentroidList, parallelism int) {
+	shards := t.buildShards(len(c), parallelism, func(td *TDigest, lo, hi int) {
+		td.AddCentroidList(c[lo:hi])
+	})
+	if shards == nil {
+		t.AddCentroidList(c)
+		return
+	}
+	t.mergeShards(shards)
+}
+
+// buildShards splits n inputs into contiguous stripes and calls add for each
+// stripe on its own goroutine, with a fresh digest configured like t, which
+// it then flushes. It returns nil if n is too small to split.
+func (t *TDigest) buildShards(n, parallelism int, add func(td *TDigest, lo, hi int)) []*TDigest {
+	if parallelism < 1 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+	if max := n / minParallelSize; parallelism > max {
+		parallelism = max
+	}
+	if parallelism < 2 {
+		return nil
+	}
+
+	shards := make([]*TDigest, parallelism)
+	t.lock()
+	for i := range shards {
+		shards[i] = t.newShard()
+	}
+	t.unlock()
+	var wg sync.WaitGroup
+	for i := range shards {
+		wg.Add(1)
+		go func(td *TDigest, lo, hi int) {
+			defer wg.Done()
+			add(td, lo, hi)
+			td.process()
+		}(shards[i], i*n/parallelism, (i+1)*n/parallelism)
+	}
+	wg.Wait()
+	return shards
+}
+
+// newShard returns an empty digest with the configuration of t, apart from
+// its lock, hooks, watches and atomic stats, which only concern t itself. It
+// starts out with the extremes of t, for the non-finite value policy to
+// clamp to.
+func (t *TDigest) newShard() *TDigest {
+	s := &TDigest{
+		Compression:       t.Compression,
+		maxProcessed:      t.maxProcessed,
+		maxUnprocessed:    t.maxUnprocessed,
+		processedFactor:   t.processedFactor,
+		unprocessedFactor: t.unprocessedFactor,
+		strict:            t.strict,
+		tracing:           t.tracing,
+		deferred:          t.deferred,
+		exactThreshold:    t.exactThreshold,
+		nonFinite:         t.nonFinite,
+	}
+	s.allocate()
+	s.reset()
+	s.min, s.max = t.min, t.max
+	return s
+}
+
+// mergeShards merges the flushed shards returned by buildShards into t,
+// along with the samples they rejected. Unlike MergeErr, it does so in
+// strict mode too, since the shards merely hold samples added to t.
+func (t *TDigest) mergeShards(shards []*TDigest) {
+	t.lock()
+	defer t.unlock()
+	for _, s := range shards {
+		t.merge(s)
+		for r, n := range s.rejected {
+			t.rejected[r] += n
+		}
+	}
+}
+
+// ParallelMergeAll returns a new digest holding the merged data of all the
+// given digests, with the largest of their compressions. The digests are
+// merged pairwise in a binary tree, with the merges at each level of the tree
+// spread over up to workers goroutines. A number of workers below one uses
+// GOMAXPROCS. Nil digests are ignored, and the given digests are left
+// unchanged, apart from processing any pending centroids; a digest must not
+// be listed more than once.
+func ParallelMergeAll(ds []*TDigest, workers int) *TDigest {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	var inputs []*TDigest
+	c := 0.0
+	for _, d := range ds {
+		if d != nil {
+			inputs = append(inputs, d)
+			d.lock()
+			c = math.Max(c, d.Compression)
+			d.unlock()
+		}
+	}
+	if len(inputs) == 0 {
+		return New()
+	}
+
+	// The first level merges pairs of inputs into new digests, which later
+	// levels merge into each other.
+	level := make([]*TDigest, (len(inputs)+1)/2)
+	forEachParallel(len(level), workers, func(i int) {
+		td := NewWithCompression(c)
+		td.mergeLocked(inputs[2*i])
+		if 2*i+1 < len(inputs) {
+			td.mergeLocked(inputs[2*i+1])
+		}
+		level[i] = td
+	})
+	for len(level) > 1 {
+		next := level[:(len(level)+1)/2]
+		forEachParallel(len(level)/2, workers, func(i int) {
+			level[2*i].merge(level[2*i+1])
+		})
+		for i := range next {
+			next[i] = level[2*i]
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// mergeLocked merges t2 into t while holding the lock of t2, but not that of
+// t, which must not be shared yet.
+func (t *TDigest) mergeLocked(t2 *TDigest) {
+	t2.lock()
+	t.merge(t2)
+	t2.unlock()
+}
+
+// forEachParallel calls f for each index below n, on up to workers
+// goroutines, and waits for all calls to return.
+func forEachParallel(n, workers int, f func(i int)) {
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,261 @@
+package tdigest_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_AddCentroidListParallel(t *testing.T) {
+	centroids := make(tdigest.CentroidList, len(NormalData))
+	for i := range centroids {
+		centroids[i].Mean = NormalData[i]
+		centroids[i].Weight = 1
+	}
+
+	for _, parallelism := range []int{0, 1, 4, 7} {
+		td := tdigest.NewWithCompression(1000)
+		td.AddCentroidListParallel(centroids, parallelism)
+		if err := compareQuantiles(td, NormalDigest, 0.001); err != nil {
+			t.Errorf("parallelism %d differs from sequential: %s", parallelism, err.Error())
+		}
+		if got, want := td.Quantile(0), NormalDigest.Quantile(0); got != want {
+			t.Errorf("parallelism %d: unexpected min, got %g want %g", parallelism, got, want)
+		}
+	}
+
+	small := tdigest.NewWithCompression(3)
+	small.AddCentroidListParallel(tdigest.CentroidList{{Mean: 1, Weight: 1}, {Mean: 2, Weight: 1}}, 8)
+	if got := small.Count(); got != 2 {
+		t.Errorf("unexpected count for small list, got %g want 2", got)
+	}
+}
+
+func TestNewFromSamplesParallel(t *testing.T) {
+	for _, data := range [][]float64{NormalData, UniformData} {
+		td := tdigest.NewFromSamplesParallel(1000, data)
+		want := tdigest.NewWithCompression(1000)
+		for _, x := range data {
+			want.Add(x, 1)
+		}
+		if err := compareQuantiles(td, want, 0.001); err != nil {
+			t.Errorf("parallel build differs from sequential: %s", err.Error())
+		}
+	}
+
+	td := tdigest.NewFromSamplesParallel(100, []float64{1, 2, 3})
+	if got := td.Quantile(0.5); got != 2 {
+		t.Errorf("unexpected median of small input, got %g want 2", got)
+	}
+}
+
+func TestBuildFromSlice(t *testing.T) {
+	for _, parallelism := range []int{0, 1, 4, 7} {
+		td := tdigest.BuildFromSlice(NormalData, parallelism)
+		if err := compareQuantiles(td, NormalDigest, 0.001); err != nil {
+			t.Errorf("parallelism %d differs from sequential: %s", parallelism, err.Error())
+		}
+		if got, want := td.Quantile(1), NormalDigest.Quantile(1); got != want {
+			t.Errorf("parallelism %d: unexpected max, got %g want %g", parallelism, got, want)
+		}
+	}
+
+	td := tdigest.BuildFromSlice([]float64{3, 1, 2}, 4, tdigest.WithExactThreshold(10))
+	if got := td.Quantile(0.5); got != 2 {
+		t.Errorf("unexpected median of small input, got %g want 2", got)
+	}
+}
+
+func BenchmarkTDigest_AddCentroidListParallel(b *testing.B) {
+	centroids := make(tdigest.CentroidList, len(NormalData))
+	for i := range centroids {
+		centroids[i].Mean = NormalData[i]
+		centroids[i].Weight = 1
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		td := tdigest.NewWithCompression(1000)
+		td.AddCentroidListParallel(centroids, 0)
+	}
+}
+
+func TestParallelMergeAll(t *testing.T) {
+	for _, n := range []int{1, 2, 7, 64} {
+		ds := make([]*tdigest.TDigest, n+1)
+		size := len(NormalData) / n
+		for i := 0; i < n; i++ {
+			ds[i] = tdigest.NewWithCompression(1000)
+			ds[i].AddSlice(NormalData[i*size : (i+1)*size])
+		}
+		// A nil digest is ignored.
+		ds[n] = nil
+		before := ds[0].Count()
+
+		for _, workers := range []int{0, 1, 3} {
+			td := tdigest.ParallelMergeAll(ds, workers)
+			if got, want := td.Count(), float64(n*size); got != want {
+				t.Errorf("%d digests, %d workers: unexpected count, got %g want %g", n, workers, got, want)
+			}
+			if got, want := td.Quantile(0.5), NormalDigest.Quantile(0.5); math.Abs(got-want)/want > 0.001 {
+				t.Errorf("%d digests, %d workers: unexpected median, got %g want %g", n, workers, got, want)
+			}
+		}
+		if got := ds[0].Count(); got != before {
+			t.Errorf("%d digests: input modified, got count %g want %g", n, got, before)
+		}
+	}
+
+	if got := tdigest.ParallelMergeAll(nil, 0).Count(); got != 0 {
+		t.Errorf("unexpected count of empty merge, got %g", got)
+	}
+}
+
+// nonFiniteSlice returns a slice large enough to be added in parallel, with
+// NaN and infinite values sprinkled in.
+func nonFiniteSlice() []float64 {
+	xs := make([]float64, 1<<17)
+	for i := range xs {
+		switch {
+		case i%97 == 0:
+			xs[i] = math.Inf(1)
+		case i%101 == 0:
+			xs[i] = math.Inf(-1)
+		case i%103 == 0:
+			xs[i] = math.NaN()
+		default:
+			xs[i] = float64(i % 1000)
+		}
+	}
+	return xs
+}
+
+func TestTdigest_AddSliceParallelOptions(t *testing.T) {
+	xs := nonFiniteSlice()
+	tests := []struct {
+		name string
+		opts []tdigest.Option
+	}{
+		{name: "keep inf"},
+		{name: "drop", opts: []tdigest.Option{tdigest.WithNonFinitePolicy(tdigest.NonFiniteDrop)}},
+		{name: "clamp", opts: []tdigest.Option{tdigest.WithNonFinitePolicy(tdigest.NonFiniteClamp)}},
+		{name: "reject", opts: []tdigest.Option{tdigest.WithNonFinitePolicy(tdigest.NonFiniteReject)}},
+		{name: "exact", opts: []tdigest.Option{tdigest.WithExactThreshold(1 << 18)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Seed both digests, so that clamping never depends on the
+			// order in which values are added.
+			want := tdigest.NewWithCompression(100, tt.opts...)
+			want.Add(500, 1)
+			want.AddSlice(xs)
+			got := tdigest.NewWithCompression(100, tt.opts...)
+			got.Add(500, 1)
+			got.AddSliceParallel(xs, 4)
+
+			if g, w := got.Count(), want.Count(); g != w {
+				t.Errorf("unexpected count, got %g want %g", g, w)
+			}
+			if g, w := got.SampleCount(), want.SampleCount(); g != w {
+				t.Errorf("unexpected sample count, got %d want %d", g, w)
+			}
+			for r := tdigest.RejectNaNValue; r <= tdigest.RejectInfiniteWeight; r++ {
+				if g, w := got.Rejected(r), want.Rejected(r); g != w {
+					t.Errorf("unexpected %v rejects, got %d want %d", r, g, w)
+				}
+			}
+			if g, w := got.Exact(), want.Exact(); g != w {
+				t.Errorf("unexpected exact, got %v want %v", g, w)
+			}
+			for _, q := range []float64{0, 0.5, 1} {
+				g, w := got.Quantile(q), want.Quantile(q)
+				if math.Abs(g-w) > 5 && g != w {
+					t.Errorf("unexpected quantile %g, got %g want %g", q, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestTdigest_AddSliceParallelStrict(t *testing.T) {
+	xs := UniformData[:1<<16]
+	td := tdigest.NewWithCompression(100, tdigest.WithStrict())
+	td.AddSliceParallel(xs, 4)
+	if got, want := td.Count(), float64(len(xs)); got != want {
+		t.Errorf("unexpected count, got %g want %g", got, want)
+	}
+	td.Flush()
+	want := tdigest.NewWithCompression(100)
+	want.AddSlice(xs)
+	if err := compareQuantiles(td, want, 0.01); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTdigest_AddCentroidListParallelOptions(t *testing.T) {
+	xs := nonFiniteSlice()
+	centroids := make(tdigest.CentroidList, len(xs))
+	for i, x := range xs {
+		centroids[i] = tdigest.Centroid{Mean: x, Weight: float64(i%3) - 0.5}
+	}
+	tests := []struct {
+		name string
+		opts []tdigest.Option
+	}{
+		{name: "keep inf"},
+		{name: "drop", opts: []tdigest.Option{tdigest.WithNonFinitePolicy(tdigest.NonFiniteDrop)}},
+		{name: "clamp", opts: []tdigest.Option{tdigest.WithNonFinitePolicy(tdigest.NonFiniteClamp)}},
+		{name: "reject", opts: []tdigest.Option{tdigest.WithNonFinitePolicy(tdigest.NonFiniteReject)}},
+		{name: "exact", opts: []tdigest.Option{tdigest.WithExactThreshold(1 << 18)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tdigest.NewWithCompression(100, tt.opts...)
+			want.Add(500, 1)
+			want.AddCentroidList(centroids)
+			got := tdigest.NewWithCompression(100, tt.opts...)
+			got.Add(500, 1)
+			got.AddCentroidListParallel(centroids, 4)
+
+			if g, w := got.Count(), want.Count(); g != w {
+				t.Errorf("unexpected count, got %g want %g", g, w)
+			}
+			if g, w := got.SampleCount(), want.SampleCount(); g != w {
+				t.Errorf("unexpected sample count, got %d want %d", g, w)
+			}
+			for r := tdigest.RejectNaNValue; r <= tdigest.RejectInfiniteWeight; r++ {
+				if g, w := got.Rejected(r), want.Rejected(r); g != w {
+					t.Errorf("unexpected %v rejects, got %d want %d", r, g, w)
+				}
+			}
+			if g, w := got.Exact(), want.Exact(); g != w {
+				t.Errorf("unexpected exact, got %v want %v", g, w)
+			}
+			for _, q := range []float64{0, 0.5, 1} {
+				g, w := got.Quantile(q), want.Quantile(q)
+				if math.Abs(g-w) > 5 && g != w {
+					t.Errorf("unexpected quantile %g, got %g want %g", q, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestTdigest_AddCentroidListParallelStrict(t *testing.T) {
+	centroids := make(tdigest.CentroidList, 1<<16)
+	for i := range centroids {
+		centroids[i] = tdigest.Centroid{Mean: UniformData[i], Weight: 2}
+	}
+	td := tdigest.NewWithCompression(100, tdigest.WithStrict())
+	td.AddCentroidListParallel(centroids, 4)
+	if got, want := td.Count(), float64(2*len(centroids)); got != want {
+		t.Errorf("unexpected count, got %g want %g", got, want)
+	}
+	td.Flush()
+	want := tdigest.NewWithCompression(100)
+	want.AddCentroidList(centroids)
+	if err := compareQuantiles(td, want, 0.01); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,44 @@
+package tdigest
+
+// PeekQuantile returns the (approximate) quantile of the distribution like
+// Quantile, but without modifying the digest: pending centroids are merged
+// into a temporary view rather than processed. Since they are not
+// compressed, the result may differ slightly from that of Quantile.
+//
+// Without a lock set by WithLocker, PeekQuantile and PeekCDF may be called
+// concurrently with each other, but not with methods that modify the
+// digest, including Quantile and CDF.
+func (t *TDigest) PeekQuantile(q float64) float64 {
+	t.lock()
+	defer t.unlock()
+	return t.peek().quantile(q)
+}
+
+// PeekCDF returns the cumulative distribution function for a given value x
+// like CDF, but without modifying the digest, see PeekQuantile.
+func (t *TDigest) PeekCDF(x float64) float64 {
+	t.lock()
+	defer t.unlock()
+	return t.peek().cdf(x)
+}
+
+// peek returns a view of all centroids, processed or not, without modifying
+// the digest. The view shares the buffers of the digest if it is up to date.
+func (t *TDigest) peek() summary {
+	n := t.processed.Len()
+	if t.unprocessed.Len() == 0 && len(t.cumulative) == n+1 && t.cumulative[n] == t.processedWeight {
+		return t.summary()
+	}
+	s := summary{
+		centroids: make(CentroidList, 0, n+t.unprocessed.Len()),
+		weight:    t.processedWeight + t.unprocessedWeight,
+		min:       t.min,
+		max:       t.max,
+		exact:     t.isExact(),
+	}
+	s.centroids = append(s.centroids, t.processed...)
+	s.centroids = append(s.centroids, t.unprocessed...)
+	sortCentroids(s.centroids)
+	s.cumulative = cumulativeWeights(make([]float64, s.centroids.Len()+1), s.centroids)
+	return s
+}
@@ -0,0 +1,43 @@
+package tdigest_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_Peek(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddSlice(NormalData[:10000])
+	td.Flush()
+	td.AddSlice(NormalData[10000:10500])
+	before := td.Clone()
+
+	for _, q := range quantiles {
+		got, want := td.PeekQuantile(q), before.Clone().Quantile(q)
+		if math.Abs(got-want)/want > 0.01 {
+			t.Errorf("unexpected quantile %g, got %g want %g", q, got, want)
+		}
+		x := want
+		got, want = td.PeekCDF(x), before.Clone().CDF(x)
+		if math.Abs(got-want) > 0.01 {
+			t.Errorf("unexpected CDF %g, got %g want %g", x, got, want)
+		}
+	}
+	if !reflect.DeepEqual(td, before) {
+		t.Error("peeking modified the digest")
+	}
+
+	// Once processed, peeking matches querying exactly.
+	td.Flush()
+	for _, q := range quantiles {
+		if got, want := td.PeekQuantile(q), td.Quantile(q); got != want {
+			t.Errorf("unexpected quantile %g, got %g want %g", q, got, want)
+		}
+	}
+	if got := tdigest.New().PeekQuantile(0.5); !math.IsNaN(got) {
+		t.Errorf("expected NaN for an empty digest, got %g", got)
+	}
+}
@@ -0,0 +1,287 @@
+package tdigest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPostgres is returned when decoding a malformed encoding of the
+// Postgres tdigest extension.
+const ErrInvalidPostgres = Error("invalid Postgres tdigest encoding")
+
+// ErrPostgresUnsupported is returned when encoding a digest that cannot be
+// represented by the Postgres tdigest extension.
+const ErrPostgresUnsupported = Error("digest cannot be represented in the Postgres tdigest format")
+
+// pgStoresMean is the flag of the Postgres tdigest extension marking
+// centroids that store their mean rather than their sum.
+const pgStoresMean = 1
+
+// Compression limits of the Postgres tdigest extension.
+const (
+	pgMinCompression = 10
+	pgMaxCompression = 10000
+)
+
+// pgHeaderSize is the size of the flags, count, compression and number of
+// centroids in the binary encoding of the Postgres tdigest extension.
+const pgHeaderSize = 4 + 8 + 4 + 4
+
+// FormatPostgres returns the text representation of the digest used by the
+// tdigest extension for Postgres, e.g.
+//
+//	flags 1 count 3 compression 100 centroids 2 (1, 1) (2.5, 2)
+//
+// so that it can be cast to the tdigest type and aggregated with functions
+// such as tdigest_percentile. The extension stores integral weights and a
+// compression between 10 and 10000, so other weights and compressions are
+// reported as ErrPostgresUnsupported, as are non-finite means. The min and
+// max are not represented.
+func FormatPostgres(t *TDigest) (string, error) {
+	t.lock()
+	defer t.unlock()
+	count, compression, err := t.postgresHeader()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "flags %d count %d compression %d centroids %d", pgStoresMean, count, compression, t.processed.Len())
+	for _, c := range t.processed {
+		b.WriteString(" (")
+		b.WriteString(strconv.FormatFloat(c.Mean, 'g', -1, 64))
+		b.WriteString(", ")
+		b.WriteString(strconv.FormatInt(int64(c.Weight), 10))
+		b.WriteByte(')')
+	}
+	return b.String(), nil
+}
+
+// ParsePostgres parses a digest from the text representation of the
+// tdigest extension for Postgres, applying the given options. Both the
+// current representation, storing means, and the older one, storing sums,
+// are supported. Since the representation lacks them, the min and max are
+// taken to be the means of the first and last centroids.
+func ParsePostgres(s string, opts ...Option) (*TDigest, error) {
+	header := s
+	if i := strings.IndexByte(s, '('); i >= 0 {
+		header, s = s[:i], s[i:]
+	} else {
+		s = ""
+	}
+	fields := strings.Fields(header)
+	keys := []string{"flags", "count", "compression", "centroids"}
+	if len(fields) != 2*len(keys) {
+		return nil, fmt.Errorf("%w: unexpected header %q", ErrInvalidPostgres, header)
+	}
+	var h pgHeader
+	values := []*int64{&h.flags, &h.count, &h.compression, &h.n}
+	for i, key := range keys {
+		v, err := strconv.ParseInt(fields[2*i+1], 10, 64)
+		if fields[2*i] != key || err != nil {
+			return nil, fmt.Errorf("%w: invalid %s field", ErrInvalidPostgres, key)
+		}
+		*values[i] = v
+	}
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+
+	d := &binaryDecoder{centroids: make(CentroidList, 0, h.capacity(len(s)/6)), invalid: ErrInvalidPostgres}
+	for i := 0; ; i++ {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			break
+		}
+		end := strings.IndexByte(s, ')')
+		sep := strings.IndexByte(s, ',')
+		if s[0] != '(' || end < 0 || sep < 0 || sep > end {
+			return nil, fmt.Errorf("%w: centroid %d: expected (mean, count)", ErrInvalidPostgres, i)
+		}
+		x, err := strconv.ParseFloat(strings.TrimSpace(s[1:sep]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: centroid %d: invalid mean", ErrInvalidPostgres, i)
+		}
+		count, err := strconv.ParseInt(strings.TrimSpace(s[sep+1:end]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: centroid %d: invalid count", ErrInvalidPostgres, i)
+		}
+		if err := h.add(d, x, count); err != nil {
+			return nil, err
+		}
+		s = s[end+1:]
+	}
+	return h.digest(d, opts)
+}
+
+// MarshalPostgres encodes the digest in the binary representation of the
+// tdigest extension for Postgres, as sent and received by the extension's
+// tdigest_send and tdigest_recv, with the restrictions of FormatPostgres.
+func (t *TDigest) MarshalPostgres() ([]byte, error) {
+	t.lock()
+	defer t.unlock()
+	count, compression, err := t.postgresHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, pgHeaderSize+16*t.processed.Len())
+	buf = appendUint32(buf, pgStoresMean)
+	buf = appendUint64(buf, uint64(count))
+	buf = appendUint32(buf, uint32(compression))
+	buf = appendUint32(buf, uint32(t.processed.Len()))
+	for _, c := range t.processed {
+		buf = appendFloat64(buf, c.Mean)
+		buf = appendUint64(buf, uint64(c.Weight))
+	}
+	return buf, nil
+}
+
+// UnmarshalPostgres replaces the data and compression of the digest by
+// those of the binary representation of the tdigest extension for
+// Postgres, keeping its other settings, like ParsePostgres.
+func (t *TDigest) UnmarshalPostgres(data []byte) error {
+	if len(data) < pgHeaderSize {
+		return fmt.Errorf("%w: truncated", ErrInvalidPostgres)
+	}
+	h := pgHeader{
+		flags:       int64(int32(binary.BigEndian.Uint32(data))),
+		count:       int64(binary.BigEndian.Uint64(data[4:])),
+		compression: int64(int32(binary.BigEndian.Uint32(data[12:]))),
+		n:           int64(int32(binary.BigEndian.Uint32(data[16:]))),
+	}
+	if err := h.validate(); err != nil {
+		return err
+	}
+	data = data[pgHeaderSize:]
+	if int64(len(data)) != 16*h.n {
+		return fmt.Errorf("%w: expected %d centroids in %d bytes", ErrInvalidPostgres, h.n, len(data))
+	}
+
+	d := &binaryDecoder{centroids: make(CentroidList, 0, h.n), invalid: ErrInvalidPostgres}
+	for ; len(data) > 0; data = data[16:] {
+		if err := h.add(d, readFloat64(data), int64(binary.BigEndian.Uint64(data[8:]))); err != nil {
+			return err
+		}
+	}
+	if err := h.check(d); err != nil {
+		return err
+	}
+	return t.setDecoded(h.binaryHeader(d), d)
+}
+
+// postgresHeader processes any pending centroids, unless forbidden by strict
+// mode, and returns the count and
+// compression of the digest, if it can be represented by the Postgres
+// tdigest extension.
+func (t *TDigest) postgresHeader() (count, compression int64, err error) {
+	if err := t.prepareRead(); err != nil {
+		return 0, 0, err
+	}
+	compression = int64(math.Round(t.Compression))
+	if compression < pgMinCompression || compression > pgMaxCompression {
+		return 0, 0, fmt.Errorf("%w: compression %g", ErrPostgresUnsupported, t.Compression)
+	}
+	for i, c := range t.processed {
+		if math.IsInf(c.Mean, 0) {
+			return 0, 0, fmt.Errorf("%w: centroid %d: infinite mean", ErrPostgresUnsupported, i)
+		}
+	}
+	count, err = t.integralCount(ErrPostgresUnsupported)
+	return count, compression, err
+}
+
+// integralCount returns the total weight of the processed centroids, or
+// unsupported if any of their weights is not an integer.
+func (t *TDigest) integralCount(unsupported Error) (int64, error) {
+	var count int64
+	for i, c := range t.processed {
+		if c.Weight != math.Trunc(c.Weight) || c.Weight >= 1<<53 {
+			return 0, fmt.Errorf("%w: centroid %d: weight %g is not an integer", unsupported, i, c.Weight)
+		}
+		count += int64(c.Weight)
+	}
+	return count, nil
+}
+
+// pgHeader holds the header of an encoding of the Postgres tdigest
+// extension.
+type pgHeader struct {
+	flags, count, compression, n int64
+}
+
+func (h *pgHeader) validate() error {
+	switch {
+	case h.flags&^pgStoresMean != 0:
+		return fmt.Errorf("%w: unsupported flags %d", ErrInvalidPostgres, h.flags)
+	case h.count < 0 || h.n < 0 || h.n > h.count:
+		return fmt.Errorf("%w: invalid counts", ErrInvalidPostgres)
+	case h.compression < 1 || h.compression > maxDecodeCompression:
+		return fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidPostgres, float64(maxDecodeCompression))
+	}
+	return nil
+}
+
+// capacity returns the number of centroids to allocate, given an upper
+// bound implied by the size of the encoding.
+func (h *pgHeader) capacity(max int) int64 {
+	if h.n > int64(max) {
+		return int64(max)
+	}
+	return h.n
+}
+
+// add adds a centroid, which stores a sum rather than a mean unless the
+// pgStoresMean flag is set.
+func (h *pgHeader) add(d *binaryDecoder, x float64, count int64) error {
+	if count <= 0 {
+		return fmt.Errorf("%w: centroid %d: invalid count", ErrInvalidPostgres, d.centroids.Len())
+	}
+	if h.flags&pgStoresMean == 0 {
+		x /= float64(count)
+	}
+	if math.IsInf(x, 0) {
+		return fmt.Errorf("%w: centroid %d: invalid mean", ErrInvalidPostgres, d.centroids.Len())
+	}
+	return d.add(x, float64(count))
+}
+
+// check verifies that the centroids match the counts of the header.
+func (h *pgHeader) check(d *binaryDecoder) error {
+	if int64(d.centroids.Len()) != h.n {
+		return fmt.Errorf("%w: expected %d centroids, got %d", ErrInvalidPostgres, h.n, d.centroids.Len())
+	}
+	if d.weight != float64(h.count) {
+		return fmt.Errorf("%w: centroid counts do not add up to %d", ErrInvalidPostgres, h.count)
+	}
+	return nil
+}
+
+// binaryHeader returns the header for setDecoded, with the min and max
+// taken from the centroids.
+func (h *pgHeader) binaryHeader(d *binaryDecoder) binaryHeader {
+	bh := binaryHeader{compression: float64(h.compression)}
+	if n := d.centroids.Len(); n > 0 {
+		bh.min, bh.max = d.centroids[0].Mean, d.centroids[n-1].Mean
+	}
+	return bh
+}
+
+// digest returns a new digest holding the decoded centroids.
+func (h *pgHeader) digest(d *binaryDecoder, opts []Option) (*TDigest, error) {
+	if err := h.check(d); err != nil {
+		return nil, err
+	}
+	t := NewWithCompression(float64(h.compression), opts...)
+	if err := t.setDecoded(h.binaryHeader(d), d); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	return appendUint32(appendUint32(buf, uint32(v>>32)), uint32(v))
+}
@@ -0,0 +1,101 @@
+package tdigest_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestFormatPostgres(t *testing.T) {
+	td := tdigest.NewWithCompression(10)
+	td.AddValues(1, 2, 3, 4, 5)
+	td.Add(2.5, 4)
+	text, err := tdigest.FormatPostgres(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "flags 1 count 9 compression 10 centroids 6 (1, 1) (2, 1) (2.5, 4) (3, 1) (4, 1) (5, 1)"
+	if text != want {
+		t.Errorf("unexpected text\ngot  %s\nwant %s", text, want)
+	}
+
+	for _, td := range []*tdigest.TDigest{tdigest.NewWithCompression(100), td, NormalDigest} {
+		text, err := tdigest.FormatPostgres(td)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := tdigest.ParsePostgres(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Compression != td.Compression || !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+			t.Error("digest differs after text round trip")
+		}
+
+		data, err := td.MarshalPostgres()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = tdigest.New()
+		if err := got.UnmarshalPostgres(data); err != nil {
+			t.Fatal(err)
+		}
+		if got.Compression != td.Compression || !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+			t.Error("digest differs after binary round trip")
+		}
+	}
+
+	weighted := tdigest.NewWithCompression(100)
+	weighted.Add(1, 0.5)
+	small := tdigest.NewWithCompression(5)
+	for _, td := range []*tdigest.TDigest{weighted, small} {
+		if _, err := tdigest.FormatPostgres(td); !errors.Is(err, tdigest.ErrPostgresUnsupported) {
+			t.Errorf("unexpected error %v", err)
+		}
+		if _, err := td.MarshalPostgres(); !errors.Is(err, tdigest.ErrPostgresUnsupported) {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+}
+
+func TestParsePostgres(t *testing.T) {
+	// The older representation stores sums rather than means.
+	td, err := tdigest.ParsePostgres("flags 0 count 3 compression 100 centroids 2 (1.000000, 1) (5.000000, 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := tdigest.CentroidList{{Mean: 1, Weight: 1}, {Mean: 2.5, Weight: 2}}
+	if !reflect.DeepEqual(td.Centroids(nil), want) {
+		t.Errorf("unexpected centroids %v", td.Centroids(nil))
+	}
+	if td.Quantile(0) != 1 || td.Quantile(1) != 2.5 {
+		t.Errorf("unexpected min and max %g %g", td.Quantile(0), td.Quantile(1))
+	}
+
+	for _, text := range []string{
+		"",
+		"flags 1 count 0 compression 100",
+		"flags 2 count 0 compression 100 centroids 0",
+		"flags 1 count 1 compression 0 centroids 1 (1, 1)",
+		"flags 1 count 2 compression 100 centroids 1 (1, 1)",
+		"flags 1 count 2 compression 100 centroids 2 (1, 1)",
+		"flags 1 count 2 compression 100 centroids 2 (2, 1) (1, 1)",
+		"flags 1 count 1 compression 100 centroids 1 (1, 0)",
+		"flags 1 count 1 compression 100 centroids 1 (nan, 1)",
+		"flags 1 count 1 compression 100 centroids 1 (1 1)",
+		"flags 1 count 1 compression 100 centroids 1 (1, 1",
+	} {
+		if _, err := tdigest.ParsePostgres(text); !errors.Is(err, tdigest.ErrInvalidPostgres) {
+			t.Errorf("%q: unexpected error %v", text, err)
+		}
+	}
+
+	data, _ := NormalDigest.MarshalPostgres()
+	for _, n := range []int{0, 19, len(data) - 1} {
+		if err := tdigest.New().UnmarshalPostgres(data[:n]); !errors.Is(err, tdigest.ErrInvalidPostgres) {
+			t.Errorf("truncated at %d: unexpected error %v", n, err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package tdigest_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestNewWithProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile tdigest.Profile
+		maxErr  float64
+	}{
+		{name: "coarse", profile: tdigest.Coarse, maxErr: 0.01},
+		{name: "latency", profile: tdigest.Latency, maxErr: 0.005},
+		{name: "high accuracy", profile: tdigest.HighAccuracy, maxErr: 0.001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := tdigest.NewWithProfile(tt.profile)
+			for _, x := range NormalData {
+				td.Add(x, 1)
+			}
+			if td.Compression != tt.profile.Compression {
+				t.Errorf("unexpected compression, got %g want %g", td.Compression, tt.profile.Compression)
+			}
+			if err := compareQuantiles(td, NormalDigest, tt.maxErr); err != nil {
+				t.Errorf("profile digest differs: %s", err.Error())
+			}
+		})
+	}
+}
+
+// TestProfiles checks the figures documented for each profile.
+func TestProfiles(t *testing.T) {
+	sorted := append([]float64(nil), NormalData...)
+	sort.Float64s(sorted)
+	tests := []struct {
+		name      string
+		profile   tdigest.Profile
+		rankErr   float64
+		tailErr   float64
+		centroids int
+		footprint int
+	}{
+		{name: "coarse", profile: tdigest.Coarse, rankErr: 0.0005, tailErr: 0.005, centroids: 60, footprint: 12 << 10},
+		{name: "latency", profile: tdigest.Latency, rankErr: 0.0001, tailErr: 0.001, centroids: 250, footprint: 24 << 10},
+		{name: "high accuracy", profile: tdigest.HighAccuracy, rankErr: 0.00005, tailErr: 0.0005, centroids: 1200, footprint: 256 << 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := tdigest.NewWithProfile(tt.profile)
+			for _, x := range NormalData {
+				td.Add(x, 1)
+			}
+			for _, q := range []float64{0.001, 0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.99, 0.999} {
+				rank := float64(sort.SearchFloat64s(sorted, td.Quantile(q))) / float64(len(sorted))
+				if math.Abs(rank-q) > tt.rankErr {
+					t.Errorf("quantile %g: rank %g is off by more than %g", q, rank, tt.rankErr)
+				}
+			}
+			want := sorted[int(0.999*float64(len(sorted)))]
+			if got := td.Quantile(0.999); math.Abs(got-want) > tt.tailErr*math.Abs(want) {
+				t.Errorf("unexpected p99.9, got %g want %g within %g", got, want, tt.tailErr)
+			}
+			if n := len(td.Centroids(nil)); math.Abs(float64(n-tt.centroids)) > 0.1*float64(tt.centroids) {
+				t.Errorf("unexpected number of centroids, got %d want about %d", n, tt.centroids)
+			}
+			if got := td.MemoryFootprint(); got > tt.footprint {
+				t.Errorf("unexpected memory footprint, got %d want at most %d", got, tt.footprint)
+			}
+		})
+	}
+}
+
+func TestWithBufferFactors(t *testing.T) {
+	tests := []struct {
+		name                   string
+		processed, unprocessed float64
+	}{
+		{name: "default", processed: 0, unprocessed: 0},
+		{name: "small", processed: 1, unprocessed: 0.5},
+		{name: "minimal", processed: 1e-9, unprocessed: 1e-9},
+		{name: "large", processed: 4, unprocessed: 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := tdigest.NewWithCompression(100, tdigest.WithBufferFactors(tt.processed, tt.unprocessed))
+			for _, x := range NormalData[:10000] {
+				td.Add(x, 1)
+			}
+			want := tdigest.NewWithCompression(100)
+			want.AddSlice(NormalData[:10000])
+			if err := compareQuantiles(td, want, 0.01); err != nil {
+				t.Errorf("digest differs: %s", err.Error())
+			}
+		})
+	}
+}
+
+func TestWithCompression(t *testing.T) {
+	if got := tdigest.New(tdigest.WithCompression(50)).Compression; got != 50 {
+		t.Errorf("unexpected compression, got %g want 50", got)
+	}
+	if got := tdigest.NewWithCompression(100, tdigest.WithCompression(50)).Compression; got != 50 {
+		t.Errorf("unexpected compression with an overriding option, got %g want 50", got)
+	}
+	td := tdigest.New()
+	td.ResetWithOptions(tdigest.WithCompression(20))
+	if td.Compression != 20 {
+		t.Errorf("unexpected compression after reset, got %g want 20", td.Compression)
+	}
+}
+
+func TestWithBufferSizes(t *testing.T) {
+	td := tdigest.New(tdigest.WithCompression(tdigest.Latency.Compression), tdigest.WithBufferSizes(0, tdigest.Latency.UnprocessedSize))
+	want := tdigest.NewWithProfile(tdigest.Latency)
+	if got, w := td.MemoryFootprint(), want.MemoryFootprint(); got != w {
+		t.Errorf("unexpected memory footprint, got %d want %d", got, w)
+	}
+	td.AddSlice(NormalData[:10000])
+	want.AddSlice(NormalData[:10000])
+	if err := compareQuantiles(td, want, 0); err != nil {
+		t.Errorf("digest differs from profile: %s", err.Error())
+	}
+
+	small := tdigest.New(tdigest.WithBufferSizes(10, 20))
+	if got, def := small.MemoryFootprint(), tdigest.New().MemoryFootprint(); got >= def {
+		t.Errorf("unexpected memory footprint, got %d want less than %d", got, def)
+	}
+}
@@ -0,0 +1,68 @@
+package tdigest
+
+import (
+	"math"
+	"sort"
+)
+
+// FromPrometheusBuckets builds a new digest with the default compression and
+// the given options from the buckets of a classic Prometheus histogram,
+// given as upper bounds and cumulative counts, like the le labels and
+// values of its _bucket series. The count of every bucket is spread evenly
+// between the bound of the previous bucket and its own, like
+// histogram_quantile interpolates. Following histogram_quantile, the first
+// bucket starts at zero if its bound is positive, and the count of the +Inf
+// bucket is placed at the highest finite bound.
+//
+// Malformed buckets are repaired as far as possible rather than rejected,
+// since they commonly occur in long histories: extra bounds or counts and
+// NaN bounds are ignored, bounds need not be sorted, and cumulative counts
+// that decrease are raised to the largest count of the buckets below.
+func FromPrometheusBuckets(bounds []float64, counts []uint64, opts ...Option) *TDigest {
+	type bucket struct {
+		bound float64
+		count uint64
+	}
+	var bs []bucket
+	for i := 0; i < len(bounds) && i < len(counts); i++ {
+		if !math.IsNaN(bounds[i]) {
+			bs = append(bs, bucket{bounds[i], counts[i]})
+		}
+	}
+	sort.SliceStable(bs, func(i, j int) bool { return bs[i].bound < bs[j].bound })
+
+	var cl CentroidList
+	var low float64
+	var soFar uint64
+	for i, b := range bs {
+		if b.count <= soFar {
+			continue
+		}
+		n := float64(b.count - soFar)
+		soFar = b.count
+		high := b.bound
+		switch {
+		case math.IsInf(high, -1):
+			// Values below every finite bound have no known value.
+			continue
+		case math.IsInf(high, 1):
+			if i == 0 {
+				continue
+			}
+			high = bs[i-1].bound
+			low = high
+		case i == 0 || math.IsInf(bs[i-1].bound, -1):
+			low = math.Min(0, high)
+		default:
+			low = bs[i-1].bound
+		}
+		if math.IsInf(low, 0) || math.IsInf(high, 0) {
+			continue
+		}
+		cl = appendBucket(cl, Bucket{Low: low, High: high, Count: n})
+	}
+
+	t := New(opts...)
+	t.AddCentroidList(cl)
+	return t
+}
@@ -0,0 +1,65 @@
+package tdigest_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestFromPrometheusBuckets(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		name      string
+		bounds    []float64
+		counts    []uint64
+		wantCount float64
+		want      map[float64]float64
+	}{
+		{
+			name:      "uniform",
+			bounds:    []float64{1, 2, 3, 4, inf},
+			counts:    []uint64{100, 200, 300, 400, 400},
+			wantCount: 400,
+			want:      map[float64]float64{0.25: 1, 0.5: 2, 0.75: 3},
+		},
+		{
+			name:      "inf bucket",
+			bounds:    []float64{1, 2, inf},
+			counts:    []uint64{0, 0, 10},
+			wantCount: 10,
+			want:      map[float64]float64{0: 2, 0.5: 2, 1: 2},
+		},
+		{
+			name:      "unsorted and decreasing",
+			bounds:    []float64{inf, 2, math.NaN(), 1, 3},
+			counts:    []uint64{20, 10, 5, 10, 9, 99},
+			wantCount: 20,
+			want:      map[float64]float64{0.25: 0.5, 0.9: 3},
+		},
+		{
+			name:      "negative",
+			bounds:    []float64{-1, 0, inf},
+			counts:    []uint64{10, 20, 20},
+			wantCount: 20,
+			want:      map[float64]float64{0: -1, 0.75: -0.5},
+		},
+		{
+			name:      "empty",
+			wantCount: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := tdigest.FromPrometheusBuckets(tt.bounds, tt.counts)
+			if got := td.Count(); got != tt.wantCount {
+				t.Errorf("unexpected count, got %g want %g", got, tt.wantCount)
+			}
+			for q, want := range tt.want {
+				if got := td.Quantile(q); math.Abs(got-want) > 0.1 {
+					t.Errorf("quantile %g: got %g want %g", q, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package tdigest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidProto is returned when decoding a malformed protobuf encoding.
+const ErrInvalidProto = Error("invalid tdigest protobuf encoding")
+
+// Protobuf field numbers of the TDigest message in tdigest.proto.
+const (
+	protoCompression = 1
+	protoMin         = 2
+	protoMax         = 3
+	protoMeans       = 4
+	protoWeights     = 5
+)
+
+// Protobuf wire types.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// MarshalProto encodes the digest as the TDigest protobuf message defined in
+// tdigest.proto, processing any pending centroids first. Means and weights
+// are packed.
+func (t *TDigest) MarshalProto() ([]byte, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, err
+	}
+
+	n := t.processed.Len()
+	buf := make([]byte, 0, 3*9+2*(binary.MaxVarintLen64+1+8*n))
+	buf = appendProtoDouble(buf, protoCompression, t.Compression)
+	if n == 0 {
+		return buf, nil
+	}
+	buf = appendProtoDouble(buf, protoMin, t.min)
+	buf = appendProtoDouble(buf, protoMax, t.max)
+	buf = appendUvarint(buf, protoMeans<<3|protoBytes)
+	buf = appendUvarint(buf, uint64(8*n))
+	for _, c := range t.processed {
+		buf = appendFloat64LE(buf, c.Mean)
+	}
+	buf = appendUvarint(buf, protoWeights<<3|protoBytes)
+	buf = appendUvarint(buf, uint64(8*n))
+	for _, c := range t.processed {
+		buf = appendFloat64LE(buf, c.Weight)
+	}
+	return buf, nil
+}
+
+// UnmarshalProto replaces the data and compression of the digest by those of
+// a TDigest protobuf message, keeping its other settings. Repeated fields may
+// be packed or not, and unknown fields are skipped. Decoding is as strict as
+// UnmarshalBinary otherwise, and leaves the digest unchanged on error.
+func (t *TDigest) UnmarshalProto(data []byte) error {
+	h := binaryHeader{min: math.NaN(), max: math.NaN()}
+	var means, weights []float64
+	for len(data) > 0 {
+		var field, wire uint64
+		var err error
+		if field, wire, data, err = readProtoKey(data); err != nil {
+			return err
+		}
+		switch {
+		case field == protoCompression && wire == protoFixed64:
+			h.compression, data, err = readProtoDouble(data)
+		case field == protoMin && wire == protoFixed64:
+			h.min, data, err = readProtoDouble(data)
+		case field == protoMax && wire == protoFixed64:
+			h.max, data, err = readProtoDouble(data)
+		case field == protoMeans && (wire == protoFixed64 || wire == protoBytes):
+			means, data, err = readProtoDoubles(means, data, wire)
+		case field == protoWeights && (wire == protoFixed64 || wire == protoBytes):
+			weights, data, err = readProtoDoubles(weights, data, wire)
+		default:
+			data, err = skipProtoField(data, wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(means) != len(weights) {
+		return fmt.Errorf("%w: %d means but %d weights", ErrInvalidProto, len(means), len(weights))
+	}
+	if !(h.compression >= 1 && h.compression <= maxDecodeCompression) {
+		return fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidProto, float64(maxDecodeCompression))
+	}
+
+	d := &binaryDecoder{centroids: make(CentroidList, 0, len(means)), invalid: ErrInvalidProto}
+	for i, mean := range means {
+		if err := d.add(mean, weights[i]); err != nil {
+			return err
+		}
+	}
+	return t.setDecoded(h, d)
+}
+
+// readProtoKey reads the field number and wire type of the next field.
+func readProtoKey(data []byte) (field, wire uint64, rest []byte, err error) {
+	key, n := binary.Uvarint(data)
+	if n <= 0 || key>>3 == 0 {
+		return 0, 0, nil, fmt.Errorf("%w: invalid field key", ErrInvalidProto)
+	}
+	return key >> 3, key & 7, data[n:], nil
+}
+
+func readProtoDouble(data []byte) (float64, []byte, error) {
+	if len(data) < 8 {
+		return 0, nil, fmt.Errorf("%w: truncated", ErrInvalidProto)
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(data)), data[8:], nil
+}
+
+// readProtoDoubles appends a packed or single double to xs.
+func readProtoDoubles(xs []float64, data []byte, wire uint64) ([]float64, []byte, error) {
+	if wire == protoFixed64 {
+		x, data, err := readProtoDouble(data)
+		return append(xs, x), data, err
+	}
+	packed, data, err := readProtoBytes(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(packed)%8 != 0 {
+		return nil, nil, fmt.Errorf("%w: invalid packed doubles", ErrInvalidProto)
+	}
+	for ; len(packed) > 0; packed = packed[8:] {
+		xs = append(xs, math.Float64frombits(binary.LittleEndian.Uint64(packed)))
+	}
+	return xs, data, nil
+}
+
+func readProtoBytes(data []byte) ([]byte, []byte, error) {
+	n, k := binary.Uvarint(data)
+	if k <= 0 || n > uint64(len(data)-k) {
+		return nil, nil, fmt.Errorf("%w: truncated", ErrInvalidProto)
+	}
+	data = data[k:]
+	return data[:n], data[n:], nil
+}
+
+// skipProtoField skips the value of an unknown field.
+func skipProtoField(data []byte, wire uint64) ([]byte, error) {
+	size := 0
+	switch wire {
+	case protoVarint:
+		if _, n := binary.Uvarint(data); n > 0 {
+			return data[n:], nil
+		}
+		return nil, fmt.Errorf("%w: invalid varint", ErrInvalidProto)
+	case protoFixed64:
+		size = 8
+	case protoBytes:
+		_, data, err := readProtoBytes(data)
+		return data, err
+	case protoFixed32:
+		size = 4
+	default:
+		return nil, fmt.Errorf("%w: unsupported wire type %d", ErrInvalidProto, wire)
+	}
+	if len(data) < size {
+		return nil, fmt.Errorf("%w: truncated", ErrInvalidProto)
+	}
+	return data[size:], nil
+}
+
+func appendProtoDouble(buf []byte, field uint64, x float64) []byte {
+	return appendFloat64LE(appendUvarint(buf, field<<3|protoFixed64), x)
+}
+
+func appendFloat64LE(buf []byte, x float64) []byte {
+	v := math.Float64bits(x)
+	return append(buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24),
+		byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56))
+}
@@ -0,0 +1,83 @@
+package tdigest_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_MarshalProto(t *testing.T) {
+	single := tdigest.NewWithCompression(10)
+	single.Add(1.5, 2)
+	for _, td := range []*tdigest.TDigest{tdigest.NewWithCompression(100), single, NormalDigest} {
+		data, err := td.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := tdigest.New()
+		if err := got.UnmarshalProto(data); err != nil {
+			t.Fatal(err)
+		}
+		if got.Compression != td.Compression || !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+			t.Error("digest differs after round trip")
+		}
+	}
+
+	data, _ := single.MarshalProto()
+	want := "090000000000002440" +
+		"11000000000000f83f" + "19000000000000f83f" +
+		"2208000000000000f83f" + "2a080000000000000040"
+	if got := hex.EncodeToString(data); got != want {
+		t.Errorf("unexpected encoding\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestTdigest_UnmarshalProto(t *testing.T) {
+	// Unpacked repeated fields, in any order, between unknown fields.
+	data := mustHex(t, "090000000000002440"+
+		"29000000000000f03f"+"21000000000000f0bf"+
+		"3001"+"3a0161"+"3d00000000"+"410000000000000000"+
+		"21000000000000f83f"+"290000000000000040"+
+		"11000000000000f0bf"+"19000000000000f83f")
+	td := tdigest.New()
+	if err := td.UnmarshalProto(data); err != nil {
+		t.Fatal(err)
+	}
+	want := tdigest.CentroidList{{Mean: -1, Weight: 1}, {Mean: 1.5, Weight: 2}}
+	if td.Compression != 10 || !reflect.DeepEqual(td.Centroids(nil), want) {
+		t.Errorf("unexpected digest %g %v", td.Compression, td.Centroids(nil))
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "compression", data: "090000000000000000"},
+		{name: "truncated", data: "0900000000"},
+		{name: "packed", data: "090000000000002440" + "2207000000000000f8"},
+		{name: "length", data: "090000000000002440" + "22ff01"},
+		{name: "mismatch", data: "090000000000002440" + "21000000000000f83f"},
+		{name: "field zero", data: "090000000000002440" + "00"},
+		{name: "wire type", data: "090000000000002440" + "33"},
+		{name: "no min", data: "090000000000002440" + "21000000000000f83f" + "29000000000000f03f"},
+		{name: "weight", data: "090000000000002440" + "11000000000000f83f" + "19000000000000f83f" +
+			"21000000000000f83f" + "290000000000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tdigest.NewWithCompression(10)
+			got.Add(42, 1)
+			err := got.UnmarshalProto(mustHex(t, tt.data))
+			if !errors.Is(err, tdigest.ErrInvalidProto) {
+				t.Errorf("unexpected error %v", err)
+			}
+			if got.Compression != 10 || got.Quantile(0.5) != 42 {
+				t.Error("digest was modified on error")
+			}
+		})
+	}
+}
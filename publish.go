@@ -0,0 +1,54 @@
+package tdigest
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Publisher shares snapshots of a digest from a single writer with any
+// number of readers. The writer owns the digest and periodically calls
+// Publish; readers answer queries from the latest published snapshot
+// without locking, so they never wait for the writer, nor the writer for
+// them. The zero value is ready to use.
+type Publisher struct {
+	v atomic.Value
+}
+
+// Publish freezes the digest and makes the snapshot visible to readers. It
+// must be called by the goroutine that owns t. In strict mode, it returns
+// ErrUnflushed, keeping the previous snapshot, if t has pending centroids.
+func (p *Publisher) Publish(t *TDigest) error {
+	f, err := t.Freeze()
+	if err != nil {
+		return err
+	}
+	p.v.Store(f)
+	return nil
+}
+
+// Snapshot returns the latest published snapshot, or nil if nothing has been
+// published yet.
+func (p *Publisher) Snapshot() *FrozenDigest {
+	f, _ := p.v.Load().(*FrozenDigest)
+	return f
+}
+
+// Quantile returns the (approximate) quantile of the latest published
+// snapshot, or NaN if nothing has been published yet.
+func (p *Publisher) Quantile(q float64) float64 {
+	f := p.Snapshot()
+	if f == nil {
+		return math.NaN()
+	}
+	return f.Quantile(q)
+}
+
+// CDF returns the cumulative distribution function of the latest published
+// snapshot for a given value x, or NaN if nothing has been published yet.
+func (p *Publisher) CDF(x float64) float64 {
+	f := p.Snapshot()
+	if f == nil {
+		return math.NaN()
+	}
+	return f.CDF(x)
+}
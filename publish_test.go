@@ -0,0 +1,56 @@
+package tdigest_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestPublisher(t *testing.T) {
+	var p tdigest.Publisher
+	if got := p.Quantile(0.5); !math.IsNaN(got) {
+		t.Errorf("expected NaN before publishing, got %g", got)
+	}
+	if got := p.CDF(0); !math.IsNaN(got) {
+		t.Errorf("expected NaN before publishing, got %g", got)
+	}
+
+	td := tdigest.NewWithCompression(100)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if f := p.Snapshot(); f != nil {
+					f.Quantile(0.99)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		td.AddSlice(NormalData[i*1000 : (i+1)*1000])
+		p.Publish(td)
+	}
+	close(done)
+	wg.Wait()
+
+	if got, want := p.Snapshot().Count(), td.Count(); got != want {
+		t.Errorf("unexpected count, got %g want %g", got, want)
+	}
+	if got, want := p.Quantile(0.5), td.Quantile(0.5); got != want {
+		t.Errorf("unexpected median, got %g want %g", got, want)
+	}
+	x := td.Quantile(0.9)
+	if got, want := p.CDF(x), td.CDF(x); got != want {
+		t.Errorf("unexpected CDF, got %g want %g", got, want)
+	}
+}
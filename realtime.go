@@ -0,0 +1,197 @@
+package tdigest
+
+import "math"
+
+// RealtimeTDigest is a digest for latency-critical code, whose Add never
+// allocates and does a bounded amount of work. Rather than compressing all
+// buffered centroids at once when the buffer fills up, as TDigest does, it
+// hands the full buffer over to an incremental merge, which advances by a
+// few centroids on every subsequent Add while a second buffer takes new
+// samples.
+//
+// Queries complete any pending work first, so they are not bounded; they
+// are expected to run off the critical path.
+type RealtimeTDigest struct {
+	// t holds the merged centroids and the configuration.
+	t *TDigest
+
+	active       CentroidList
+	activeWeight float64
+
+	// merging is set while the centroids in heap are being merged with those
+	// of t into next.
+	merging bool
+	// heap is a min-heap of the centroids being merged, once heapified
+	// drops below zero.
+	heap      CentroidList
+	heapified int
+	next      CentroidList
+	cursor    int
+	total     float64
+	soFar     float64
+	limit     float64
+
+	// steps is the number of merge steps performed per Add, which is enough
+	// to complete a merge before the active buffer fills up again.
+	steps int
+}
+
+// NewRealtime initializes a new real-time distribution with custom
+// compression. Of the options, only those setting buffer sizes and the
+// policy for non-finite values apply; in particular, WithExactThreshold is
+// ignored.
+func NewRealtime(c float64, opts ...Option) *RealtimeTDigest {
+	t := NewWithCompression(c, opts...)
+	// The incremental merge always compresses, so the digest is never
+	// exact, whatever WithExactThreshold says.
+	t.exact, t.exactThreshold = false, 0
+	m := t.maxUnprocessed
+	// A merge never yields more centroids than it consumes.
+	n := t.maxProcessed + m
+	t.processed = make(CentroidList, 0, n)
+	t.unprocessed = nil
+	return &RealtimeTDigest{
+		t:      t,
+		active: make(CentroidList, 0, m),
+		heap:   make(CentroidList, 0, m),
+		next:   make(CentroidList, 0, n),
+		// Heapifying takes m/2 steps, and emitting each of the at most n
+		// centroids one step.
+		steps: (m/2+n)/m + 1,
+	}
+}
+
+// Add adds a value x with a weight w to the distribution.
+func (r *RealtimeTDigest) Add(x, w float64) {
+	c := Centroid{Mean: x, Weight: w}
+	if r.t.check(&c) != nil {
+		return
+	}
+	if r.active.Len() == cap(r.active) {
+		r.finish()
+		r.start()
+	}
+	r.active = append(r.active, c)
+	r.activeWeight += c.Weight
+	r.t.accepted++
+	r.t.min = math.Min(r.t.min, c.Mean)
+	r.t.max = math.Max(r.t.max, c.Mean)
+	r.step(r.steps)
+}
+
+// Count returns the total weight of the distribution.
+func (r *RealtimeTDigest) Count() float64 {
+	if r.merging {
+		return r.total + r.activeWeight
+	}
+	return r.t.processedWeight + r.activeWeight
+}
+
+// Quantile returns the (approximate) quantile of the distribution, see
+// TDigest.Quantile.
+func (r *RealtimeTDigest) Quantile(q float64) float64 {
+	r.Flush()
+	return r.t.summary().quantile(q)
+}
+
+// CDF returns the cumulative distribution function for a given value x, see
+// TDigest.CDF.
+func (r *RealtimeTDigest) CDF(x float64) float64 {
+	r.Flush()
+	return r.t.summary().cdf(x)
+}
+
+// Centroids returns a copy of the merged centroids, see TDigest.Centroids.
+func (r *RealtimeTDigest) Centroids(cl CentroidList) CentroidList {
+	r.Flush()
+	return append(cl, r.t.processed...)
+}
+
+// Flush completes all pending work, which is otherwise spread over
+// subsequent calls to Add.
+func (r *RealtimeTDigest) Flush() {
+	r.finish()
+	if r.active.Len() > 0 {
+		r.start()
+		r.finish()
+	}
+	r.t.updateCumulative()
+}
+
+// Reset resets the distribution to its initial state.
+func (r *RealtimeTDigest) Reset() {
+	r.t.Reset()
+	r.active = r.active[:0]
+	r.activeWeight = 0
+	r.heap = r.heap[:0]
+	r.next = r.next[:0]
+	r.merging = false
+}
+
+// start hands the active centroids over to a new merge.
+func (r *RealtimeTDigest) start() {
+	r.heap, r.active = r.active, r.heap[:0]
+	r.heapified = r.heap.Len()/2 - 1
+	r.next = r.next[:0]
+	r.cursor = 0
+	r.total = r.t.processedWeight + r.activeWeight
+	r.activeWeight = 0
+	r.soFar, r.limit = 0, 0
+	r.merging = true
+}
+
+// finish completes the current merge, if any.
+func (r *RealtimeTDigest) finish() {
+	for r.merging {
+		r.step(math.MaxInt32)
+	}
+}
+
+// step advances the current merge by up to n steps.
+func (r *RealtimeTDigest) step(n int) {
+	t := r.t
+	for ; n > 0 && r.merging; n-- {
+		if r.heapified >= 0 {
+			siftDownMin(r.heap, r.heapified)
+			r.heapified--
+			continue
+		}
+
+		var c Centroid
+		switch {
+		case r.heap.Len() > 0 && (r.cursor == t.processed.Len() || r.heap[0].Mean < t.processed[r.cursor].Mean):
+			c = r.heap[0]
+			last := r.heap.Len() - 1
+			r.heap[0] = r.heap[last]
+			r.heap = r.heap[:last]
+			siftDownMin(r.heap, 0)
+		case r.cursor < t.processed.Len():
+			c = t.processed[r.cursor]
+			r.cursor++
+		default:
+			t.processed, r.next = r.next, t.processed[:0]
+			t.processedWeight = r.total
+			r.merging = false
+			continue
+		}
+		r.next, r.soFar, r.limit = t.compressInto(r.next, r.total, c, r.soFar, r.limit)
+	}
+}
+
+// siftDownMin restores the min-heap property of h below the given root.
+func siftDownMin(h CentroidList, root int) {
+	for {
+		child := 2*root + 1
+		if child >= h.Len() {
+			return
+		}
+		if child+1 < h.Len() && h[child+1].Mean < h[child].Mean {
+			child++
+		}
+		if !(h[child].Mean < h[root].Mean) {
+			return
+		}
+		h[root], h[child] = h[child], h[root]
+		root = child
+	}
+}
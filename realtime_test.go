@@ -0,0 +1,83 @@
+package tdigest_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestRealtimeTDigest(t *testing.T) {
+	r := tdigest.NewRealtime(1000)
+	if got := r.Quantile(0.5); !math.IsNaN(got) {
+		t.Errorf("expected NaN for an empty digest, got %g", got)
+	}
+	for i, x := range NormalData {
+		r.Add(x, 1)
+		if i%100000 == 0 {
+			if got, want := r.Count(), float64(i+1); got != want {
+				t.Fatalf("unexpected count, got %g want %g", got, want)
+			}
+		}
+	}
+	r.Add(math.NaN(), 1)
+	if got, want := r.Count(), float64(len(NormalData)); got != want {
+		t.Errorf("unexpected count, got %g want %g", got, want)
+	}
+	for _, q := range quantiles {
+		got, want := r.Quantile(q), NormalDigest.Quantile(q)
+		if math.Abs(got-want)/want > 0.001 {
+			t.Errorf("unexpected quantile %g, got %g want %g", q, got, want)
+		}
+	}
+	if got, want := r.Quantile(0), NormalDigest.Quantile(0); got != want {
+		t.Errorf("unexpected min, got %g want %g", got, want)
+	}
+	x := NormalDigest.Quantile(0.9)
+	if got, want := r.CDF(x), 0.9; math.Abs(got-want) > 0.001 {
+		t.Errorf("unexpected CDF %g, got %g want %g", x, got, want)
+	}
+	if got := len(r.Centroids(nil)); got == 0 || got > 2000 {
+		t.Errorf("unexpected number of centroids %d", got)
+	}
+
+	r.Reset()
+	r.Add(1, 1)
+	r.Add(2, 1)
+	r.Add(3, 1)
+	if got := r.Quantile(0.5); got != 2 {
+		t.Errorf("unexpected median after reset, got %g want 2", got)
+	}
+}
+
+func TestRealtimeTDigest_IgnoresExactThreshold(t *testing.T) {
+	r := tdigest.NewRealtime(100, tdigest.WithExactThreshold(len(NormalData)))
+	want := tdigest.NewRealtime(100)
+	for _, x := range NormalData[:100000] {
+		r.Add(x, 1)
+		want.Add(x, 1)
+	}
+	for _, q := range quantiles {
+		if got, w := r.Quantile(q), want.Quantile(q); got != w {
+			t.Errorf("unexpected quantile %g, got %g want %g", q, got, w)
+		}
+	}
+	r.Reset()
+	r.Add(1, 1)
+	r.Add(2, 1)
+	if got := r.Quantile(0.5); got != 1.5 {
+		t.Errorf("unexpected median after Reset, got %g want 1.5", got)
+	}
+}
+
+func TestRealtimeTDigest_AddAllocs(t *testing.T) {
+	r := tdigest.NewRealtime(100)
+	i := 0
+	allocs := testing.AllocsPerRun(100000, func() {
+		r.Add(NormalData[i%len(NormalData)], 1)
+		i++
+	})
+	if allocs != 0 {
+		t.Errorf("unexpected allocations, got %g want 0", allocs)
+	}
+}
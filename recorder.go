@@ -0,0 +1,109 @@
+package tdigest
+
+import (
+	"sync"
+	"time"
+)
+
+// QuantileSample is a reading of the commonly reported quantiles of a
+// digest at a point in time.
+type QuantileSample struct {
+	Time time.Time
+	P50  float64
+	P95  float64
+	P99  float64
+}
+
+// Recorder keeps a bounded history of quantile samples taken from a digest,
+// so that recent percentile trends can be inspected without an external
+// time series database. Once full, the oldest samples are overwritten.
+//
+// A Recorder is safe for concurrent use, but the digest passed to Record is
+// not synchronized by the Recorder.
+type Recorder struct {
+	mu      sync.Mutex
+	samples []QuantileSample
+	next    int
+	full    bool
+}
+
+// NewRecorder initializes a recorder holding at most size samples.
+func NewRecorder(size int) *Recorder {
+	if size < 1 {
+		size = 1
+	}
+	return &Recorder{
+		samples: make([]QuantileSample, size),
+	}
+}
+
+// Record takes a sample of the quantiles of td at time ts.
+func (r *Recorder) Record(ts time.Time, td *TDigest) {
+	s := QuantileSample{
+		Time: ts,
+		P50:  td.Quantile(0.5),
+		P95:  td.Quantile(0.95),
+		P99:  td.Quantile(0.99),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples[r.next] = s
+	r.next++
+	if r.next == len(r.samples) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Len returns the number of samples currently held.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.full {
+		return len(r.samples)
+	}
+	return r.next
+}
+
+// Samples appends all held samples to dst, oldest first.
+func (r *Recorder) Samples(dst []QuantileSample) []QuantileSample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.full {
+		dst = append(dst, r.samples[r.next:]...)
+	}
+	return append(dst, r.samples[:r.next]...)
+}
+
+// Range appends the samples taken within [from, to] to dst, oldest first.
+func (r *Recorder) Range(dst []QuantileSample, from, to time.Time) []QuantileSample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	appendRange := func(samples []QuantileSample) {
+		for _, s := range samples {
+			if !s.Time.Before(from) && !s.Time.After(to) {
+				dst = append(dst, s)
+			}
+		}
+	}
+	if r.full {
+		appendRange(r.samples[r.next:])
+	}
+	appendRange(r.samples[:r.next])
+	return dst
+}
+
+// Latest returns the most recent sample, and false if nothing was recorded.
+func (r *Recorder) Latest() (QuantileSample, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full && r.next == 0 {
+		return QuantileSample{}, false
+	}
+	i := r.next - 1
+	if i < 0 {
+		i = len(r.samples) - 1
+	}
+	return r.samples[i], true
+}
@@ -0,0 +1,44 @@
+package tdigest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestRecorder(t *testing.T) {
+	r := tdigest.NewRecorder(3)
+	if _, ok := r.Latest(); ok {
+		t.Error("expected no latest sample for empty recorder")
+	}
+
+	td := tdigest.New()
+	start := time.Unix(0, 0)
+	for i := 0; i < 5; i++ {
+		td.Add(float64(i), 1)
+		r.Record(start.Add(time.Duration(i)*time.Minute), td)
+	}
+
+	if got := r.Len(); got != 3 {
+		t.Fatalf("unexpected length, got %d want 3", got)
+	}
+	samples := r.Samples(nil)
+	for i, s := range samples {
+		if want := start.Add(time.Duration(i+2) * time.Minute); !s.Time.Equal(want) {
+			t.Errorf("unexpected time for sample %d, got %v want %v", i, s.Time, want)
+		}
+	}
+	latest, ok := r.Latest()
+	if !ok || latest != samples[len(samples)-1] {
+		t.Errorf("unexpected latest sample %+v", latest)
+	}
+	if latest.P50 != td.Quantile(0.5) || latest.P99 != td.Quantile(0.99) {
+		t.Errorf("latest sample does not match digest, got %+v", latest)
+	}
+
+	got := r.Range(nil, start.Add(3*time.Minute), start.Add(10*time.Minute))
+	if len(got) != 2 || got[0] != samples[1] || got[1] != samples[2] {
+		t.Errorf("unexpected range %+v", got)
+	}
+}
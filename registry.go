@@ -0,0 +1,179 @@
+package tdigest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Labels is a set of label names and values identifying a digest in a
+// Registry, such as {"method": "GET", "status": "200"}. A label with an
+// empty value is the same as a missing one.
+type Labels map[string]string
+
+// key returns a canonical encoding of the label set, along with a copy of
+// it without empty values.
+func (l Labels) key() (string, Labels) {
+	names := make([]string, 0, len(l))
+	for name, v := range l {
+		if v != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	c := make(Labels, len(names))
+	for _, name := range names {
+		b.WriteString(strconv.Quote(name))
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(l[name]))
+		b.WriteByte(',')
+		c[name] = l[name]
+	}
+	return b.String(), c
+}
+
+// Registry manages digests keyed by label sets. It is safe for concurrent
+// use, and so are the digests it returns, which are created with their own
+// lock.
+type Registry struct {
+	compression float64
+	opts        []Option
+
+	mu      sync.RWMutex
+	entries map[string]*registryEntry
+}
+
+type registryEntry struct {
+	labels Labels
+	td     *TDigest
+}
+
+// NewRegistry returns an empty registry creating digests with the given
+// compression and options.
+func NewRegistry(compression float64, opts ...Option) *Registry {
+	return &Registry{
+		compression: compression,
+		opts:        opts,
+		entries:     make(map[string]*registryEntry),
+	}
+}
+
+// Get returns the digest for the given labels, or nil if there is none.
+func (r *Registry) Get(labels Labels) *TDigest {
+	key, _ := labels.key()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if e := r.entries[key]; e != nil {
+		return e.td
+	}
+	return nil
+}
+
+// GetOrCreate returns the digest for the given labels, creating an empty
+// one if there is none.
+func (r *Registry) GetOrCreate(labels Labels) *TDigest {
+	key, labels := labels.key()
+	r.mu.RLock()
+	e := r.entries[key]
+	r.mu.RUnlock()
+	if e != nil {
+		return e.td
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e = r.entries[key]; e == nil {
+		opts := append(append(make([]Option, 0, len(r.opts)+1), r.opts...), WithLocker(new(sync.Mutex)))
+		e = &registryEntry{labels: labels, td: NewWithCompression(r.compression, opts...)}
+		r.entries[key] = e
+	}
+	return e.td
+}
+
+// Observe adds a value x with a weight of one to the digest for the given
+// labels, creating it if needed.
+func (r *Registry) Observe(labels Labels, x float64) {
+	r.GetOrCreate(labels).Add(x, 1)
+}
+
+// Delete removes the digest for the given labels.
+func (r *Registry) Delete(labels Labels) {
+	key, _ := labels.key()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, key)
+}
+
+// Len returns the number of digests in the registry.
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.entries)
+}
+
+// Range calls f for each digest in the registry, in a stable order, until
+// f returns false. The labels must not be modified. The registry is not
+// locked while f runs, so f may use it, and digests created meanwhile may
+// be skipped.
+func (r *Registry) Range(f func(labels Labels, t *TDigest) bool) {
+	r.mu.RLock()
+	keys := make([]string, 0, len(r.entries))
+	for key := range r.entries {
+		keys = append(keys, key)
+	}
+	entries := make([]*registryEntry, len(keys))
+	sort.Strings(keys)
+	for i, key := range keys {
+		entries[i] = r.entries[key]
+	}
+	r.mu.RUnlock()
+
+	for _, e := range entries {
+		if !f(e.labels, e.td) {
+			return
+		}
+	}
+}
+
+// Merge merges every digest of other, e.g. a registry decoded from another
+// source, into the digest with the same labels in r, creating it if needed.
+// other must not be r. In strict mode, it stops at the first error of
+// TDigest.MergeErr and returns it.
+func (r *Registry) Merge(other *Registry) error {
+	var err error
+	other.Range(func(labels Labels, t *TDigest) bool {
+		err = r.GetOrCreate(labels).MergeErr(t)
+		return err == nil
+	})
+	return err
+}
+
+// MergeBy returns a new registry with the same compression and options,
+// holding the digests of r merged by the given label names: the labels of
+// every digest are reduced to those names, and digests with equal reduced
+// labels merged. For example, MergeBy("region") returns one digest per
+// region across all methods and statuses, and MergeBy() a single digest
+// with empty labels. In strict mode, it returns the first error of
+// TDigest.MergeErr.
+func (r *Registry) MergeBy(names ...string) (*Registry, error) {
+	merged := &Registry{
+		compression: r.compression,
+		opts:        r.opts,
+		entries:     make(map[string]*registryEntry),
+	}
+	var err error
+	r.Range(func(labels Labels, t *TDigest) bool {
+		reduced := make(Labels, len(names))
+		for _, name := range names {
+			reduced[name] = labels[name]
+		}
+		err = merged.GetOrCreate(reduced).MergeErr(t)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
@@ -0,0 +1,84 @@
+package tdigest_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestRegistry(t *testing.T) {
+	r := tdigest.NewRegistry(100)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			region := []string{"eu", "us"}[i%2]
+			for j := 0; j < 100; j++ {
+				r.Observe(tdigest.Labels{"region": region, "status": "200"}, float64(j))
+			}
+			r.Observe(tdigest.Labels{"region": region, "status": "500", "extra": ""}, 1000)
+		}(i)
+	}
+	wg.Wait()
+
+	if r.Len() != 4 {
+		t.Errorf("unexpected number of digests, got %d want 4", r.Len())
+	}
+	td := r.Get(tdigest.Labels{"status": "500", "region": "eu"})
+	if td == nil || td.Count() != 4 {
+		t.Fatal("digest for equal labels not shared")
+	}
+	if r.Get(tdigest.Labels{"region": "eu"}) != nil {
+		t.Error("unexpected digest for missing labels")
+	}
+
+	var got []tdigest.Labels
+	r.Range(func(labels tdigest.Labels, td *tdigest.TDigest) bool {
+		got = append(got, labels)
+		return len(got) < 3
+	})
+	want := []tdigest.Labels{
+		{"region": "eu", "status": "200"},
+		{"region": "eu", "status": "500"},
+		{"region": "us", "status": "200"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected labels, got %v want %v", got, want)
+	}
+
+	byStatus, err := r.MergeBy("status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byStatus.Len() != 2 {
+		t.Errorf("unexpected number of merged digests, got %d want 2", byStatus.Len())
+	}
+	if got := byStatus.Get(tdigest.Labels{"status": "200"}).Count(); got != 800 {
+		t.Errorf("unexpected merged count, got %g want 800", got)
+	}
+	all, err := r.MergeBy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := all.Get(nil).Count(); got != 808 {
+		t.Errorf("unexpected total count, got %g want 808", got)
+	}
+
+	other := tdigest.NewRegistry(100)
+	other.Observe(tdigest.Labels{"region": "eu", "status": "500"}, 2000)
+	other.Observe(tdigest.Labels{"region": "ap"}, 1)
+	if err := r.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 5 || td.Count() != 5 || td.Quantile(1) != 2000 {
+		t.Error("registries not merged")
+	}
+
+	r.Delete(tdigest.Labels{"region": "ap"})
+	if r.Len() != 4 {
+		t.Errorf("unexpected number of digests after delete, got %d want 4", r.Len())
+	}
+}
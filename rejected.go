@@ -0,0 +1,77 @@
+package tdigest
+
+// RejectReason identifies why a sample was not added to a digest.
+type RejectReason int
+
+const (
+	// RejectNaNValue counts samples with a NaN value.
+	RejectNaNValue RejectReason = iota
+	// RejectInfiniteValue counts samples with an infinite value dropped by
+	// the non-finite value policy.
+	RejectInfiniteValue
+	// RejectNaNWeight counts samples with a NaN weight.
+	RejectNaNWeight
+	// RejectNonPositiveWeight counts samples with a zero or negative weight.
+	RejectNonPositiveWeight
+	// RejectInfiniteWeight counts samples with an infinite weight.
+	RejectInfiniteWeight
+
+	numRejectReasons
+)
+
+func (r RejectReason) String() string {
+	switch r {
+	case RejectNaNValue:
+		return "nan_value"
+	case RejectInfiniteValue:
+		return "infinite_value"
+	case RejectNaNWeight:
+		return "nan_weight"
+	case RejectNonPositiveWeight:
+		return "non_positive_weight"
+	case RejectInfiniteWeight:
+		return "infinite_weight"
+	}
+	return "unknown"
+}
+
+// SampleCount returns the number of samples added to the digest since it
+// was created or last reset, whatever their weights: one for every value
+// or centroid added, plus the sample counts of merged digests. Encodings do
+// not record it, so a decoded digest counts one sample per centroid.
+func (t *TDigest) SampleCount() uint64 {
+	t.lock()
+	defer t.unlock()
+	return t.accepted
+}
+
+// WeightSum returns the total weight of the samples added to the digest,
+// like Count, but without processing pending centroids.
+func (t *TDigest) WeightSum() float64 {
+	t.lock()
+	defer t.unlock()
+	return t.processedWeight + t.unprocessedWeight
+}
+
+// RejectedCount returns the number of samples ignored by the digest since it
+// was created or last reset, for any reason.
+func (t *TDigest) RejectedCount() uint64 {
+	t.lock()
+	defer t.unlock()
+	var n uint64
+	for _, c := range t.rejected {
+		n += c
+	}
+	return n
+}
+
+// Rejected returns the number of samples ignored by the digest since it was
+// created or last reset, for the given reason.
+func (t *TDigest) Rejected(reason RejectReason) uint64 {
+	if reason < 0 || reason >= numRejectReasons {
+		return 0
+	}
+	t.lock()
+	defer t.unlock()
+	return t.rejected[reason]
+}
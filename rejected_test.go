@@ -0,0 +1,99 @@
+package tdigest_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_Rejected(t *testing.T) {
+	td := tdigest.New(tdigest.WithNonFinitePolicy(tdigest.NonFiniteDrop))
+	td.Add(math.NaN(), 1)
+	td.Add(1, math.NaN())
+	td.Add(1, 0)
+	td.Add(1, -1000)
+	td.Add(1, math.Inf(1))
+	td.Add(math.Inf(-1), 1)
+	td.AddSlice([]float64{1, math.NaN(), 2, math.Inf(1)})
+	td.AddSorted([]float64{3, 4})
+	td.AddWeightedSlice([]float64{5, 6}, []float64{2, -1})
+	td.AddErr(7, math.NaN())
+
+	want := map[tdigest.RejectReason]uint64{
+		tdigest.RejectNaNValue:          2,
+		tdigest.RejectInfiniteValue:     2,
+		tdigest.RejectNaNWeight:         2,
+		tdigest.RejectNonPositiveWeight: 3,
+		tdigest.RejectInfiniteWeight:    1,
+	}
+	var total uint64
+	for reason, n := range want {
+		if got := td.Rejected(reason); got != n {
+			t.Errorf("unexpected count for %v, got %d want %d", reason, got, n)
+		}
+		total += n
+	}
+	if got := td.RejectedCount(); got != total {
+		t.Errorf("unexpected rejected count, got %d want %d", got, total)
+	}
+	if got := td.NonFiniteCount(); got != 4 {
+		t.Errorf("unexpected non-finite count, got %d want 4", got)
+	}
+	if got := td.SampleCount(); got != 5 {
+		t.Errorf("unexpected sample count, got %d want 5", got)
+	}
+	if got := td.Count(); got != 6 {
+		t.Errorf("unexpected count, got %g want 6", got)
+	}
+
+	td.Reset()
+	if td.RejectedCount() != 0 || td.SampleCount() != 0 {
+		t.Error("counters were not reset")
+	}
+}
+
+func TestRejectReason_String(t *testing.T) {
+	if got, want := tdigest.RejectNonPositiveWeight.String(), "non_positive_weight"; got != want {
+		t.Errorf("unexpected string, got %q want %q", got, want)
+	}
+	if got, want := tdigest.RejectReason(-1).String(), "unknown"; got != want {
+		t.Errorf("unexpected string, got %q want %q", got, want)
+	}
+}
+
+func TestTdigest_SampleCount(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.Add(1, 10)
+	td.AddSlice(UniformData[:5000])
+	td.AddCentroidList(tdigest.CentroidList{{Mean: 2, Weight: 0.5}, {Mean: 3, Weight: 0.5}})
+	if got := td.SampleCount(); got != 5003 {
+		t.Errorf("unexpected sample count, got %d want 5003", got)
+	}
+	if got := td.WeightSum(); got != 5011 {
+		t.Errorf("unexpected weight sum, got %g want 5011", got)
+	}
+
+	// Merging adds the samples of the merged digest, not its centroids.
+	merged := tdigest.NewWithCompression(100)
+	merged.Add(0, 1)
+	merged.Merge(td)
+	if got := merged.SampleCount(); got != 5004 {
+		t.Errorf("unexpected sample count after merge, got %d want 5004", got)
+	}
+	if got := merged.WeightSum(); got != 5012 {
+		t.Errorf("unexpected weight sum after merge, got %g want 5012", got)
+	}
+
+	data, _ := td.MarshalBinary()
+	decoded := tdigest.New()
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decoded.SampleCount(), uint64(len(td.Centroids(nil))); got != want {
+		t.Errorf("unexpected sample count after decoding, got %d want %d", got, want)
+	}
+	if got := decoded.WeightSum(); got != 5011 {
+		t.Errorf("unexpected weight sum after decoding, got %g want 5011", got)
+	}
+}
@@ -0,0 +1,89 @@
+package tdigest
+
+import (
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// TemplateFuncs returns helpers for embedding digest summaries in
+// text/template and html/template based reports:
+//
+//	quantile: QuantileString, e.g. {{quantile .Latency 0.99}}
+//	histogram: HistogramTable, e.g. {{histogram .Latency 10}}
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"quantile":  QuantileString,
+		"histogram": HistogramTable,
+	}
+}
+
+// QuantileString returns quantile q of the digest formatted for reports,
+// with six significant digits.
+func QuantileString(t *TDigest, q float64) string {
+	return formatReportFloat(t.Quantile(q))
+}
+
+// HistogramTable returns a plain text table approximating the histogram of
+// the digest, with the range between its minimum and maximum value split
+// into the given number of equal-width buckets. Counts are estimated from
+// the CDF and rounded to the nearest integer. In strict mode it returns
+// ErrUnflushed if the digest has pending centroids.
+func HistogramTable(t *TDigest, buckets int) (string, error) {
+	hs, err := histogramBuckets(t, buckets)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+	w.Write([]byte("lower\tupper\tcount\n"))
+	for _, h := range hs {
+		w.Write([]byte(formatReportFloat(h.Low) + "\t" +
+			formatReportFloat(h.High) + "\t" +
+			strconv.FormatFloat(h.Count, 'f', 0, 64) + "\n"))
+	}
+	w.Flush()
+	return b.String(), nil
+}
+
+// histogramBuckets splits the range between the minimum and maximum value of
+// the digest into the given number of equal-width buckets, with counts
+// estimated from the CDF. It returns no buckets if the digest is empty.
+func histogramBuckets(t *TDigest, buckets int) ([]Bucket, error) {
+	if buckets < 1 {
+		buckets = 1
+	}
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, err
+	}
+	s := t.summary()
+	count := s.weight
+	if count == 0 {
+		return nil, nil
+	}
+	min, max := s.min, s.max
+	if min == max {
+		buckets = 1
+	}
+	hs := make([]Bucket, 0, buckets)
+	width := (max - min) / float64(buckets)
+	lower, prev := min, 0.0
+	for i := 1; i <= buckets; i++ {
+		upper, cdf := min+float64(i)*width, 1.0
+		if i < buckets {
+			cdf = s.cdf(upper)
+		} else {
+			upper = max
+		}
+		hs = append(hs, Bucket{Low: lower, High: upper, Count: (cdf - prev) * count})
+		lower, prev = upper, cdf
+	}
+	return hs, nil
+}
+
+func formatReportFloat(x float64) string {
+	return strconv.FormatFloat(x, 'g', 6, 64)
+}
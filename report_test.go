@@ -0,0 +1,81 @@
+package tdigest_test
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestQuantileString(t *testing.T) {
+	td := tdigest.New()
+	for _, x := range []float64{1, 2, 3, 4, 5} {
+		td.Add(x, 1)
+	}
+	if got, want := tdigest.QuantileString(td, 0.5), "3"; got != want {
+		t.Errorf("unexpected quantile string, got %q want %q", got, want)
+	}
+	if got, want := tdigest.QuantileString(NormalDigest, 0.9), "13.8421"; got != want {
+		t.Errorf("unexpected quantile string, got %q want %q", got, want)
+	}
+}
+
+func TestHistogramTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []float64
+		buckets int
+		want    string
+	}{
+		{
+			name:    "empty",
+			buckets: 2,
+			want:    "lower  upper  count\n",
+		},
+		{
+			name:    "single value",
+			data:    []float64{3, 3},
+			buckets: 4,
+			want: "lower  upper  count\n" +
+				"3      3      2\n",
+		},
+		{
+			name:    "uniform",
+			data:    []float64{1, 2, 3, 4, 5, 5, 4, 3, 2, 1},
+			buckets: 2,
+			want: "lower  upper  count\n" +
+				"1      3      6\n" +
+				"3      5      4\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := tdigest.New()
+			for _, x := range tt.data {
+				td.Add(x, 1)
+			}
+			got, err := tdigest.HistogramTable(td, tt.buckets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("unexpected table, got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tmpl := template.Must(template.New("report").
+		Funcs(tdigest.TemplateFuncs()).
+		Parse("p90={{quantile . 0.9}}\n{{histogram . 4}}"))
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, NormalDigest); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); !strings.HasPrefix(got, "p90=13.8421\nlower") {
+		t.Errorf("unexpected report %q", got)
+	}
+}
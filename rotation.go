@@ -0,0 +1,86 @@
+package tdigest
+
+import (
+	"context"
+	"time"
+)
+
+// rotation is a running rotation scheduler, see Start.
+type rotation struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start rotates the slots on a new goroutine at every multiple of interval
+// since the zero time of the clock set by SetClock, such as every full five
+// minutes, until ctx is done or Stop is called. If onRotate is not nil, it
+// is called after each rotation with a copy of the slot that was closed,
+// e.g. to report the samples of every interval. Start stops any scheduler
+// started before. Like time.NewTicker, it panics if interval is not
+// positive.
+func (w *WindowedTDigest) Start(ctx context.Context, interval time.Duration, onRotate func(closed *TDigest)) {
+	if interval <= 0 {
+		panic("tdigest: non-positive interval for WindowedTDigest.Start")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	r := &rotation{cancel: cancel, done: make(chan struct{})}
+	// Replace the previous scheduler under one lock, so that concurrent
+	// calls leave a single one running.
+	w.mu.Lock()
+	prev := w.rotation
+	w.rotation = r
+	w.mu.Unlock()
+	if prev != nil {
+		prev.cancel()
+		<-prev.done
+	}
+
+	go func() {
+		defer close(r.done)
+		for {
+			w.mu.Lock()
+			now := w.now()
+			w.mu.Unlock()
+			timer := time.NewTimer(now.Truncate(interval).Add(interval).Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			closed := w.rotateClosed(onRotate != nil)
+			if onRotate != nil {
+				onRotate(closed)
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler started by Start, if any, and waits for it to
+// return, including from a call to its onRotate, which therefore must not
+// call Stop or Start itself.
+func (w *WindowedTDigest) Stop() {
+	w.mu.Lock()
+	r := w.rotation
+	w.rotation = nil
+	w.mu.Unlock()
+	if r != nil {
+		r.cancel()
+		<-r.done
+	}
+}
+
+// rotateClosed rotates like Rotate, returning a copy of the slot that was
+// closed if clone is set.
+func (w *WindowedTDigest) rotateClosed(clone bool) *TDigest {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire()
+	var closed *TDigest
+	if clone {
+		closed = w.slots[w.current].Clone()
+	}
+	w.rotate()
+	w.starts[w.current] = w.now()
+	return closed
+}
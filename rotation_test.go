@@ -0,0 +1,116 @@
+package tdigest_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestWindowedTDigest_Start(t *testing.T) {
+	w := tdigest.NewWindowed(100, 3)
+	w.Add(1, 1)
+	closed := make(chan *tdigest.TDigest, 10)
+	const interval = 20 * time.Millisecond
+	w.Start(context.Background(), interval, func(td *tdigest.TDigest) {
+		closed <- td
+	})
+	defer w.Stop()
+
+	var td *tdigest.TDigest
+	select {
+	case td = <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slots not rotated")
+	}
+	if td.Count() != 1 || td.Quantile(0.5) != 1 {
+		t.Error("unexpected closed slot")
+	}
+
+	w.Stop()
+	for len(closed) > 0 {
+		<-closed
+	}
+	n := w.Count()
+	time.Sleep(3 * interval)
+	if len(closed) != 0 || w.Count() != n {
+		t.Error("slots rotated after Stop")
+	}
+}
+
+func TestWindowedTDigest_StartClock(t *testing.T) {
+	w := tdigest.NewWindowed(100, 2)
+	// The clock is just short of a full hour, so the slots rotate right away.
+	now := time.Unix(1e9, 0).Truncate(time.Hour).Add(-time.Millisecond)
+	w.SetClock(func() time.Time { return now })
+	rotated := make(chan struct{}, 1)
+	w.Start(context.Background(), time.Hour, func(*tdigest.TDigest) {
+		select {
+		case rotated <- struct{}{}:
+		default:
+		}
+	})
+	defer w.Stop()
+	select {
+	case <-rotated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slots not rotated at the time of the clock")
+	}
+}
+
+func TestWindowedTDigest_StartContext(t *testing.T) {
+	w := tdigest.NewWindowed(100, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	rotated := make(chan struct{}, 10)
+	w.Start(ctx, time.Millisecond, func(*tdigest.TDigest) { rotated <- struct{}{} })
+	<-rotated
+	cancel()
+	w.Stop()
+	for len(rotated) > 0 {
+		<-rotated
+	}
+	time.Sleep(10 * time.Millisecond)
+	if len(rotated) != 0 {
+		t.Error("slots rotated after the context was done")
+	}
+}
+
+func TestWindowedTDigest_StartInterval(t *testing.T) {
+	w := tdigest.NewWindowed(100, 2)
+	for _, interval := range []time.Duration{0, -time.Second} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for interval %v", interval)
+				}
+			}()
+			w.Start(context.Background(), interval, nil)
+		}()
+	}
+	w.Stop()
+}
+
+func TestWindowedTDigest_StartConcurrent(t *testing.T) {
+	w := tdigest.NewWindowed(100, 2)
+	var rotations int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Start(context.Background(), time.Millisecond, func(*tdigest.TDigest) {
+				atomic.AddInt64(&rotations, 1)
+			})
+		}()
+	}
+	wg.Wait()
+	w.Stop()
+	n := atomic.LoadInt64(&rotations)
+	time.Sleep(20 * time.Millisecond)
+	if got := atomic.LoadInt64(&rotations); got != n {
+		t.Errorf("schedulers still running after Stop, %d rotations since", got-n)
+	}
+}
@@ -0,0 +1,155 @@
+package tdigest
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ShardedTDigest is a digest that is safe for concurrent use. Samples are
+// spread over several digests, each guarded by its own lock, so goroutines
+// adding concurrently rarely contend. The shards are merged when the
+// distribution is queried.
+type ShardedTDigest struct {
+	compression float64
+	opts        []Option
+	shards      []shard
+	// indices holds shard indices. Since a sync.Pool caches its values per
+	// processor, goroutines running on different processors mostly pick
+	// different shards without writing to shared memory; next only hands
+	// out the indices the pool creates.
+	indices sync.Pool
+	next    uint32
+
+	// mu guards merged, which is reused between queries.
+	mu     sync.Mutex
+	merged *TDigest
+}
+
+// shard is padded to a cache line, so that locking one shard does not slow
+// down its neighbours.
+type shard struct {
+	mu sync.Mutex
+	td *TDigest
+	_  [48]byte
+}
+
+// NewSharded initializes a new concurrent distribution with the given
+// compression, spread over n shards, each created with opts. A number of
+// shards below one uses GOMAXPROCS.
+func NewSharded(c float64, n int, opts ...Option) *ShardedTDigest {
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	s := &ShardedTDigest{
+		compression: c,
+		opts:        opts,
+		shards:      make([]shard, n),
+		merged:      NewWithCompression(c, opts...),
+	}
+	for i := range s.shards {
+		s.shards[i].td = NewWithCompression(c, opts...)
+	}
+	s.indices.New = func() interface{} {
+		i := int(atomic.AddUint32(&s.next, 1) % uint32(len(s.shards)))
+		return &i
+	}
+	return s
+}
+
+// shard returns the shard to add to, usually the one of the processor the
+// calling goroutine runs on.
+func (s *ShardedTDigest) shard() *shard {
+	i := s.indices.Get().(*int)
+	sh := &s.shards[*i]
+	s.indices.Put(i)
+	return sh
+}
+
+// Add adds a value x with a weight w to the distribution.
+func (s *ShardedTDigest) Add(x, w float64) {
+	sh := s.shard()
+	sh.mu.Lock()
+	sh.td.Add(x, w)
+	sh.mu.Unlock()
+}
+
+// AddSlice adds each of the values xs with a weight of one to a single
+// shard, see TDigest.AddSlice.
+func (s *ShardedTDigest) AddSlice(xs []float64) {
+	sh := s.shard()
+	sh.mu.Lock()
+	sh.td.AddSlice(xs)
+	sh.mu.Unlock()
+}
+
+// Merge merges the supplied digest into a single shard, see
+// TDigest.MergeErr, which also describes the errors of strict mode. It may
+// be called concurrently with Add and other merges, but t2 itself must not
+// be in use by other goroutines.
+func (s *ShardedTDigest) Merge(t2 *TDigest) error {
+	sh := s.shard()
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.td.MergeErr(t2)
+}
+
+// Digest returns a new digest holding the merged data of all shards.
+func (s *ShardedTDigest) Digest() *TDigest {
+	t := NewWithCompression(s.compression, s.opts...)
+	s.mergeInto(t)
+	return t
+}
+
+// Quantile returns the (approximate) quantile of the merged distribution,
+// see TDigest.Quantile.
+func (s *ShardedTDigest) Quantile(q float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mergeInto(s.merged)
+	return s.merged.Quantile(q)
+}
+
+// CDF returns the cumulative distribution function of the merged
+// distribution for a given value x, see TDigest.CDF.
+func (s *ShardedTDigest) CDF(x float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mergeInto(s.merged)
+	return s.merged.CDF(x)
+}
+
+// Count returns the total weight of all shards.
+func (s *ShardedTDigest) Count() float64 {
+	var n float64
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		n += sh.td.Count()
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+// Reset resets all shards to their initial state.
+func (s *ShardedTDigest) Reset() {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		sh.td.Reset()
+		sh.mu.Unlock()
+	}
+}
+
+// mergeInto resets t and merges all shards into it, locking one shard at a
+// time. t is flushed, so that it can be read in strict mode.
+func (s *ShardedTDigest) mergeInto(t *TDigest) {
+	t.Reset()
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		t.merge(sh.td)
+		sh.mu.Unlock()
+	}
+	t.Flush()
+}
@@ -0,0 +1,105 @@
+package tdigest_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func BenchmarkShardedTDigest_Add(b *testing.B) {
+	b.Run("sharded", func(b *testing.B) {
+		s := tdigest.NewSharded(1000, 0)
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				s.Add(NormalData[i%len(NormalData)], 1)
+			}
+		})
+	})
+	b.Run("locked", func(b *testing.B) {
+		td := tdigest.NewWithCompression(1000, tdigest.WithLocker(new(sync.Mutex)))
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				td.Add(NormalData[i%len(NormalData)], 1)
+			}
+		})
+	})
+}
+
+func TestShardedTDigest(t *testing.T) {
+	const workers = 4
+	s := tdigest.NewSharded(1000, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(xs []float64) {
+			defer wg.Done()
+			for j, x := range xs {
+				s.Add(x, 1)
+				if j%10000 == 0 {
+					s.Quantile(0.5)
+				}
+			}
+		}(NormalData[i*len(NormalData)/workers : (i+1)*len(NormalData)/workers])
+	}
+	wg.Wait()
+
+	if got, want := s.Count(), float64(len(NormalData)); got != want {
+		t.Errorf("unexpected count, got %g want %g", got, want)
+	}
+	if err := compareQuantiles(s.Digest(), NormalDigest, 0.001); err != nil {
+		t.Errorf("sharded digest differs: %s", err.Error())
+	}
+	for _, q := range quantiles {
+		if got, want := s.Quantile(q), s.Digest().Quantile(q); got != want {
+			t.Errorf("unexpected quantile %g, got %g want %g", q, got, want)
+		}
+	}
+	x := NormalDigest.Quantile(0.5)
+	if got, want := s.CDF(x), s.Digest().CDF(x); got != want {
+		t.Errorf("unexpected CDF %g, got %g want %g", x, got, want)
+	}
+
+	s.Reset()
+	if got := s.Count(); got != 0 {
+		t.Errorf("unexpected count after reset, got %g", got)
+	}
+	s.AddSlice([]float64{1, 2, 3})
+	if got := s.Quantile(0.5); got != 2 {
+		t.Errorf("unexpected median, got %g want 2", got)
+	}
+}
+
+func TestShardedTDigest_Merge(t *testing.T) {
+	const workers = 4
+	s := tdigest.NewSharded(1000, workers)
+	var wg sync.WaitGroup
+	n := len(NormalData) / (2 * workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(xs []float64) {
+			defer wg.Done()
+			for _, x := range xs {
+				s.Add(x, 1)
+			}
+		}(NormalData[2*i*n : (2*i+1)*n])
+		go func(xs []float64) {
+			defer wg.Done()
+			for j := 0; j < len(xs); j += 5000 {
+				td := tdigest.New()
+				td.AddSlice(xs[j : j+5000])
+				if err := s.Merge(td); err != nil {
+					t.Error(err)
+				}
+			}
+		}(NormalData[(2*i+1)*n : (2*i+2)*n])
+	}
+	wg.Wait()
+
+	if got, want := s.Count(), float64(len(NormalData)); got != want {
+		t.Errorf("unexpected count, got %g want %g", got, want)
+	}
+	if err := compareQuantiles(s.Digest(), NormalDigest, 0.001); err != nil {
+		t.Errorf("sharded digest differs: %s", err.Error())
+	}
+}
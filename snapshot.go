@@ -0,0 +1,35 @@
+package tdigest
+
+// Snapshot processes any pending centroids and returns an immutable view of
+// the digest like Freeze, but without copying: the view shares the
+// centroids of the digest, which copies them only once it next modifies
+// them. This makes taking a snapshot cheap, e.g. at every scrape, as long as
+// the digest is written less often than it is snapshotted.
+//
+// The snapshot is safe for concurrent use, including while the digest is
+// being written by the goroutine that owns it. In strict mode, Snapshot
+// returns ErrUnflushed if the digest has pending centroids.
+func (t *TDigest) Snapshot() (*FrozenDigest, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, err
+	}
+	t.shared = true
+	return &FrozenDigest{
+		compression: t.Compression,
+		s:           t.summary(),
+	}, nil
+}
+
+// unshare gives the digest its own copy of the processed centroids and
+// cumulative weights, if they are shared with a snapshot, so that they can be
+// modified.
+func (t *TDigest) unshare() {
+	if !t.shared {
+		return
+	}
+	t.processed = append(make(CentroidList, 0, cap(t.processed)), t.processed...)
+	t.cumulative = append(make([]float64, 0, cap(t.cumulative)), t.cumulative...)
+	t.shared = false
+}
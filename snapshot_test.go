@@ -0,0 +1,69 @@
+package tdigest_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_Snapshot(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(td *tdigest.TDigest)
+	}{
+		{name: "add", write: func(td *tdigest.TDigest) { td.AddSlice(UniformData[:10000]) }},
+		{name: "add sorted", write: func(td *tdigest.TDigest) { td.AddSorted([]float64{1, 2, 3}) }},
+		{name: "scale", write: func(td *tdigest.TDigest) { td.Scale(2) }},
+		{name: "map values", write: func(td *tdigest.TDigest) { td.MapValues(func(x float64) float64 { return -x }, true) }},
+		{name: "reset", write: func(td *tdigest.TDigest) { td.Reset(); td.AddSlice(UniformData[:10000]) }},
+		{name: "clone into", write: func(td *tdigest.TDigest) { NormalDigest.CloneInto(td) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := tdigest.NewWithCompression(100)
+			td.AddSlice(NormalData[:10000])
+			snap, err := td.Snapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := td.Freeze()
+
+			tt.write(td)
+			td.Quantile(0.5)
+			if !reflect.DeepEqual(snap.Centroids(nil), want.Centroids(nil)) {
+				t.Error("snapshot centroids changed")
+			}
+			for _, q := range quantiles {
+				if got, w := snap.Quantile(q), want.Quantile(q); got != w {
+					t.Errorf("unexpected quantile %g, got %g want %g", q, got, w)
+				}
+			}
+		})
+	}
+}
+
+func TestTdigest_SnapshotConcurrent(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	snaps := make(chan *tdigest.FrozenDigest)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for snap := range snaps {
+			snap.Quantile(0.99)
+			snap.CDF(10)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		td.AddSlice(NormalData[i*1000 : (i+1)*1000])
+		snap, err := td.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		snaps <- snap
+	}
+	close(snaps)
+	wg.Wait()
+}
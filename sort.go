@@ -0,0 +1,108 @@
+package tdigest
+
+import "math/bits"
+
+// sortCentroids sorts the centroids by ascending mean. It is a quicksort
+// specialized for CentroidList, which avoids the interface calls for Less
+// and Swap that dominate sort.Sort on the hot path of process. Like
+// sort.Sort, it is not stable.
+func sortCentroids(l CentroidList) {
+	// Fall back to heapsort once the recursion gets this deep, which bounds
+	// the worst case at O(n log n).
+	quickSortCentroids(l, 2*bits.Len(uint(len(l))))
+}
+
+// insertionSortThreshold is the length below which lists are sorted by
+// insertion sort.
+const insertionSortThreshold = 12
+
+func quickSortCentroids(l CentroidList, depth int) {
+	for len(l) > insertionSortThreshold {
+		if depth == 0 {
+			heapSortCentroids(l)
+			return
+		}
+		depth--
+		p := partitionCentroids(l)
+		// Recurse into the smaller half, and loop over the larger one, to
+		// bound the stack depth.
+		if p < len(l)-p {
+			quickSortCentroids(l[:p], depth)
+			l = l[p+1:]
+		} else {
+			quickSortCentroids(l[p+1:], depth)
+			l = l[:p]
+		}
+	}
+	insertionSortCentroids(l)
+}
+
+// partitionCentroids partitions l around the median of its first, middle and
+// last means, and returns the final index of the pivot.
+func partitionCentroids(l CentroidList) int {
+	hi := len(l) - 1
+	mid := hi / 2
+	if l[mid].Mean < l[0].Mean {
+		l[mid], l[0] = l[0], l[mid]
+	}
+	if l[hi].Mean < l[0].Mean {
+		l[hi], l[0] = l[0], l[hi]
+	}
+	if l[hi].Mean < l[mid].Mean {
+		l[hi], l[mid] = l[mid], l[hi]
+	}
+	// l[0] <= l[mid] <= l[hi]; move the pivot out of the way.
+	l[mid], l[hi-1] = l[hi-1], l[mid]
+	pivot := l[hi-1].Mean
+	i, j := 0, hi-1
+	for {
+		for i++; l[i].Mean < pivot; i++ {
+		}
+		for j--; pivot < l[j].Mean; j-- {
+		}
+		if i >= j {
+			break
+		}
+		l[i], l[j] = l[j], l[i]
+	}
+	l[i], l[hi-1] = l[hi-1], l[i]
+	return i
+}
+
+func insertionSortCentroids(l CentroidList) {
+	for i := 1; i < len(l); i++ {
+		c := l[i]
+		j := i
+		for ; j > 0 && c.Mean < l[j-1].Mean; j-- {
+			l[j] = l[j-1]
+		}
+		l[j] = c
+	}
+}
+
+func heapSortCentroids(l CentroidList) {
+	for i := len(l)/2 - 1; i >= 0; i-- {
+		siftDownCentroids(l, i, len(l))
+	}
+	for end := len(l) - 1; end > 0; end-- {
+		l[0], l[end] = l[end], l[0]
+		siftDownCentroids(l, 0, end)
+	}
+}
+
+func siftDownCentroids(l CentroidList, root, n int) {
+	for {
+		child := 2*root + 1
+		if child >= n {
+			return
+		}
+		if child+1 < n && l[child].Mean < l[child+1].Mean {
+			child++
+		}
+		if !(l[root].Mean < l[child].Mean) {
+			return
+		}
+		l[root], l[child] = l[child], l[root]
+		root = child
+	}
+}
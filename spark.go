@@ -0,0 +1,116 @@
+package tdigest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidSpark is returned when decoding a malformed Spark percentile
+// digest.
+const ErrInvalidSpark = Error("invalid Spark percentile digest")
+
+// ErrSparkUnsupported is returned when encoding a digest that cannot be
+// represented as a Spark percentile digest.
+const ErrSparkUnsupported = Error("digest cannot be represented as a Spark percentile digest")
+
+// sparkCompressThreshold is the default compressThreshold of the
+// QuantileSummaries of Spark.
+const sparkCompressThreshold = 10000
+
+// sparkHeaderSize is the size of the compressThreshold, relativeError, count
+// and number of samples that precede the samples.
+const sparkHeaderSize = 4 + 8 + 8 + 4
+
+// sparkSampleSize is the size of the value, g and delta of a sample.
+const sparkSampleSize = 8 + 8 + 8
+
+// MarshalSparkPercentileDigest encodes the digest as the serialized
+// PercentileDigest that Spark keeps as the state of approx_percentile and
+// percentile_approx: the compressThreshold as an int, the relativeError as a
+// double and the count as a long, followed by the number of samples as an
+// int and, for each sample, its value as a double and its g and delta as
+// longs, all big endian.
+//
+// Every centroid becomes a sample with its mean as value, its weight as g and
+// a delta of zero, and the relativeError is chosen to cover half the weight
+// of the largest centroid, so that Spark answers every rank with a centroid.
+// Since Spark counts are integers, other weights are reported as
+// ErrSparkUnsupported.
+func (t *TDigest) MarshalSparkPercentileDigest() ([]byte, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, err
+	}
+	count, err := t.integralCount(ErrSparkUnsupported)
+	if err != nil {
+		return nil, err
+	}
+
+	var relativeError, maxWeight float64
+	for _, c := range t.processed {
+		maxWeight = math.Max(maxWeight, c.Weight)
+	}
+	if count > 0 {
+		relativeError = maxWeight / 2 / float64(count)
+	}
+
+	buf := make([]byte, 0, sparkHeaderSize+t.processed.Len()*sparkSampleSize)
+	buf = appendUint32(buf, sparkCompressThreshold)
+	buf = appendFloat64(buf, relativeError)
+	buf = appendUint64(buf, uint64(count))
+	buf = appendUint32(buf, uint32(t.processed.Len()))
+	for _, c := range t.processed {
+		buf = appendFloat64(buf, c.Mean)
+		buf = appendUint64(buf, uint64(c.Weight))
+		buf = appendUint64(buf, 0)
+	}
+	return buf, nil
+}
+
+// ParseSparkPercentileDigest decodes a serialized Spark PercentileDigest
+// into a new digest with the given compression and options. Every sample
+// becomes a centroid with its value as mean and its g as weight, so the
+// uncertainty of its rank, delta, is dropped. The min and max are taken to
+// be the values of the first and last samples. The compression must be
+// between 1 and 100000.
+func ParseSparkPercentileDigest(data []byte, compression float64, opts ...Option) (*TDigest, error) {
+	if !(compression >= 1 && compression <= maxDecodeCompression) {
+		return nil, fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidSpark, float64(maxDecodeCompression))
+	}
+	if len(data) < sparkHeaderSize {
+		return nil, fmt.Errorf("%w: truncated", ErrInvalidSpark)
+	}
+	count := int64(binary.BigEndian.Uint64(data[12:]))
+	n := int(int32(binary.BigEndian.Uint32(data[20:])))
+	data = data[sparkHeaderSize:]
+	if n < 0 || len(data) != n*sparkSampleSize {
+		return nil, fmt.Errorf("%w: expected %d samples in %d bytes", ErrInvalidSpark, n, len(data))
+	}
+
+	d := &binaryDecoder{centroids: make(CentroidList, 0, n), invalid: ErrInvalidSpark}
+	for i := 0; i < n; i++ {
+		sample := data[i*sparkSampleSize:]
+		g := int64(binary.BigEndian.Uint64(sample[8:]))
+		if g <= 0 {
+			return nil, fmt.Errorf("%w: sample %d: invalid g", ErrInvalidSpark, i)
+		}
+		if err := d.add(readFloat64(sample), float64(g)); err != nil {
+			return nil, err
+		}
+	}
+	if d.weight != float64(count) {
+		return nil, fmt.Errorf("%w: samples do not add up to the count %d", ErrInvalidSpark, count)
+	}
+
+	h := binaryHeader{compression: compression}
+	if n > 0 {
+		h.min, h.max = d.centroids[0].Mean, d.centroids[n-1].Mean
+	}
+	t := NewWithCompression(compression, opts...)
+	if err := t.setDecoded(h, d); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,92 @@
+package tdigest_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+// sparkQuery answers a quantile from a serialized Spark PercentileDigest
+// like QuantileSummaries.query.
+func sparkQuery(data []byte, q float64) float64 {
+	relativeError := math.Float64frombits(binary.BigEndian.Uint64(data[4:]))
+	count := float64(binary.BigEndian.Uint64(data[12:]))
+	n := int(binary.BigEndian.Uint32(data[20:]))
+	rank := math.Ceil(q * count)
+	targetError := math.Ceil(relativeError * count)
+	minRank := 0.0
+	value := func(i int) float64 { return math.Float64frombits(binary.BigEndian.Uint64(data[24+24*i:])) }
+	for i := 0; i < n-1; i++ {
+		minRank += float64(binary.BigEndian.Uint64(data[24+24*i+8:]))
+		maxRank := minRank + float64(binary.BigEndian.Uint64(data[24+24*i+16:]))
+		if maxRank-targetError <= rank && rank <= minRank+targetError {
+			return value(i)
+		}
+	}
+	return value(n - 1)
+}
+
+func TestTdigest_MarshalSparkPercentileDigest(t *testing.T) {
+	data, err := NormalDigest.MarshalSparkPercentileDigest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relativeError := math.Float64frombits(binary.BigEndian.Uint64(data[4:]))
+	for _, q := range quantiles {
+		x := sparkQuery(data, q)
+		if got := NormalDigest.CDF(x); math.Abs(got-q) > 2*relativeError+1e-6 {
+			t.Errorf("quantile %g: Spark answers %g at rank %g", q, x, got)
+		}
+	}
+
+	td, err := tdigest.ParseSparkPercentileDigest(data, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(td.Centroids(nil), NormalDigest.Centroids(nil)) {
+		t.Error("digest differs after round trip")
+	}
+
+	empty, _ := tdigest.New().MarshalSparkPercentileDigest()
+	if td, err := tdigest.ParseSparkPercentileDigest(empty, 100); err != nil || td.Count() != 0 {
+		t.Errorf("unexpected empty digest %v %v", td, err)
+	}
+
+	weighted := tdigest.NewWithCompression(100)
+	weighted.Add(1, 0.5)
+	if _, err := weighted.MarshalSparkPercentileDigest(); !errors.Is(err, tdigest.ErrSparkUnsupported) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseSparkPercentileDigestErrors(t *testing.T) {
+	td := tdigest.NewWithCompression(100)
+	td.AddValues(1, 2, 3)
+	valid, _ := td.MarshalSparkPercentileDigest()
+	corrupt := func(offset int, b ...byte) []byte {
+		data := append([]byte(nil), valid...)
+		copy(data[offset:], b)
+		return data
+	}
+	for name, data := range map[string][]byte{
+		"empty":     nil,
+		"truncated": valid[:len(valid)-1],
+		"samples":   corrupt(20, 0xff),
+		"count":     corrupt(19, 4),
+		"g":         corrupt(24+8, 0xff),
+		"unsorted":  corrupt(24, 0x40, 0x20),
+	} {
+		if _, err := tdigest.ParseSparkPercentileDigest(data, 100); !errors.Is(err, tdigest.ErrInvalidSpark) {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+	for _, compression := range []float64{0, math.NaN(), 1e6} {
+		if _, err := tdigest.ParseSparkPercentileDigest(valid, compression); !errors.Is(err, tdigest.ErrInvalidSpark) {
+			t.Errorf("compression %g: unexpected error %v", compression, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package tdigest
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Stats holds the total weight and the extremes of a distribution.
+type Stats struct {
+	Count float64
+	Min   float64
+	Max   float64
+}
+
+// atomicStats holds the bits of the fields of Stats, for atomic access.
+type atomicStats struct {
+	count uint64
+	min   uint64
+	max   uint64
+}
+
+// WithAtomicStats makes the digest maintain copies of its count and extremes
+// that Stats can read from any goroutine, without holding the lock that
+// guards the digest. This costs a few atomic stores per add.
+func WithAtomicStats() Option {
+	return func(t *TDigest) {
+		t.stats = new(atomicStats)
+	}
+}
+
+// Stats returns the total weight, and the minimum and maximum values, of
+// the distribution. The extremes are NaN if the digest is empty. With
+// WithAtomicStats, Stats may be called concurrently with any method that
+// modifies the digest, apart from ResetWithOptions and CloneInto; otherwise
+// it takes the lock set by WithLocker, if any.
+func (t *TDigest) Stats() Stats {
+	if s := t.stats; s != nil {
+		return Stats{
+			Count: math.Float64frombits(atomic.LoadUint64(&s.count)),
+			Min:   math.Float64frombits(atomic.LoadUint64(&s.min)),
+			Max:   math.Float64frombits(atomic.LoadUint64(&s.max)),
+		}
+	}
+	t.lock()
+	defer t.unlock()
+	return t.currentStats()
+}
+
+func (t *TDigest) currentStats() Stats {
+	s := Stats{
+		Count: t.processedWeight + t.unprocessedWeight,
+		Min:   t.min,
+		Max:   t.max,
+	}
+	if s.Count == 0 {
+		s.Min, s.Max = math.NaN(), math.NaN()
+	}
+	return s
+}
+
+// publishStats updates the copies of the count and extremes, if enabled.
+func (t *TDigest) publishStats() {
+	if t.stats == nil {
+		return
+	}
+	s := t.currentStats()
+	atomic.StoreUint64(&t.stats.count, math.Float64bits(s.Count))
+	atomic.StoreUint64(&t.stats.min, math.Float64bits(s.Min))
+	atomic.StoreUint64(&t.stats.max, math.Float64bits(s.Max))
+}
@@ -0,0 +1,73 @@
+package tdigest_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_Stats(t *testing.T) {
+	for _, opts := range [][]tdigest.Option{nil, {tdigest.WithAtomicStats()}} {
+		td := tdigest.NewWithCompression(100, opts...)
+		s := td.Stats()
+		if s.Count != 0 || !math.IsNaN(s.Min) || !math.IsNaN(s.Max) {
+			t.Errorf("unexpected stats of empty digest: %+v", s)
+		}
+
+		td.Add(5, 2)
+		td.AddSlice([]float64{3, 7})
+		td.AddSorted([]float64{4, 6})
+		other := tdigest.New()
+		other.Add(-1, 1)
+		td.Merge(other)
+		want := tdigest.Stats{Count: 7, Min: -1, Max: 7}
+		if got := td.Stats(); got != want {
+			t.Errorf("unexpected stats, got %+v want %+v", got, want)
+		}
+
+		if err := td.Scale(2); err != nil {
+			t.Fatal(err)
+		}
+		want.Count = 14
+		if got := td.Stats(); got != want {
+			t.Errorf("unexpected stats after scaling, got %+v want %+v", got, want)
+		}
+
+		clone := td.Clone()
+		td.Reset()
+		if got := clone.Stats(); got != want {
+			t.Errorf("unexpected stats of clone, got %+v want %+v", got, want)
+		}
+		if got := td.Stats(); got.Count != 0 {
+			t.Errorf("unexpected stats after reset: %+v", got)
+		}
+	}
+}
+
+func TestWithAtomicStats(t *testing.T) {
+	td := tdigest.New(tdigest.WithAtomicStats())
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				td.Stats()
+			}
+		}
+	}()
+	for _, x := range NormalData[:100000] {
+		td.Add(x, 1)
+	}
+	close(done)
+	wg.Wait()
+	if got := td.Stats().Count; got != 100000 {
+		t.Errorf("unexpected count, got %g want 100000", got)
+	}
+}
@@ -0,0 +1,73 @@
+package tdigest
+
+import (
+	"hash/crc32"
+	"io"
+)
+
+// streamBufferSize is the size of the buffer used to encode centroids a
+// chunk at a time.
+const streamBufferSize = 64 * maxBinaryCentroidSize
+
+// MarshalBinaryTo writes the encoding of MarshalBinary to w, a chunk at a
+// time, without holding the whole encoding in memory.
+func (t *TDigest) MarshalBinaryTo(w io.Writer) error {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return err
+	}
+
+	var chunk [streamBufferSize + checksumSize]byte
+	buf := t.appendBinaryHeader(chunk[:0])
+	var key uint64
+	var crc uint32
+	for i, c := range t.processed {
+		if len(buf)+maxBinaryCentroidSize > streamBufferSize {
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+			crc = crc32.Update(crc, crcTable, buf)
+			buf = chunk[:0]
+		}
+		buf, key = appendBinaryCentroid(buf, i, key, c)
+	}
+	crc = crc32.Update(crc, crcTable, buf)
+	_, err := w.Write(appendUint32(buf, crc))
+	return err
+}
+
+// UnmarshalBinaryFrom reads an encoding written by MarshalBinaryTo, or
+// MarshalBinary, from r, and replaces the data of the digest like
+// UnmarshalBinary. It reads exactly the encoded digest, so further data may
+// follow it in r. Since the checksum can only be verified once the whole
+// encoding has been read, a corrupt encoding may also be reported as
+// ErrInvalidBinary.
+func (t *TDigest) UnmarshalBinaryFrom(r io.Reader) error {
+	br, ok := r.(binaryReader)
+	if !ok {
+		br = &byteReader{Reader: r}
+	}
+	cr := &checksumReader{r: br}
+	h, d, err := decodeBinary(cr)
+	if err != nil {
+		return err
+	}
+	if err := cr.verify(); err != nil {
+		return err
+	}
+	return t.setDecoded(h, d)
+}
+
+// byteReader adds ReadByte to a reader without reading ahead.
+type byteReader struct {
+	io.Reader
+	b [1]byte
+}
+
+func (r *byteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(r.Reader, r.b[:]); err != nil {
+		return 0, err
+	}
+	return r.b[0], nil
+}
@@ -0,0 +1,55 @@
+package tdigest_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/iotest"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_MarshalBinaryTo(t *testing.T) {
+	small := tdigest.NewWithCompression(10)
+	small.AddValues(1, 2, 3)
+	for _, td := range []*tdigest.TDigest{tdigest.New(), small, NormalDigest} {
+		var buf bytes.Buffer
+		if err := td.MarshalBinaryTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := td.MarshalBinary()
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Fatal("streamed encoding differs from MarshalBinary")
+		}
+
+		// Two digests back to back, read one byte at a time.
+		buf.Write(want)
+		r := iotest.OneByteReader(&buf)
+		for i := 0; i < 2; i++ {
+			got := tdigest.New()
+			if err := got.UnmarshalBinaryFrom(r); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+				t.Error("centroids differ after round trip")
+			}
+		}
+	}
+}
+
+func TestTdigest_UnmarshalBinaryFromErrors(t *testing.T) {
+	data, _ := NormalDigest.MarshalBinary()
+	for _, n := range []int{0, 10, 32, 100, len(data) - 1} {
+		err := tdigest.New().UnmarshalBinaryFrom(bytes.NewReader(data[:n]))
+		if !errors.Is(err, tdigest.ErrInvalidBinary) {
+			t.Errorf("truncated at %d: unexpected error %v", n, err)
+		}
+	}
+
+	// Errors of the reader are passed on.
+	err := tdigest.New().UnmarshalBinaryFrom(iotest.TimeoutReader(bytes.NewReader(data)))
+	if err != iotest.ErrTimeout {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package tdigest
+
+import "math"
+
+// ErrUnflushed is returned in strict mode when a digest with pending
+// centroids is read without calling Flush first.
+const ErrUnflushed = Error("digest has unprocessed centroids, call Flush first")
+
+// ErrConfigMismatch is returned in strict mode when merging digests with
+// different compressions.
+const ErrConfigMismatch = Error("digests have different compressions")
+
+// WithStrict enables strict mode, in which reading the digest never
+// processes pending centroids, which is lossy, implicitly:
+//
+//   - Quantile and CDF do not process pending centroids; QuantileErr and
+//     CDFErr return ErrUnflushed until Flush is called, as do all the other
+//     readers and encoders returning an error, such as Freeze, Split,
+//     FormatText, WriteLineProtocol, MarshalBinary, Columns, Hash and
+//     ToDDSketch, and MapValues and Canonicalize.
+//   - Count reports the total weight without processing, and Centroids and
+//     ForEachCentroid leave out the pending centroids.
+//   - MergeErr returns ErrConfigMismatch for a digest with a different
+//     compression, and ErrUnflushed for a digest with pending centroids;
+//     Merge panics with those errors.
+//
+// Pending centroids are still processed by Flush and ShrinkToFit, and when
+// adding fills the buffer.
+func WithStrict() Option {
+	return func(t *TDigest) {
+		t.strict = true
+	}
+}
+
+// QuantileErr returns the (approximate) quantile of the distribution like
+// Quantile. In strict mode it returns ErrUnflushed if the digest has pending
+// centroids; it always returns a nil error otherwise.
+func (t *TDigest) QuantileErr(q float64) (float64, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return math.NaN(), err
+	}
+	if x, ok := t.cached(false, q); ok {
+		return x, nil
+	}
+	x := t.summary().quantile(q)
+	t.store(false, q, x)
+	return x, nil
+}
+
+// CDFErr returns the cumulative distribution function for a given value x
+// like CDF. In strict mode it returns ErrUnflushed if the digest has pending
+// centroids; it always returns a nil error otherwise.
+func (t *TDigest) CDFErr(x float64) (float64, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return math.NaN(), err
+	}
+	if p, ok := t.cached(true, x); ok {
+		return p, nil
+	}
+	p := t.summary().cdf(x)
+	t.store(true, x, p)
+	return p, nil
+}
+
+// prepareRead brings the processed centroids and cumulative weights up to
+// date, unless that is forbidden by strict mode.
+func (t *TDigest) prepareRead() error {
+	if t.strict && t.pending() {
+		return ErrUnflushed
+	}
+	t.process()
+	t.updateCumulative()
+	return nil
+}
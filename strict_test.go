@@ -0,0 +1,297 @@
+package tdigest_test
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestWithStrict(t *testing.T) {
+	td := tdigest.NewWithCompression(100, tdigest.WithStrict())
+	for _, x := range []float64{1, 2, 3, 4, 5} {
+		td.Add(x, 1)
+	}
+
+	if _, err := td.QuantileErr(0.5); err != tdigest.ErrUnflushed {
+		t.Errorf("expected ErrUnflushed from QuantileErr, got %v", err)
+	}
+	if _, err := td.CDFErr(3); err != tdigest.ErrUnflushed {
+		t.Errorf("expected ErrUnflushed from CDFErr, got %v", err)
+	}
+	if q := td.Quantile(0.5); !math.IsNaN(q) {
+		t.Errorf("expected NaN quantile before Flush, got %g", q)
+	}
+	if c := td.Count(); c != 5 {
+		t.Errorf("unexpected count before Flush, got %g want 5", c)
+	}
+
+	td.Flush()
+	if q, err := td.QuantileErr(0.5); err != nil || q != 3 {
+		t.Errorf("unexpected quantile after Flush, got %g, %v want 3", q, err)
+	}
+	if p, err := td.CDFErr(5); err != nil || p != 1 {
+		t.Errorf("unexpected CDF after Flush, got %g, %v want 1", p, err)
+	}
+
+	other := tdigest.NewWithCompression(1000)
+	other.Add(10, 1)
+	other.Flush()
+	if err := td.MergeErr(other); err != tdigest.ErrConfigMismatch {
+		t.Errorf("expected ErrConfigMismatch, got %v", err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != tdigest.ErrConfigMismatch {
+				t.Errorf("expected Merge to panic with ErrConfigMismatch, got %v", r)
+			}
+		}()
+		td.Merge(other)
+	}()
+	if c := td.Count(); c != 5 {
+		t.Errorf("mismatched merge changed count to %g", c)
+	}
+
+	unflushed := tdigest.NewWithCompression(100)
+	unflushed.Add(10, 1)
+	if err := td.MergeErr(unflushed); err != tdigest.ErrUnflushed {
+		t.Errorf("expected ErrUnflushed from MergeErr, got %v", err)
+	}
+	unflushed.Flush()
+	if err := td.MergeErr(unflushed); err != nil {
+		t.Errorf("unexpected error merging flushed digest: %v", err)
+	}
+	if _, err := td.QuantileErr(0.5); err != tdigest.ErrUnflushed {
+		t.Errorf("expected ErrUnflushed after merge, got %v", err)
+	}
+	td.Flush()
+	if q := td.Quantile(1); q != 10 {
+		t.Errorf("unexpected max after merge, got %g want 10", q)
+	}
+}
+
+func TestTdigest_QuantileErr(t *testing.T) {
+	q, err := NormalDigest.QuantileErr(0.9)
+	if err != nil || q != NormalDigest.Quantile(0.9) {
+		t.Errorf("unexpected quantile, got %g, %v", q, err)
+	}
+	p, err := NormalDigest.CDFErr(10)
+	if err != nil || p != NormalDigest.CDF(10) {
+		t.Errorf("unexpected CDF, got %g, %v", p, err)
+	}
+}
+
+func TestWithStrict_Readers(t *testing.T) {
+	td := tdigest.NewWithCompression(100, tdigest.WithStrict())
+	td.AddValues(1, 2, 3, 4, 5)
+	readers := []struct {
+		name string
+		read func() error
+	}{
+		{name: "WriteLineProtocol", read: func() error {
+			return td.WriteLineProtocol(&bytes.Buffer{}, "m", nil, []float64{0.5}, time.Time{})
+		}},
+		{name: "WriteOpenMetricsSummary", read: func() error {
+			return td.WriteOpenMetricsSummary(&bytes.Buffer{}, "m", nil, []float64{0.5})
+		}},
+		{name: "FormatText", read: func() error {
+			_, err := tdigest.FormatText(td)
+			return err
+		}},
+		{name: "Split", read: func() error {
+			_, _, err := td.Split(3)
+			return err
+		}},
+		{name: "Freeze", read: func() error {
+			_, err := td.Freeze()
+			return err
+		}},
+		{name: "Snapshot", read: func() error {
+			_, err := td.Snapshot()
+			return err
+		}},
+		{name: "HistogramTable", read: func() error {
+			_, err := tdigest.HistogramTable(td, 4)
+			return err
+		}},
+		{name: "QuantileAcross", read: func() error {
+			_, err := tdigest.QuantileAcross(0.5, NormalDigest, td)
+			return err
+		}},
+	}
+	for _, r := range readers {
+		if err := r.read(); err != tdigest.ErrUnflushed {
+			t.Errorf("%s: expected ErrUnflushed, got %v", r.name, err)
+		}
+	}
+	if s := tdigest.NewExpvar(td, 0.5).String(); !strings.Contains(s, `"error"`) {
+		t.Errorf("expected an error from the expvar, got %s", s)
+	}
+	if c := td.Count(); c != 5 {
+		t.Errorf("reads changed the count to %g", c)
+	}
+
+	td.Flush()
+	for _, r := range readers {
+		if err := r.read(); err != nil {
+			t.Errorf("%s: unexpected error after Flush: %v", r.name, err)
+		}
+	}
+}
+
+func TestWithStrict_Exporters(t *testing.T) {
+	td := tdigest.NewWithCompression(100, tdigest.WithStrict())
+	td.AddValues(1, 2, 3, 4, 5)
+	exporters := []struct {
+		name   string
+		export func() error
+	}{
+		{name: "MarshalBinary", export: func() error {
+			_, err := td.MarshalBinary()
+			return err
+		}},
+		{name: "AppendBinary", export: func() error {
+			_, err := td.AppendBinary(nil)
+			return err
+		}},
+		{name: "MarshalBinaryTo", export: func() error {
+			return td.MarshalBinaryTo(&bytes.Buffer{})
+		}},
+		{name: "MarshalBinaryCompressed", export: func() error {
+			_, err := td.MarshalBinaryCompressed(flateCodec{name: "flate"})
+			return err
+		}},
+		{name: "MarshalCBOR", export: func() error {
+			_, err := td.MarshalCBOR()
+			return err
+		}},
+		{name: "MarshalProto", export: func() error {
+			_, err := td.MarshalProto()
+			return err
+		}},
+		{name: "MarshalElasticsearch", export: func() error {
+			_, err := td.MarshalElasticsearch()
+			return err
+		}},
+		{name: "MarshalSparkPercentileDigest", export: func() error {
+			_, err := td.MarshalSparkPercentileDigest()
+			return err
+		}},
+		{name: "MarshalPostgres", export: func() error {
+			_, err := td.MarshalPostgres()
+			return err
+		}},
+		{name: "FormatPostgres", export: func() error {
+			_, err := tdigest.FormatPostgres(td)
+			return err
+		}},
+		{name: "Columns", export: func() error {
+			_, _, _, err := td.Columns()
+			return err
+		}},
+		{name: "RecordInto", export: func() error {
+			return td.RecordInto(recorder{})
+		}},
+		{name: "ToDDSketch", export: func() error {
+			_, err := td.ToDDSketch(0.01)
+			return err
+		}},
+		{name: "ToLogLinear", export: func() error {
+			_, err := td.ToLogLinear()
+			return err
+		}},
+		{name: "ToNativeHistogram", export: func() error {
+			_, err := td.ToNativeHistogram(0, 0)
+			return err
+		}},
+		{name: "Hash", export: func() error {
+			_, err := td.Hash()
+			return err
+		}},
+		{name: "Canonicalize", export: td.Canonicalize},
+		{name: "MapValues", export: func() error {
+			return td.MapValues(func(x float64) float64 { return x }, true)
+		}},
+	}
+	for _, e := range exporters {
+		if err := e.export(); err != tdigest.ErrUnflushed {
+			t.Errorf("%s: expected ErrUnflushed, got %v", e.name, err)
+		}
+	}
+	if n := len(td.Centroids(nil)); n != 0 {
+		t.Errorf("Centroids processed the pending centroids, got %d centroids", n)
+	}
+	if c := td.Count(); c != 5 {
+		t.Errorf("exports changed the count to %g", c)
+	}
+
+	td.Flush()
+	for _, e := range exporters {
+		if err := e.export(); err != nil {
+			t.Errorf("%s: unexpected error after Flush: %v", e.name, err)
+		}
+	}
+	if n := len(td.Centroids(nil)); n != 5 {
+		t.Errorf("unexpected number of centroids after Flush, got %d want 5", n)
+	}
+}
+
+func TestWithStrict_Aggregates(t *testing.T) {
+	unflushed := tdigest.NewWithCompression(100)
+	unflushed.AddValues(10, 20)
+
+	s := tdigest.NewSharded(100, 2, tdigest.WithStrict())
+	s.Add(1, 1)
+	s.Add(3, 1)
+	if q := s.Quantile(1); q != 3 {
+		t.Errorf("unexpected sharded maximum, got %g want 3", q)
+	}
+	if err := s.Merge(unflushed); err != tdigest.ErrUnflushed {
+		t.Errorf("expected ErrUnflushed from the sharded merge, got %v", err)
+	}
+
+	w := tdigest.NewWindowed(100, 2, tdigest.WithStrict())
+	w.AddSlice([]float64{1, 2, 3})
+	if q := w.Quantile(0.5); q != 2 {
+		t.Errorf("unexpected windowed median, got %g want 2", q)
+	}
+	if err := w.Merge(unflushed); err != tdigest.ErrUnflushed {
+		t.Errorf("expected ErrUnflushed from the windowed merge, got %v", err)
+	}
+
+	m := tdigest.NewLatencyMiddleware(100, nil, tdigest.WithStrict())
+	if err := m.Merge("", unflushed); err != tdigest.ErrUnflushed {
+		t.Errorf("expected ErrUnflushed from the middleware merge, got %v", err)
+	}
+
+	r := tdigest.NewRegistry(100, tdigest.WithStrict())
+	r.Observe(tdigest.Labels{"status": "200"}, 1)
+	if _, err := r.MergeBy(); err != tdigest.ErrUnflushed {
+		t.Errorf("expected ErrUnflushed from MergeBy, got %v", err)
+	}
+	r.Get(tdigest.Labels{"status": "200"}).Flush()
+	if _, err := r.MergeBy(); err != nil {
+		t.Errorf("unexpected error from MergeBy after Flush: %v", err)
+	}
+
+	a, b := tdigest.NewInt(100, 1, tdigest.WithStrict()), tdigest.NewInt(100, 1)
+	a.Add(1, 1)
+	a.Add(2, 1)
+	if q := a.Quantile(1); q != 2 {
+		t.Errorf("unexpected maximum after leaving exact mode, got %g want 2", q)
+	}
+	b.Add(1, 1)
+	b.Add(2, 1)
+	if err := a.Merge(b); err != nil {
+		t.Errorf("unexpected error merging a flushed digest: %v", err)
+	}
+
+	strict := tdigest.NewWithCompression(100, tdigest.WithStrict())
+	strict.AddValues(1, 2)
+	if _, err := tdigest.Delta(tdigest.New(), strict); err != tdigest.ErrUnflushed {
+		t.Errorf("expected ErrUnflushed from Delta, got %v", err)
+	}
+}
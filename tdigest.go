@@ -3,6 +3,7 @@ package tdigest
 import (
 	"math"
 	"sort"
+	"sync"
 )
 
 // TDigest is a data structure for accurate on-line accumulation of
@@ -12,6 +13,8 @@ type TDigest struct {
 
 	maxProcessed      int
 	maxUnprocessed    int
+	processedFactor   float64
+	unprocessedFactor float64
 	processed         CentroidList
 	unprocessed       CentroidList
 	cumulative        []float64
@@ -19,26 +22,82 @@ type TDigest struct {
 	unprocessedWeight float64
 	min               float64
 	max               float64
+
+	// unsorted is set once the unprocessed centroids are known to be out of
+	// order, in which case process sorts them before merging them with the
+	// processed centroids.
+	unsorted bool
+	strict   bool
+	tracing  bool
+	deferred bool
+	locker   sync.Locker
+	// shared is set while the processed centroids and cumulative weights
+	// are shared with a snapshot, see Snapshot.
+	shared bool
+
+	// exact is set while every sample is kept as its own centroid, which
+	// lasts until the total weight exceeds exactThreshold.
+	exact          bool
+	exactThreshold float64
+
+	nonFinite  NonFinitePolicy
+	onCompress func(CompressStats)
+	watches    []*quantileWatch
+	accepted   uint64
+	rejected   [numRejectReasons]uint64
+
+	// stats holds copies of the count and extremes for concurrent readers,
+	// see WithAtomicStats.
+	stats *atomicStats
+
+	// generation changes whenever the cumulative weights are recomputed,
+	// which invalidates the query cache.
+	generation uint64
+	cache      []cacheEntry
+	cacheNext  int
 }
 
-// New initializes a new distribution with a default compression.
-func New() *TDigest {
-	return NewWithCompression(1000)
+// New initializes a new distribution with a default compression of 1000,
+// configured by the given options, such as WithCompression.
+func New(opts ...Option) *TDigest {
+	return NewWithCompression(1000, opts...)
 }
 
 // NewWithCompression initializes a new distribution with custom compression.
-func NewWithCompression(c float64) *TDigest {
+// It is the same as New with WithCompression(c) as the first option.
+func NewWithCompression(c float64, opts ...Option) *TDigest {
+	return newDigest(c, 0, 0, opts)
+}
+
+// newDigest initializes a new distribution with the given compression and
+// buffer sizes; a size of zero derives it from the compression.
+func newDigest(c float64, processed, unprocessed int, opts []Option) *TDigest {
 	t := &TDigest{
-		Compression: c,
+		Compression:    c,
+		maxProcessed:   processed,
+		maxUnprocessed: unprocessed,
 	}
-	t.maxProcessed = processedSize(0, t.Compression)
-	t.maxUnprocessed = unprocessedSize(0, t.Compression)
-	t.processed = make(CentroidList, 0, t.maxProcessed)
-	t.unprocessed = make(CentroidList, 0, t.maxUnprocessed+1)
-	t.Reset()
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.maxProcessed = processedSize(t.maxProcessed, t.processedFactor, t.Compression)
+	t.maxUnprocessed = unprocessedSize(t.maxUnprocessed, t.unprocessedFactor, t.Compression)
+	t.allocate()
+	t.reset()
 	return t
 }
 
+// allocate replaces the centroid buffers with empty ones sized for the
+// current limits. Both buffers share a single allocation. The capacity of
+// each is capped, so that appending past it reallocates rather than
+// overwriting the other. The cumulative weights have a different element
+// type and are only allocated once the digest is queried.
+func (t *TDigest) allocate() {
+	arena := make(CentroidList, t.maxProcessed+t.maxUnprocessed+1)
+	t.processed = arena[:0:t.maxProcessed]
+	t.unprocessed = arena[t.maxProcessed:t.maxProcessed]
+}
+
 // Calculate number of bytes needed for a tdigest of size c,
 // where c is the compression value
 func ByteSizeForCompression(comp float64) int {
@@ -65,42 +124,352 @@ func ByteSizeForCompression(comp float64) int {
 
 // Reset resets the distribution to its initial state.
 func (t *TDigest) Reset() {
+	t.lock()
+	t.reset()
+	t.unlock()
+}
+
+func (t *TDigest) reset() {
+	t.unshare()
 	t.processed = t.processed[:0]
 	t.unprocessed = t.unprocessed[:0]
 	t.cumulative = t.cumulative[:0]
 	t.processedWeight = 0
 	t.unprocessedWeight = 0
+	t.unsorted = false
+	t.exact = t.exactThreshold > 0
+	t.accepted = 0
+	t.rejected = [numRejectReasons]uint64{}
 	t.min = math.MaxFloat64
 	t.max = -math.MaxFloat64
+	t.publishStats()
+}
+
+// ResetWithCompression resets the distribution to its initial state with a
+// new compression, keeping all other settings. The buffers are retained if
+// they are large enough for the new compression, which makes it cheap to
+// reuse pooled digests with different settings.
+func (t *TDigest) ResetWithCompression(c float64) {
+	t.lock()
+	t.setCompression(c)
+	t.reallocate()
+	t.reset()
+	t.unlock()
+}
+
+// ResetWithOptions resets the distribution to its initial state, keeping
+// its compression unless WithCompression is given, and replaces all settings
+// made by options with the given ones. Like ResetWithCompression, it retains
+// the buffers if they are large enough. Since that replaces the lock set by
+// WithLocker too, the digest must not be in use by other goroutines.
+func (t *TDigest) ResetWithOptions(opts ...Option) {
+	t.unshare()
+	*t = TDigest{
+		Compression: t.Compression,
+		processed:   t.processed,
+		unprocessed: t.unprocessed,
+		cumulative:  t.cumulative,
+		watches:     t.watches,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.maxProcessed = processedSize(t.maxProcessed, t.processedFactor, t.Compression)
+	t.maxUnprocessed = unprocessedSize(t.maxUnprocessed, t.unprocessedFactor, t.Compression)
+	t.reallocate()
+	t.reset()
+}
+
+// reallocate replaces the centroid buffers if they are too small for the
+// current limits.
+func (t *TDigest) reallocate() {
+	if cap(t.processed) < t.maxProcessed || cap(t.unprocessed) < t.maxUnprocessed+1 {
+		t.allocate()
+	}
 }
 
 // Add adds a value x with a weight w to the distribution.
 func (t *TDigest) Add(x, w float64) {
-	t.AddCentroid(Centroid{Mean: x, Weight: w})
+	t.lock()
+	t.addCentroid(Centroid{Mean: x, Weight: w})
+	t.unlock()
+}
+
+// Add1 adds a single value x with a weight of one to the distribution.
+func (t *TDigest) Add1(x float64) {
+	t.lock()
+	t.addCentroid(Centroid{Mean: x, Weight: 1})
+	t.unlock()
+}
+
+// AddValues adds each of the values xs with a weight of one to the
+// distribution.
+func (t *TDigest) AddValues(xs ...float64) {
+	t.lock()
+	for _, x := range xs {
+		t.addCentroid(Centroid{Mean: x, Weight: 1})
+	}
+	t.unlock()
+}
+
+// AddSlice adds each of the values xs with a weight of one, like AddValues,
+// but a chunk at a time. Each chunk, sized like the buffer of incoming
+// centroids, is copied and sorted, then merged with the existing centroids
+// in a single pass like AddSorted, compressing once per chunk. This avoids
+// the per-value bookkeeping of Add when loading large batches. A final
+// partial chunk is buffered like AddValues instead, as are all values if
+// the digest is exact or defers compaction. NaN values are ignored.
+func (t *TDigest) AddSlice(xs []float64) {
+	t.lock()
+	t.addSlice(xs)
+	t.unlock()
+}
+
+func (t *TDigest) addSlice(xs []float64) {
+	n := t.maxUnprocessed + 1
+	if len(xs) >= n && !t.exact && !t.deferred {
+		chunk := make([]float64, n)
+		for ; len(xs) >= n; xs = xs[n:] {
+			copy(chunk, xs)
+			sort.Float64s(chunk)
+			t.addSorted(chunk)
+		}
+	}
+	t.addBuffered(xs)
+}
+
+// addBuffered copies the values xs into the buffer of incoming centroids,
+// processing the digest whenever it is full.
+func (t *TDigest) addBuffered(xs []float64) {
+	for len(xs) > 0 {
+		n := t.maxUnprocessed + 1 - t.unprocessed.Len()
+		if n > len(xs) || t.deferred {
+			n = len(xs)
+		}
+		min, max, w := t.min, t.max, 0.0
+		for _, x := range xs[:n] {
+			if math.IsNaN(x) || math.IsInf(x, 0) {
+				var err error
+				if x, err = t.nonFiniteValue(x, min, max); err != nil {
+					continue
+				}
+			}
+			if l := t.unprocessed.Len(); l > 0 && x < t.unprocessed[l-1].Mean {
+				t.unsorted = true
+			}
+			t.unprocessed = append(t.unprocessed, Centroid{Mean: x, Weight: 1})
+			min = math.Min(min, x)
+			max = math.Max(max, x)
+			w++
+		}
+		t.unprocessedWeight += w
+		t.accepted += uint64(w)
+		t.min, t.max = min, max
+		t.publishStats()
+		xs = xs[n:]
+
+		if t.full() {
+			t.process()
+		}
+	}
+}
+
+// AddSorted adds each of the values xs, which must be sorted in ascending
+// order, with a weight of one. The values are merged with the existing
+// centroids in a single linear pass, without buffering or sorting them. If
+// xs turns out not to be sorted, or the digest is exact or defers
+// compaction, it is buffered like AddValues instead. NaN values are ignored.
+func (t *TDigest) AddSorted(xs []float64) {
+	t.lock()
+	t.addSorted(xs)
+	t.unlock()
+}
+
+func (t *TDigest) addSorted(xs []float64) {
+	if t.exact || t.deferred {
+		t.addBuffered(xs)
+		return
+	}
+	n, prev := 0, math.Inf(-1)
+	for _, x := range xs {
+		if t.nonFinite != NonFiniteKeepInf && (math.IsNaN(x) || math.IsInf(x, 0)) {
+			t.addBuffered(xs)
+			return
+		}
+		if math.IsNaN(x) {
+			continue
+		}
+		if x < prev {
+			t.addBuffered(xs)
+			return
+		}
+		prev = x
+		n++
+	}
+	t.rejected[RejectNaNValue] += uint64(len(xs) - n)
+	t.accepted += uint64(n)
+	if n == 0 {
+		return
+	}
+
+	// Use the unprocessed list to hold the existing centroids while they
+	// are merged with xs.
+	t.process()
+	t.unshare()
+	r := t.startRegion("tdigest.merge")
+	old := append(t.unprocessed, t.processed...)
+	t.processed.Clear()
+	t.processedWeight += float64(n)
+	soFar, limit := 0.0, 0.0
+	i := 0
+	for _, x := range xs {
+		if math.IsNaN(x) {
+			continue
+		}
+		for ; i < old.Len() && old[i].Mean <= x; i++ {
+			soFar, limit = t.compress(old[i], soFar, limit)
+		}
+		soFar, limit = t.compress(Centroid{Mean: x, Weight: 1}, soFar, limit)
+	}
+	for ; i < old.Len(); i++ {
+		soFar, limit = t.compress(old[i], soFar, limit)
+	}
+	t.unprocessed = old[:0]
+	t.min = math.Min(t.min, t.processed[0].Mean)
+	t.max = math.Max(t.max, t.processed[t.processed.Len()-1].Mean)
+	t.publishStats()
+	endRegion(r)
+	t.compressed(old.Len() + n)
 }
 
 // AddCentroidList can quickly add multiple centroids.
+// Lists sorted by mean, such as the output of Centroids, are merged with the
+// existing centroids without sorting them.
 func (t *TDigest) AddCentroidList(c CentroidList) {
+	t.lock()
+	t.addCentroidList(c)
+	t.unlock()
+}
+
+func (t *TDigest) addCentroidList(c CentroidList) {
 	// It's possible to optimize this by bulk-copying the slice, but this
 	// yields just a 1-2% speedup (most time is in process()), so not worth
 	// the complexity.
 	for i := range c {
-		t.AddCentroid(c[i])
+		t.addCentroid(c[i])
 	}
 }
 
 // AddCentroid adds a single centroid.
 // Weights which are not a number or are <= 0 are ignored, as are NaN means.
 func (t *TDigest) AddCentroid(c Centroid) {
-	if math.IsNaN(c.Mean) || c.Weight <= 0 || math.IsNaN(c.Weight) || math.IsInf(c.Weight, 1) {
+	t.lock()
+	t.addCentroid(c)
+	t.unlock()
+}
+
+func (t *TDigest) addCentroid(c Centroid) {
+	if t.check(&c) != nil {
 		return
 	}
+	t.add(c)
+}
+
+// AddErr adds a value x with a weight w to the distribution like Add, but
+// returns an error describing why the sample was rejected instead of
+// silently ignoring it.
+func (t *TDigest) AddErr(x, w float64) error {
+	t.lock()
+	defer t.unlock()
+	c := Centroid{Mean: x, Weight: w}
+	if err := t.check(&c); err != nil {
+		if err == errDropped {
+			return nil
+		}
+		return err
+	}
+	t.add(c)
+	return nil
+}
+
+// AddWeightedSlice adds each of the values with the weight at the same index
+// in weights, e.g. to load pre-aggregated data. It returns
+// ErrLengthMismatch, adding nothing, if the slices differ in length. Pairs
+// that AddCentroid would ignore are skipped, and reported by returning a
+// *RejectedError once all other pairs are added.
+func (t *TDigest) AddWeightedSlice(values, weights []float64) error {
+	if len(values) != len(weights) {
+		return ErrLengthMismatch
+	}
+	t.lock()
+	defer t.unlock()
+	rejected := 0
+	for i, x := range values {
+		c := Centroid{Mean: x, Weight: weights[i]}
+		if err := t.check(&c); err != nil {
+			if err != errDropped {
+				rejected++
+			}
+			continue
+		}
+		t.add(c)
+	}
+	if rejected > 0 {
+		return &RejectedError{Rejected: rejected, Total: len(values)}
+	}
+	return nil
+}
+
+// check returns why c can not be added to the digest, or nil if it can,
+// after applying the non-finite value policy to its mean.
+func (t *TDigest) check(c *Centroid) error {
+	if math.IsNaN(c.Mean) || math.IsInf(c.Mean, 0) {
+		var err error
+		if c.Mean, err = t.nonFiniteValue(c.Mean, t.min, t.max); err != nil {
+			return err
+		}
+	}
+	err := checkCentroid(*c)
+	switch err {
+	case nil:
+	case ErrNaNWeight:
+		t.rejected[RejectNaNWeight]++
+	case ErrNonPositiveWeight:
+		t.rejected[RejectNonPositiveWeight]++
+	case ErrInfiniteWeight:
+		t.rejected[RejectInfiniteWeight]++
+	}
+	return err
+}
 
+// checkCentroid returns why c can not be added to a digest, or nil if it can.
+func checkCentroid(c Centroid) error {
+	switch {
+	case math.IsNaN(c.Mean):
+		return ErrNaNValue
+	case math.IsNaN(c.Weight):
+		return ErrNaNWeight
+	case c.Weight <= 0:
+		return ErrNonPositiveWeight
+	case math.IsInf(c.Weight, 1):
+		return ErrInfiniteWeight
+	}
+	return nil
+}
+
+// add adds a valid centroid.
+func (t *TDigest) add(c Centroid) {
+	t.accepted++
+	if n := t.unprocessed.Len(); n > 0 && c.Mean < t.unprocessed[n-1].Mean {
+		t.unsorted = true
+	}
 	t.unprocessed = append(t.unprocessed, c)
 	t.unprocessedWeight += c.Weight
+	t.min = math.Min(t.min, c.Mean)
+	t.max = math.Max(t.max, c.Mean)
+	t.publishStats()
 
-	if t.processed.Len() > t.maxProcessed ||
-		t.unprocessed.Len() > t.maxUnprocessed {
+	if t.full() {
 		t.process()
 	}
 }
@@ -108,43 +477,302 @@ func (t *TDigest) AddCentroid(c Centroid) {
 // Merges the supplied digest into this digest. Functionally equivalent to
 // calling t.AddCentroidList(t2.Centroids(nil)), but avoids making an extra
 // copy of the CentroidList.
+//
+// The result always keeps the compression of t. Merging a digest with a
+// higher compression re-compresses its centroids, so the extra resolution of
+// t2 is lost; merging a digest with a lower compression can not recover
+// resolution t2 never had, so the accuracy of the merged data is bounded by
+// the smaller of the two compressions. Use MergeAdoptingCompression to keep
+// the larger compression instead.
+//
+// The exact minimum and maximum of t2 are carried over, rather than being
+// approximated by its outermost centroids. The total weight, reported by
+// Count, always reflects the merged weight.
+//
+// In strict mode, Merge panics whenever MergeErr would return an error,
+// rather than losing the data of t2; use MergeErr to handle those errors.
 func (t *TDigest) Merge(t2 *TDigest) {
+	if err := t.MergeErr(t2); err != nil {
+		panic(err)
+	}
+}
+
+// MergeErr merges the supplied digest into this digest like Merge. In strict
+// mode, it returns ErrConfigMismatch if the digests have different
+// compressions, and ErrUnflushed if t2 has not been flushed, leaving t
+// unchanged. It always returns nil otherwise.
+func (t *TDigest) MergeErr(t2 *TDigest) error {
+	t.lock()
+	defer t.unlock()
+	if t2 != t {
+		t2.lock()
+		defer t2.unlock()
+	}
+	if t.strict {
+		if t2.Compression != t.Compression {
+			return ErrConfigMismatch
+		}
+		if t2.pending() {
+			return ErrUnflushed
+		}
+	}
+	t.merge(t2)
+	return nil
+}
+
+func (t *TDigest) merge(t2 *TDigest) {
 	t2.process()
-	t.AddCentroidList(t2.processed)
+	if t2.processed.Len() == 0 {
+		return
+	}
+	t.exact = t.exact && t2.exact
+	// Merged centroids count as the samples they were built from.
+	accepted := t.accepted + t2.accepted
+	t.addCentroidList(t2.processed)
+	t.accepted = accepted
+	t.min = math.Min(t.min, t2.min)
+	t.max = math.Max(t.max, t2.max)
+	t.publishStats()
 }
 
-func (t *TDigest) process() {
-	if t.unprocessed.Len() > 0 ||
-		t.processed.Len() > t.maxProcessed {
+// MergeAdoptingCompression merges the supplied digest into this digest like
+// Merge, but first raises the compression of t to that of t2 if t2 has the
+// higher compression.
+func (t *TDigest) MergeAdoptingCompression(t2 *TDigest) {
+	t.lock()
+	defer t.unlock()
+	if t2 != t {
+		t2.lock()
+		defer t2.unlock()
+	}
+	if t2.Compression > t.Compression {
+		t.setCompression(t2.Compression)
+	}
+	t.merge(t2)
+}
 
-		// Append all processed centroids to the unprocessed list and sort
-		t.unprocessed = append(t.unprocessed, t.processed...)
-		sort.Sort(&t.unprocessed)
+// Downsize returns a new digest holding the data of t re-compressed to the
+// given compression, e.g. for archiving high resolution digests at a lower
+// resolution. The digest t is left unchanged, apart from processing any
+// pending centroids.
+func (t *TDigest) Downsize(newCompression float64) *TDigest {
+	d := NewWithCompression(newCompression)
+	d.Merge(t)
+	return d
+}
 
-		// Reset processed list with first centroid
-		t.processed.Clear()
-		t.processed = append(t.processed, t.unprocessed[0])
+// Scale multiplies the weight of every centroid, and hence Count, by the
+// given factor, which must be positive and finite. This can be used to
+// implement custom decay schemes, or to normalize a digest to a total weight
+// of one.
+func (t *TDigest) Scale(factor float64) error {
+	if !(factor > 0) || math.IsInf(factor, 1) {
+		return ErrInvalidScaleFactor
+	}
+	t.lock()
+	defer t.unlock()
+	t.unshare()
+	for i := range t.processed {
+		t.processed[i].Weight *= factor
+	}
+	for i := range t.unprocessed {
+		t.unprocessed[i].Weight *= factor
+	}
+	t.processedWeight *= factor
+	t.unprocessedWeight *= factor
+	t.cumulative = t.cumulative[:0]
+	t.publishStats()
+	return nil
+}
+
+// MapValues replaces the mean of every centroid, and the minimum and
+// maximum, by f applied to them, e.g. math.Log to turn a digest of values
+// into a digest of log-values.
+//
+// If monotonic is true, f must be monotonic over the data; a decreasing f
+// reverses the order of the centroids. ErrNotMonotonic is returned, and the
+// digest left unchanged, if the mapped centroids turn out to be unordered.
+// If monotonic is false, the mapped centroids are re-sorted, and the minimum
+// and maximum become approximate. ErrMappedNaN is returned, and the digest
+// left unchanged, if f yields NaN.
+func (t *TDigest) MapValues(f func(float64) float64, monotonic bool) error {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return err
+	}
+	t.unshare()
+	if t.processed.Len() == 0 {
+		return nil
+	}
+
+	// The unprocessed list is empty after processing, so use it as scratch
+	// space to leave the digest intact on error.
+	mapped := t.unprocessed[:0]
+	increasing, decreasing := true, true
+	for i, c := range t.processed {
+		c.Mean = f(c.Mean)
+		if math.IsNaN(c.Mean) {
+			return ErrMappedNaN
+		}
+		if i > 0 {
+			prev := mapped[i-1].Mean
+			increasing = increasing && prev <= c.Mean
+			decreasing = decreasing && prev >= c.Mean
+		}
+		mapped = append(mapped, c)
+	}
+	min, max := f(t.min), f(t.max)
+	if math.IsNaN(min) || math.IsNaN(max) {
+		return ErrMappedNaN
+	}
+
+	switch {
+	case increasing:
+	case decreasing:
+		for i, j := 0, mapped.Len()-1; i < j; i, j = i+1, j-1 {
+			mapped[i], mapped[j] = mapped[j], mapped[i]
+		}
+		min, max = max, min
+	case monotonic:
+		return ErrNotMonotonic
+	default:
+		sortCentroids(mapped)
+		min, max = math.Min(min, max), math.Max(min, max)
+	}
+
+	t.processed, t.unprocessed = mapped, t.processed[:0]
+	t.min = math.Min(min, t.processed[0].Mean)
+	t.max = math.Max(max, t.processed[t.processed.Len()-1].Mean)
+	t.cumulative = t.cumulative[:0]
+	t.publishStats()
+	return nil
+}
+
+// Split partitions the digest at x, returning a digest of the mass up to x
+// and a digest of the mass above it. Both keep the compression of t. The
+// weight assigned to each side follows CDF(x), so the centroids straddling
+// x are pro-rated between the two, with their means clamped to x. In strict
+// mode, Split returns ErrUnflushed if the digest has pending centroids.
+func (t *TDigest) Split(x float64) (below, above *TDigest, err error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return nil, nil, err
+	}
+	below = NewWithCompression(t.Compression)
+	above = NewWithCompression(t.Compression)
+	if t.processed.Len() == 0 {
+		return below, above, nil
+	}
 
+	limit := t.summary().cdf(x) * t.processedWeight
+	soFar := 0.0
+	for _, c := range t.processed {
+		switch {
+		case soFar+c.Weight <= limit:
+			below.AddCentroid(c)
+		case soFar >= limit:
+			above.AddCentroid(c)
+		default:
+			w := limit - soFar
+			below.AddCentroid(Centroid{Mean: math.Min(c.Mean, x), Weight: w})
+			above.AddCentroid(Centroid{Mean: math.Max(c.Mean, x), Weight: c.Weight - w})
+		}
+		soFar += c.Weight
+	}
+	below.min = math.Min(below.min, t.min)
+	above.max = math.Max(above.max, t.max)
+	return below, above, nil
+}
+
+// setCompression changes the compression, and the buffer limits derived from
+// it. Existing centroids are left as they are until the next process.
+func (t *TDigest) setCompression(c float64) {
+	t.Compression = c
+	t.maxProcessed = processedSize(0, t.processedFactor, c)
+	t.maxUnprocessed = unprocessedSize(0, t.unprocessedFactor, c)
+}
+
+func (t *TDigest) process() {
+	if t.unprocessed.Len() > 0 || t.overfull() {
+		t.unshare()
+		r := t.startRegion("tdigest.sort")
+		// The processed centroids are already sorted, so only the
+		// unprocessed ones need sorting before the two are merged.
+		if t.unsorted {
+			sortCentroids(t.unprocessed)
+		}
+		t.mergeProcessed()
+		endRegion(r)
+		if t.keepExact() {
+			return
+		}
+		r = t.startRegion("tdigest.merge")
+
+		before := t.unprocessed.Len()
+		t.processed.Clear()
 		t.processedWeight += t.unprocessedWeight
 		t.unprocessedWeight = 0
-		soFar := t.unprocessed[0].Weight
-		limit := t.processedWeight * t.integratedQ(1.0)
-		for _, centroid := range t.unprocessed[1:] {
-			projected := soFar + centroid.Weight
-			if projected <= limit {
-				soFar = projected
-				(&t.processed[t.processed.Len()-1]).Add(centroid)
-			} else {
-				k1 := t.integratedLocation(soFar / t.processedWeight)
-				limit = t.processedWeight * t.integratedQ(k1+1.0)
-				soFar += centroid.Weight
-				t.processed = append(t.processed, centroid)
-			}
+		soFar, limit := 0.0, 0.0
+		for _, centroid := range t.unprocessed {
+			soFar, limit = t.compress(centroid, soFar, limit)
 		}
 		t.min = math.Min(t.min, t.processed[0].Mean)
 		t.max = math.Max(t.max, t.processed[t.processed.Len()-1].Mean)
 		t.unprocessed.Clear()
+		t.unsorted = false
+		endRegion(r)
+		t.compressed(before)
+	}
+}
+
+// mergeProcessed merges the processed centroids into the unprocessed list,
+// which must be sorted. The lists are merged from the back, so that no
+// centroid is overwritten before it is read and no scratch space is needed.
+func (t *TDigest) mergeProcessed() {
+	m, n := t.unprocessed.Len(), t.processed.Len()
+	u := t.unprocessed
+	if m+n > cap(u) {
+		u = make(CentroidList, m, m+n+t.maxUnprocessed/4)
+		copy(u, t.unprocessed)
+	}
+	u = u[:m+n]
+	p := t.processed
+	i, j := m-1, n-1
+	for k := m + n - 1; j >= 0; k-- {
+		if i >= 0 && u[i].Mean > p[j].Mean {
+			u[k] = u[i]
+			i--
+		} else {
+			u[k] = p[j]
+			j--
+		}
 	}
+	t.unprocessed = u
+}
+
+// compress appends the centroid c, which must not be less than any processed
+// centroid, to the processed list, merging it into the last processed
+// centroid if the size limit allows. soFar and limit carry the state between
+// calls and start out as zero; processedWeight must already include the
+// weight of all centroids to be compressed.
+func (t *TDigest) compress(c Centroid, soFar, limit float64) (float64, float64) {
+	t.processed, soFar, limit = t.compressInto(t.processed, t.processedWeight, c, soFar, limit)
+	return soFar, limit
+}
+
+// compressInto is like compress, but appends to the list cl, which holds
+// centroids of the given total weight once complete, and returns it.
+func (t *TDigest) compressInto(cl CentroidList, total float64, c Centroid, soFar, limit float64) (CentroidList, float64, float64) {
+	projected := soFar + c.Weight
+	if projected <= limit {
+		(&cl[cl.Len()-1]).Add(c)
+		return cl, projected, limit
+	}
+	k1 := t.integratedLocation(soFar / total)
+	limit = total * t.integratedQ(k1+1.0)
+	return append(cl, c), projected, limit
 }
 
 // Centroids returns a copy of processed centroids.
@@ -152,12 +780,41 @@ func (t *TDigest) process() {
 //
 // Centroids are appended to the passed CentroidList; if you're re-using a
 // buffer, be sure to pass cl[:0].
+//
+// In strict mode, pending centroids are not processed, and so are left out
+// until Flush is called.
 func (t *TDigest) Centroids(cl CentroidList) CentroidList {
-	t.process()
+	t.lock()
+	defer t.unlock()
+	if !t.strict {
+		t.process()
+	}
 	return append(cl, t.processed...)
 }
 
+// ForEachCentroid calls f for each processed centroid in ascending order of
+// mean, until f returns false. Unlike Centroids, it does not copy the
+// centroids. The digest must not be modified from within f. Like Centroids,
+// it leaves out pending centroids in strict mode.
+func (t *TDigest) ForEachCentroid(f func(Centroid) bool) {
+	t.lock()
+	defer t.unlock()
+	if !t.strict {
+		t.process()
+	}
+	for _, c := range t.processed {
+		if !f(c) {
+			return
+		}
+	}
+}
+
 func (t *TDigest) Count() float64 {
+	t.lock()
+	defer t.unlock()
+	if t.strict {
+		return t.processedWeight + t.unprocessedWeight
+	}
 	t.process()
 
 	// t.process always updates t.processedWeight to the total count of all
@@ -173,105 +830,228 @@ func (t *TDigest) updateCumulative() {
 		return
 	}
 
+	t.unshare()
 	if n := t.processed.Len() + 1; n <= cap(t.cumulative) {
 		t.cumulative = t.cumulative[:n]
 	} else {
 		t.cumulative = make([]float64, n)
 	}
+	r := t.startRegion("tdigest.cumulative")
+	cumulativeWeights(t.cumulative, t.processed)
+	endRegion(r)
+	t.generation++
+}
 
+// cumulativeWeights fills cum, which must have room for len(cl)+1 values,
+// with the cumulative weight up to the midpoint of each centroid followed by
+// the total weight, and returns it.
+func cumulativeWeights(cum []float64, cl CentroidList) []float64 {
+	// Reslicing up front lets the compiler drop the bounds checks in the
+	// loop below.
+	cum = cum[:len(cl)+1]
+	mid := cum[:len(cl)]
 	prev := 0.0
-	for i, centroid := range t.processed {
+	for i, centroid := range cl {
 		cur := centroid.Weight
-		t.cumulative[i] = prev + cur/2.0
+		mid[i] = prev + cur/2.0
 		prev = prev + cur
 	}
-	t.cumulative[t.processed.Len()] = prev
+	cum[len(cl)] = prev
+	return cum
 }
 
 // Quantile returns the (approximate) quantile of
 // the distribution. Accepted values for q are between 0.0 and 1.0.
 // Returns NaN if Count is zero or bad inputs.
+//
+// In strict mode, Quantile returns NaN if the digest has not been flushed;
+// use QuantileErr to tell this apart from an empty digest.
 func (t *TDigest) Quantile(q float64) float64 {
+	x, _ := t.QuantileErr(q)
+	return x
+}
+
+// CDF returns the cumulative distribution function for a given value x.
+//
+// In strict mode, CDF returns NaN if the digest has not been flushed; use
+// CDFErr to tell this apart from other results.
+func (t *TDigest) CDF(x float64) float64 {
+	p, _ := t.CDFErr(x)
+	return p
+}
+
+// Flush processes all pending centroids, so that subsequent queries do not
+// need to. It is required before querying a digest in strict mode.
+func (t *TDigest) Flush() {
+	t.lock()
 	t.process()
 	t.updateCumulative()
-	if q < 0 || q > 1 || t.processed.Len() == 0 {
+	t.unlock()
+}
+
+// pending reports whether the next process will change the centroids.
+func (t *TDigest) pending() bool {
+	return t.unprocessed.Len() > 0 || t.overfull()
+}
+
+// full reports whether the digest should be processed after adding
+// centroids.
+func (t *TDigest) full() bool {
+	return !t.deferred && (t.overfull() || t.unprocessed.Len() > t.maxUnprocessed)
+}
+
+// overfull reports whether the processed centroids need to be compressed.
+func (t *TDigest) overfull() bool {
+	return !t.exact && t.processed.Len() > t.maxProcessed
+}
+
+// QuantileAcross returns the (approximate) quantile of the union of the
+// supplied digests. The centroids of all digests are combined into a single
+// sorted view to answer the query, which is considerably cheaper than merging
+// them into a new digest when only a few quantiles are needed.
+// Nil digests are ignored. Returns NaN if the combined Count is zero or bad
+// inputs. It returns ErrUnflushed if any digest in strict mode has pending
+// centroids.
+func QuantileAcross(q float64, ds ...*TDigest) (float64, error) {
+	s := summary{
+		min:   math.MaxFloat64,
+		max:   -math.MaxFloat64,
+		exact: true,
+	}
+	for _, d := range ds {
+		if d == nil {
+			continue
+		}
+		d.lock()
+		err := d.prepareRead()
+		if err == nil && d.processed.Len() > 0 {
+			s.centroids = append(s.centroids, d.processed...)
+			s.weight += d.processedWeight
+			s.min = math.Min(s.min, d.min)
+			s.max = math.Max(s.max, d.max)
+			s.exact = s.exact && d.exact
+		}
+		d.unlock()
+		if err != nil {
+			return math.NaN(), err
+		}
+	}
+	sortCentroids(s.centroids)
+	s.cumulative = cumulativeWeights(make([]float64, s.centroids.Len()+1), s.centroids)
+	return s.quantile(q), nil
+}
+
+// summary is a read-only view of a sorted list of centroids, along with the
+// cumulative weights and extremes needed to answer rank queries.
+type summary struct {
+	centroids  CentroidList
+	cumulative []float64
+	weight     float64
+	min        float64
+	max        float64
+	exact      bool
+}
+
+// summary returns a view over the processed centroids. Callers must ensure
+// the digest has been processed and the cumulative weights are up to date.
+func (t *TDigest) summary() summary {
+	return summary{
+		centroids:  t.processed,
+		cumulative: t.cumulative,
+		weight:     t.processedWeight,
+		min:        t.min,
+		max:        t.max,
+		exact:      t.exact,
+	}
+}
+
+func (s summary) quantile(q float64) float64 {
+	if q < 0 || q > 1 || s.centroids.Len() == 0 {
 		return math.NaN()
 	}
-	if t.processed.Len() == 1 {
-		return t.processed[0].Mean
+	if s.centroids.Len() == 1 {
+		return s.centroids[0].Mean
+	}
+	if s.exact {
+		return s.exactQuantile(q)
 	}
-	index := q * t.processedWeight
-	if index <= t.processed[0].Weight/2.0 {
-		return t.min + 2.0*index/t.processed[0].Weight*(t.processed[0].Mean-t.min)
+	index := q * s.weight
+	if index <= s.centroids[0].Weight/2.0 {
+		return s.min + 2.0*index/s.centroids[0].Weight*(s.centroids[0].Mean-s.min)
 	}
 
-	lower := sort.Search(len(t.cumulative), func(i int) bool {
-		return t.cumulative[i] >= index
+	lower := sort.Search(len(s.cumulative), func(i int) bool {
+		return s.cumulative[i] >= index
 	})
+	// The total of fractional weights may round to slightly more than the
+	// last cumulative weight.
+	if lower == len(s.cumulative) {
+		lower--
+	}
 
-	if lower+1 != len(t.cumulative) {
-		z1 := index - t.cumulative[lower-1]
-		z2 := t.cumulative[lower] - index
-		return weightedAverage(t.processed[lower-1].Mean, z2, t.processed[lower].Mean, z1)
+	if lower+1 != len(s.cumulative) {
+		z1 := index - s.cumulative[lower-1]
+		z2 := s.cumulative[lower] - index
+		return weightedAverage(s.centroids[lower-1].Mean, z2, s.centroids[lower].Mean, z1)
 	}
 
-	z1 := index - t.processedWeight - t.processed[lower-1].Weight/2.0
-	z2 := (t.processed[lower-1].Weight / 2.0) - z1
-	return weightedAverage(t.processed[t.processed.Len()-1].Mean, z1, t.max, z2)
+	z1 := index - s.weight - s.centroids[lower-1].Weight/2.0
+	z2 := (s.centroids[lower-1].Weight / 2.0) - z1
+	return weightedAverage(s.centroids[s.centroids.Len()-1].Mean, z1, s.max, z2)
 }
 
-// CDF returns the cumulative distribution function for a given value x.
-func (t *TDigest) CDF(x float64) float64 {
-	t.process()
-	t.updateCumulative()
-	switch t.processed.Len() {
+func (s summary) cdf(x float64) float64 {
+	if s.exact {
+		return s.exactCDF(x)
+	}
+	switch s.centroids.Len() {
 	case 0:
 		return 0.0
 	case 1:
-		width := t.max - t.min
-		if x <= t.min {
+		width := s.max - s.min
+		if x <= s.min {
 			return 0.0
 		}
-		if x >= t.max {
+		if x >= s.max {
 			return 1.0
 		}
-		if (x - t.min) <= width {
+		if (x - s.min) <= width {
 			// min and max are too close together to do any viable interpolation
 			return 0.5
 		}
-		return (x - t.min) / width
+		return (x - s.min) / width
 	}
 
-	if x <= t.min {
+	if x <= s.min {
 		return 0.0
 	}
-	if x >= t.max {
+	if x >= s.max {
 		return 1.0
 	}
-	m0 := t.processed[0].Mean
+	m0 := s.centroids[0].Mean
 	// Left Tail
 	if x <= m0 {
-		if m0-t.min > 0 {
-			return (x - t.min) / (m0 - t.min) * t.processed[0].Weight / t.processedWeight / 2.0
+		if m0-s.min > 0 {
+			return (x - s.min) / (m0 - s.min) * s.centroids[0].Weight / s.weight / 2.0
 		}
 		return 0.0
 	}
 	// Right Tail
-	mn := t.processed[t.processed.Len()-1].Mean
+	mn := s.centroids[s.centroids.Len()-1].Mean
 	if x >= mn {
-		if t.max-mn > 0.0 {
-			return 1.0 - (t.max-x)/(t.max-mn)*t.processed[t.processed.Len()-1].Weight/t.processedWeight/2.0
+		if s.max-mn > 0.0 {
+			return 1.0 - (s.max-x)/(s.max-mn)*s.centroids[s.centroids.Len()-1].Weight/s.weight/2.0
 		}
 		return 1.0
 	}
 
-	upper := sort.Search(t.processed.Len(), func(i int) bool {
-		return t.processed[i].Mean > x
+	upper := sort.Search(s.centroids.Len(), func(i int) bool {
+		return s.centroids[i].Mean > x
 	})
 
-	z1 := x - t.processed[upper-1].Mean
-	z2 := t.processed[upper].Mean - x
-	return weightedAverage(t.cumulative[upper-1], z2, t.cumulative[upper], z1) / t.processedWeight
+	z1 := x - s.centroids[upper-1].Mean
+	z2 := s.centroids[upper].Mean - x
+	return weightedAverage(s.cumulative[upper-1], z2, s.cumulative[upper], z1) / s.weight
 }
 
 func (t *TDigest) integratedQ(k float64) float64 {
@@ -294,16 +1074,29 @@ func weightedAverageSorted(x1, w1, x2, w2 float64) float64 {
 	return math.Max(x1, math.Min(x, x2))
 }
 
-func processedSize(size int, compression float64) int {
+// Default multipliers of the compression for the sizes of the processed and
+// unprocessed buffers, see WithBufferFactors.
+const (
+	defaultProcessedFactor   = 2
+	defaultUnprocessedFactor = 8
+)
+
+func processedSize(size int, factor, compression float64) int {
 	if size == 0 {
-		return int(2 * math.Ceil(compression))
+		if factor == 0 {
+			factor = defaultProcessedFactor
+		}
+		return int(math.Ceil(factor * math.Ceil(compression)))
 	}
 	return size
 }
 
-func unprocessedSize(size int, compression float64) int {
+func unprocessedSize(size int, factor, compression float64) int {
 	if size == 0 {
-		return int(8 * math.Ceil(compression))
+		if factor == 0 {
+			factor = defaultUnprocessedFactor
+		}
+		return int(math.Ceil(factor * math.Ceil(compression)))
 	}
 	return size
 }
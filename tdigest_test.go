@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/influxdata/tdigest"
@@ -84,6 +85,33 @@ func TestTdigest_AddFuncs(t *testing.T) {
 	if err := compareQuantiles(addDigest, addCentroidListDigest, 0.01); err != nil {
 		t.Errorf("AddCentroidList() differs from from Add(): %s", err.Error())
 	}
+
+	data := UniformData[:10000]
+	addDigest = tdigest.NewWithCompression(100)
+	add1Digest := tdigest.NewWithCompression(100)
+	addValuesDigest := tdigest.NewWithCompression(100)
+
+	for _, x := range data {
+		addDigest.Add(x, 1)
+		add1Digest.Add1(x)
+	}
+	addValuesDigest.AddValues(data...)
+
+	if !reflect.DeepEqual(addDigest.Centroids(nil), add1Digest.Centroids(nil)) {
+		t.Error("Add1() differs from Add()")
+	}
+	if !reflect.DeepEqual(addDigest.Centroids(nil), addValuesDigest.Centroids(nil)) {
+		t.Error("AddValues() differs from Add()")
+	}
+
+	addSliceDigest := tdigest.NewWithCompression(100)
+	addSliceDigest.AddSlice(append(append([]float64(nil), data...), math.NaN()))
+	if !reflect.DeepEqual(addDigest.Centroids(nil), addSliceDigest.Centroids(nil)) {
+		t.Error("AddSlice() differs from Add()")
+	}
+	if got, want := addSliceDigest.Quantile(0), addDigest.Quantile(0); got != want {
+		t.Errorf("AddSlice() has unexpected min, got %g want %g", got, want)
+	}
 }
 
 func TestTdigest_Count(t *testing.T) {
@@ -350,6 +378,37 @@ func TestTdigest_OddInputs(t *testing.T) {
 	}
 }
 
+func TestTdigest_AddErr(t *testing.T) {
+	tests := []struct {
+		name string
+		x, w float64
+		want error
+	}{
+		{name: "valid", x: 1, w: 1},
+		{name: "infinite value", x: math.Inf(1), w: 1},
+		{name: "nan value", x: math.NaN(), w: 1, want: tdigest.ErrNaNValue},
+		{name: "nan weight", x: 1, w: math.NaN(), want: tdigest.ErrNaNWeight},
+		{name: "zero weight", x: 1, w: 0, want: tdigest.ErrNonPositiveWeight},
+		{name: "negative weight", x: 1, w: -1000, want: tdigest.ErrNonPositiveWeight},
+		{name: "infinite weight", x: 1, w: math.Inf(1), want: tdigest.ErrInfiniteWeight},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := tdigest.New()
+			if err := td.AddErr(tt.x, tt.w); err != tt.want {
+				t.Errorf("unexpected error, got %v want %v", err, tt.want)
+			}
+			want := 1.0
+			if tt.want != nil {
+				want = 0
+			}
+			if got := td.Count(); got != want {
+				t.Errorf("unexpected count, got %g want %g", got, want)
+			}
+		})
+	}
+}
+
 func TestTdigest_Merge(t *testing.T) {
 	// Repeat merges enough times to ensure we call compress()
 	numRepeats := 20
@@ -393,6 +452,25 @@ func BenchmarkTDigest_Add(b *testing.B) {
 	}
 }
 
+func BenchmarkTDigest_AddSlice(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		td := tdigest.NewWithCompression(1000)
+		td.AddSlice(NormalData)
+	}
+}
+
+func BenchmarkTDigest_AddSorted(b *testing.B) {
+	data := append([]float64(nil), NormalData...)
+	sort.Float64s(data)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		td := tdigest.NewWithCompression(1000)
+		for i := 0; i < len(data); i += 10000 {
+			td.AddSorted(data[i : i+10000])
+		}
+	}
+}
+
 func BenchmarkTDigest_AddCentroid(b *testing.B) {
 	centroids := make(tdigest.CentroidList, len(NormalData))
 	for i := range centroids {
@@ -507,3 +585,525 @@ func TestTdigest_Centroids(t *testing.T) {
 		})
 	}
 }
+
+func TestQuantileAcross(t *testing.T) {
+	// A single digest answers exactly like the digest itself.
+	for _, q := range quantiles {
+		got, err := tdigest.QuantileAcross(q, NormalDigest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := NormalDigest.Quantile(q); got != want {
+			t.Errorf("unexpected quantile %g for single digest, got %g want %g", q, got, want)
+		}
+	}
+
+	shards := make([]*tdigest.TDigest, 8)
+	for i := range shards {
+		shards[i] = tdigest.NewWithCompression(1000)
+	}
+	merged := tdigest.NewWithCompression(1000)
+	for i, x := range NormalData {
+		shards[i%len(shards)].Add(x, 1)
+		merged.Add(x, 1)
+	}
+	for _, q := range quantiles {
+		got, _ := tdigest.QuantileAcross(q, shards...)
+		want := merged.Quantile(q)
+		if math.Abs(got-want)/want > 0.001 {
+			t.Errorf("unexpected quantile %g across shards, got %g want %g", q, got, want)
+		}
+	}
+
+	if q, _ := tdigest.QuantileAcross(0.5, nil, tdigest.New()); !math.IsNaN(q) {
+		t.Errorf("expected NaN for empty digests, got %g", q)
+	}
+}
+
+func TestTdigest_MergeDifferentCompression(t *testing.T) {
+	coarse := tdigest.NewWithCompression(100)
+	fine := tdigest.NewWithCompression(1000)
+	for _, x := range NormalData {
+		coarse.Add(x, 1)
+		fine.Add(x, 1)
+	}
+
+	tests := []struct {
+		name  string
+		dst   *tdigest.TDigest
+		src   *tdigest.TDigest
+		adopt bool
+		want  float64
+	}{
+		{
+			name: "coarse into fine",
+			dst:  tdigest.NewWithCompression(1000),
+			src:  coarse,
+			want: 1000,
+		},
+		{
+			name: "fine into coarse",
+			dst:  tdigest.NewWithCompression(100),
+			src:  fine,
+			want: 100,
+		},
+		{
+			name:  "fine into coarse adopting compression",
+			dst:   tdigest.NewWithCompression(100),
+			src:   fine,
+			adopt: true,
+			want:  1000,
+		},
+		{
+			name:  "coarse into fine adopting compression",
+			dst:   tdigest.NewWithCompression(1000),
+			src:   coarse,
+			adopt: true,
+			want:  1000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.adopt {
+				tt.dst.MergeAdoptingCompression(tt.src)
+			} else {
+				tt.dst.Merge(tt.src)
+			}
+			if tt.dst.Compression != tt.want {
+				t.Errorf("unexpected compression, got %g want %g", tt.dst.Compression, tt.want)
+			}
+			if err := compareQuantiles(tt.dst, tt.src, 0.01); err != nil {
+				t.Errorf("merged digest differs from source: %s", err.Error())
+			}
+			if n, limit := len(tt.dst.Centroids(nil)), int(2*tt.want); n > limit {
+				t.Errorf("too many centroids for compression %g, got %d want <= %d", tt.want, n, limit)
+			}
+		})
+	}
+}
+
+func TestTdigest_MergeExtremes(t *testing.T) {
+	src := tdigest.NewWithCompression(5)
+	for i := 1; i <= 1000; i++ {
+		src.Add(float64(i), 1)
+	}
+	cl := src.Centroids(nil)
+	if cl[0].Mean == 1 || cl[len(cl)-1].Mean == 1000 {
+		t.Fatal("expected the outermost centroids to be merged")
+	}
+
+	dst := tdigest.New()
+	dst.Merge(src)
+	if got := dst.Count(); got != 1000 {
+		t.Errorf("unexpected count, got %g want 1000", got)
+	}
+	if got := dst.Quantile(0); got != 1 {
+		t.Errorf("unexpected min, got %g want 1", got)
+	}
+	if got := dst.CDF(999.99); got == 1 {
+		t.Error("expected max to be carried over from the source")
+	}
+	if got := dst.CDF(1000); got != 1 {
+		t.Errorf("unexpected CDF at max, got %g want 1", got)
+	}
+}
+
+func TestTdigest_Downsize(t *testing.T) {
+	small := NormalDigest.Downsize(100)
+	if small.Compression != 100 {
+		t.Errorf("unexpected compression, got %g want 100", small.Compression)
+	}
+	if got, want := len(small.Centroids(nil)), len(NormalDigest.Centroids(nil))/5; got > want {
+		t.Errorf("expected fewer centroids, got %d want <= %d", got, want)
+	}
+	if err := compareQuantiles(small, NormalDigest, 0.01); err != nil {
+		t.Errorf("downsized digest differs: %s", err.Error())
+	}
+	if got, want := small.Quantile(0), NormalDigest.Quantile(0); got != want {
+		t.Errorf("unexpected min, got %g want %g", got, want)
+	}
+}
+
+func TestTdigest_Split(t *testing.T) {
+	tests := []struct {
+		name string
+		x    float64
+	}{
+		{name: "below min", x: -100},
+		{name: "low", x: 4},
+		{name: "mean", x: 10},
+		{name: "high", x: 16},
+		{name: "above max", x: 100},
+	}
+	total := NormalDigest.Count()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			below, above, err := NormalDigest.Split(tt.x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := below.Count() + above.Count(); math.Abs(got-total) > 1e-6 {
+				t.Errorf("unexpected total count, got %g want %g", got, total)
+			}
+			if got, want := below.Count()/total, NormalDigest.CDF(tt.x); math.Abs(got-want) > 1e-9 {
+				t.Errorf("unexpected fraction below, got %g want %g", got, want)
+			}
+			if below.Count() > 0 {
+				if got := below.Quantile(1); got > tt.x {
+					t.Errorf("below digest exceeds split point, got %g", got)
+				}
+				if got, want := below.Quantile(0), NormalDigest.Quantile(0); got != want {
+					t.Errorf("unexpected min of below digest, got %g want %g", got, want)
+				}
+			}
+			if above.Count() > 0 {
+				if got := above.Quantile(0); got < tt.x {
+					t.Errorf("above digest is less than split point, got %g", got)
+				}
+				if got, want := above.Quantile(1), NormalDigest.Quantile(1); got != want {
+					t.Errorf("unexpected max of above digest, got %g want %g", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTdigest_Scale(t *testing.T) {
+	td := tdigest.New()
+	for _, x := range UniformData[:10000] {
+		td.Add(x, 1)
+	}
+	// Leave some centroids unprocessed.
+	td.Add(50, 1)
+	td.Add(60, 1)
+	want := tdigest.New()
+	want.Merge(td)
+
+	if err := td.Scale(1 / td.Count()); err != nil {
+		t.Fatal(err)
+	}
+	if got := td.Count(); math.Abs(got-1) > 1e-9 {
+		t.Errorf("unexpected count, got %g want 1", got)
+	}
+	for _, q := range quantiles {
+		if got, want := td.Quantile(q), want.Quantile(q); math.Abs(got-want)/want > 1e-9 {
+			t.Errorf("quantile %g changed by scaling, got %g want %g", q, got, want)
+		}
+	}
+
+	// The cumulative weights of fractional centroids may round to less than
+	// the total weight.
+	frac := tdigest.NewWithCompression(100)
+	for i := 0; i < 1000; i++ {
+		frac.Add(float64(i), 0.1+float64(i%7)*0.37)
+	}
+	frac.Scale(0.01)
+	if got := frac.Quantile(1); got != 999 {
+		t.Errorf("unexpected maximum after scaling, got %g want 999", got)
+	}
+
+	for _, factor := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if err := td.Scale(factor); err != tdigest.ErrInvalidScaleFactor {
+			t.Errorf("expected error for factor %g, got %v", factor, err)
+		}
+	}
+}
+
+func TestTdigest_MapValues(t *testing.T) {
+	square := func(x float64) float64 { return x * x }
+	negate := func(x float64) float64 { return -x }
+
+	tests := []struct {
+		name      string
+		data      []float64
+		f         func(float64) float64
+		monotonic bool
+		wantErr   error
+		want      []float64
+	}{
+		{
+			name:      "increasing",
+			data:      []float64{1, 2, 4, 8, 16},
+			f:         math.Log2,
+			monotonic: true,
+			want:      []float64{0, 2, 4},
+		},
+		{
+			name:      "decreasing",
+			data:      []float64{1, 2, 3, 4, 5},
+			f:         negate,
+			monotonic: true,
+			want:      []float64{-5, -3, -1},
+		},
+		{
+			name:      "not monotonic",
+			data:      []float64{-2, -1, 0, 1, 2},
+			f:         square,
+			monotonic: true,
+			wantErr:   tdigest.ErrNotMonotonic,
+		},
+		{
+			name: "unordered",
+			data: []float64{-3, -1, 0, 2, 4},
+			f:    square,
+			want: []float64{0, 4, 16},
+		},
+		{
+			name:      "nan",
+			data:      []float64{-1, 0, 1},
+			f:         math.Log,
+			monotonic: true,
+			wantErr:   tdigest.ErrMappedNaN,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := tdigest.NewWithCompression(1000)
+			for _, x := range tt.data {
+				td.Add(x, 1)
+			}
+			before := td.Centroids(nil)
+
+			err := td.MapValues(tt.f, tt.monotonic)
+			if err != tt.wantErr {
+				t.Fatalf("unexpected error, got %v want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !reflect.DeepEqual(td.Centroids(nil), before) {
+					t.Error("digest changed despite error")
+				}
+				return
+			}
+			got := []float64{td.Quantile(0), td.Quantile(0.5), td.Quantile(1)}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected min, median, max, got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTdigest_AddSorted(t *testing.T) {
+	sorted := append([]float64(nil), NormalData...)
+	sort.Float64s(sorted)
+
+	td := tdigest.NewWithCompression(1000)
+	td.AddSorted(sorted[:len(sorted)/2])
+	td.AddSorted(sorted[len(sorted)/2:])
+	if err := compareQuantiles(td, NormalDigest, 0.001); err != nil {
+		t.Errorf("AddSorted() differs from Add(): %s", err.Error())
+	}
+	if got, want := td.Quantile(0), sorted[0]; got != want {
+		t.Errorf("unexpected min, got %g want %g", got, want)
+	}
+	if got, want := td.Quantile(1), sorted[len(sorted)-1]; got != want {
+		t.Errorf("unexpected max, got %g want %g", got, want)
+	}
+
+	// Interleaving with previously added data.
+	td = tdigest.NewWithCompression(1000)
+	td.AddSlice(UniformData[:len(UniformData)/2])
+	half := append([]float64(nil), UniformData[len(UniformData)/2:]...)
+	sort.Float64s(half)
+	td.AddSorted(half)
+	if err := compareQuantiles(td, UniformDigest, 0.001); err != nil {
+		t.Errorf("AddSorted() after AddSlice() differs from Add(): %s", err.Error())
+	}
+
+	// Unsorted input falls back to the regular path.
+	td = tdigest.NewWithCompression(1000)
+	td.AddSorted([]float64{5, math.NaN(), 4, 3, 2, 1})
+	if got := td.Count(); got != 5 {
+		t.Errorf("unexpected count for unsorted input, got %g want 5", got)
+	}
+	if got := td.Quantile(0.5); got != 3 {
+		t.Errorf("unexpected median for unsorted input, got %g want 3", got)
+	}
+}
+
+func TestTdigest_AddSliceChunks(t *testing.T) {
+	// A compression of 100 buffers 801 incoming centroids.
+	xs := append([]float64(nil), UniformData[:10*801]...)
+	compressions := 0
+	td := tdigest.NewWithCompression(100, tdigest.WithCompressHook(func(tdigest.CompressStats) {
+		compressions++
+	}))
+	td.AddSlice(xs)
+	if compressions != 10 {
+		t.Errorf("unexpected compressions, got %d want 10", compressions)
+	}
+	if !reflect.DeepEqual(xs, UniformData[:len(xs)]) {
+		t.Error("AddSlice() modified its input")
+	}
+	want := tdigest.NewWithCompression(100)
+	for _, x := range xs {
+		want.Add(x, 1)
+	}
+	if err := compareQuantiles(td, want, 0.01); err != nil {
+		t.Errorf("AddSlice() differs from Add(): %s", err.Error())
+	}
+}
+
+func TestTdigest_AddWeightedSlice(t *testing.T) {
+	td := tdigest.NewWithCompression(1000)
+	values := []float64{1, 2, math.NaN(), 3, 4, 5}
+	weights := []float64{1, 2, 1, 0, 2, -1}
+	err := td.AddWeightedSlice(values, weights)
+	rerr, ok := err.(*tdigest.RejectedError)
+	if !ok {
+		t.Fatalf("expected *RejectedError, got %v", err)
+	}
+	if rerr.Rejected != 3 || rerr.Total != 6 {
+		t.Errorf("unexpected rejection summary %+v", rerr)
+	}
+	if got, want := rerr.Error(), "rejected 3 of 6 samples"; got != want {
+		t.Errorf("unexpected error message, got %q want %q", got, want)
+	}
+	if got := td.Count(); got != 5 {
+		t.Errorf("unexpected count, got %g want 5", got)
+	}
+
+	if err := td.AddWeightedSlice([]float64{1, 2}, []float64{1}); err != tdigest.ErrLengthMismatch {
+		t.Errorf("expected ErrLengthMismatch, got %v", err)
+	}
+
+	want := tdigest.NewWithCompression(1000)
+	td = tdigest.NewWithCompression(1000)
+	weights = make([]float64, len(UniformData))
+	for i, x := range UniformData {
+		weights[i] = float64(i%3 + 1)
+		want.Add(x, weights[i])
+	}
+	if err := td.AddWeightedSlice(UniformData, weights); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(td.Centroids(nil), want.Centroids(nil)) {
+		t.Error("AddWeightedSlice() differs from Add()")
+	}
+}
+
+func TestNewWithCompression_Allocs(t *testing.T) {
+	// The digest and a single buffer shared by its centroid lists.
+	allocs := testing.AllocsPerRun(100, func() {
+		tdigest.NewWithCompression(100)
+	})
+	if allocs != 2 {
+		t.Errorf("unexpected number of allocations, got %g want 2", allocs)
+	}
+}
+
+func TestTdigest_ForEachCentroid(t *testing.T) {
+	var got tdigest.CentroidList
+	NormalDigest.ForEachCentroid(func(c tdigest.Centroid) bool {
+		got = append(got, c)
+		return true
+	})
+	if !reflect.DeepEqual(got, NormalDigest.Centroids(nil)) {
+		t.Error("centroids differ from Centroids")
+	}
+
+	n := 0
+	NormalDigest.ForEachCentroid(func(tdigest.Centroid) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Errorf("expected iteration to stop after 3 centroids, got %d", n)
+	}
+
+	var sum float64
+	allocs := testing.AllocsPerRun(10, func() {
+		NormalDigest.ForEachCentroid(func(c tdigest.Centroid) bool {
+			sum += c.Weight
+			return true
+		})
+	})
+	if allocs != 0 {
+		t.Errorf("unexpected allocations, got %g want 0", allocs)
+	}
+}
+
+func TestTdigest_ResetWithCompression(t *testing.T) {
+	td := tdigest.NewWithCompression(1000)
+	td.AddSlice(UniformData[:10000])
+	for _, c := range []float64{100, 1000, 5000} {
+		td.ResetWithCompression(c)
+		if td.Compression != c {
+			t.Errorf("unexpected compression, got %g want %g", td.Compression, c)
+		}
+		if td.Count() != 0 {
+			t.Errorf("expected empty digest, got count %g", td.Count())
+		}
+		td.AddSlice(NormalData[:10000])
+		want := tdigest.NewWithCompression(c)
+		want.AddSlice(NormalData[:10000])
+		if err := compareQuantiles(td, want, 0); err != nil {
+			t.Errorf("compression %g: digest differs: %s", c, err.Error())
+		}
+	}
+
+	td.ResetWithCompression(100)
+	allocs := testing.AllocsPerRun(10, func() {
+		td.ResetWithCompression(1000)
+	})
+	if allocs != 0 {
+		t.Errorf("unexpected allocations, got %g want 0", allocs)
+	}
+}
+
+func TestTdigest_ResetWithOptions(t *testing.T) {
+	td := tdigest.NewWithCompression(100, tdigest.WithStrict())
+	td.Add(1, 1)
+	td.ResetWithOptions(tdigest.WithNonFinitePolicy(tdigest.NonFiniteDrop))
+	if td.Count() != 0 {
+		t.Errorf("expected empty digest, got count %g", td.Count())
+	}
+	if td.Compression != 100 {
+		t.Errorf("unexpected compression, got %g want 100", td.Compression)
+	}
+	td.Add(1, 1)
+	td.Add(math.Inf(1), 1)
+	// Strict mode was cleared, so reads process implicitly.
+	if _, err := td.QuantileErr(0.5); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := td.Count(); got != 1 {
+		t.Errorf("unexpected count, got %g want 1", got)
+	}
+}
+
+func TestWithDeferredCompaction(t *testing.T) {
+	const n = 100000
+	td := tdigest.NewWithCompression(100, tdigest.WithDeferredCompaction())
+	for _, x := range NormalData[:n/2] {
+		td.Add(x, 1)
+	}
+	td.AddSorted([]float64{1, 2, 3})
+	td.AddSlice(NormalData[n/2 : n])
+
+	// A buffer large enough for all values is processed only once, which is
+	// what deferring compaction should amount to.
+	want := tdigest.NewWithCompression(100, tdigest.WithBufferFactors(0, 2*n/100))
+	want.AddSlice(NormalData[:n/2])
+	want.AddSlice([]float64{1, 2, 3})
+	want.AddSlice(NormalData[n/2 : n])
+
+	td.Flush()
+	if !reflect.DeepEqual(td.Centroids(nil), want.Centroids(nil)) {
+		t.Error("deferred digest was compressed before flushing")
+	}
+}
+
+func TestTdigest_MergeAllocs(t *testing.T) {
+	src := tdigest.NewWithCompression(100)
+	src.AddSlice(NormalData[:10000])
+	src.Flush()
+	dst := tdigest.NewWithCompression(100)
+	dst.Merge(src)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		dst.Merge(src)
+	})
+	if allocs != 0 {
+		t.Errorf("unexpected allocations, got %g want 0", allocs)
+	}
+}
@@ -0,0 +1,153 @@
+package tdigest
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidText is returned when parsing a malformed text encoding.
+const ErrInvalidText = Error("invalid tdigest text encoding")
+
+const textHeader = "tdigest"
+
+// maxDecodeCompression bounds the compression accepted by ParseText,
+// UnmarshalBinary and the other decoders and converters, since the buffers
+// of a digest are sized by its compression.
+const maxDecodeCompression = 1e5
+
+// FormatText returns a human readable encoding of the digest, suitable for
+// test fixtures and copy-pasting. The encoding consists of a header line
+// followed by a line of comma separated mean:weight pairs, e.g.
+//
+//	tdigest compression=100 min=1 max=5
+//	1:1,2.5:2,4:1,5:1
+//
+// The min and max fields are omitted for an empty digest. Values are
+// formatted so that ParseText restores them exactly. In strict mode,
+// FormatText returns ErrUnflushed if the digest has pending centroids.
+func FormatText(t *TDigest) (string, error) {
+	t.lock()
+	defer t.unlock()
+	if err := t.prepareRead(); err != nil {
+		return "", err
+	}
+	r := t.startRegion("tdigest.format")
+	defer endRegion(r)
+
+	var b strings.Builder
+	b.WriteString(textHeader)
+	b.WriteString(" compression=")
+	b.WriteString(formatTextFloat(t.Compression))
+	if t.processed.Len() > 0 {
+		b.WriteString(" min=")
+		b.WriteString(formatTextFloat(t.min))
+		b.WriteString(" max=")
+		b.WriteString(formatTextFloat(t.max))
+	}
+	b.WriteByte('\n')
+	for i, c := range t.processed {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(formatTextFloat(c.Mean))
+		b.WriteByte(':')
+		b.WriteString(formatTextFloat(c.Weight))
+	}
+	b.WriteByte('\n')
+	return b.String(), nil
+}
+
+// ParseText parses a digest from the encoding produced by FormatText.
+// Parsing is strict: centroids must be sorted by mean, weights must be
+// positive and finite, NaN is never accepted, min and max must enclose all
+// centroid means, and the compression may not exceed 100000.
+func ParseText(s string) (*TDigest, error) {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if len(lines) != 2 {
+		return nil, fmt.Errorf("%w: expected header and centroid lines, got %d lines", ErrInvalidText, len(lines))
+	}
+
+	fields := strings.Split(lines[0], " ")
+	if fields[0] != textHeader {
+		return nil, fmt.Errorf("%w: missing %q header", ErrInvalidText, textHeader)
+	}
+	if len(fields) != 2 && len(fields) != 4 {
+		return nil, fmt.Errorf("%w: unexpected number of header fields", ErrInvalidText)
+	}
+	compression, err := parseTextField(fields[1], "compression")
+	if err != nil {
+		return nil, err
+	}
+	if compression < 1 || compression > maxDecodeCompression {
+		return nil, fmt.Errorf("%w: compression must be between 1 and %g", ErrInvalidText, float64(maxDecodeCompression))
+	}
+
+	t := NewWithCompression(compression)
+	if len(fields) == 2 {
+		if lines[1] != "" {
+			return nil, fmt.Errorf("%w: centroids require min and max", ErrInvalidText)
+		}
+		return t, nil
+	}
+	if lines[1] == "" {
+		return nil, fmt.Errorf("%w: min and max require centroids", ErrInvalidText)
+	}
+	min, err := parseTextField(fields[2], "min")
+	if err != nil {
+		return nil, err
+	}
+	max, err := parseTextField(fields[3], "max")
+	if err != nil {
+		return nil, err
+	}
+
+	var weight float64
+	for i, pair := range strings.Split(lines[1], ",") {
+		sep := strings.IndexByte(pair, ':')
+		if sep < 0 {
+			return nil, fmt.Errorf("%w: centroid %d: expected mean:weight", ErrInvalidText, i)
+		}
+		mean, err := strconv.ParseFloat(pair[:sep], 64)
+		if err != nil || math.IsNaN(mean) {
+			return nil, fmt.Errorf("%w: centroid %d: invalid mean %q", ErrInvalidText, i, pair[:sep])
+		}
+		w, err := strconv.ParseFloat(pair[sep+1:], 64)
+		if err != nil || !(w > 0) || math.IsInf(w, 1) {
+			return nil, fmt.Errorf("%w: centroid %d: invalid weight %q", ErrInvalidText, i, pair[sep+1:])
+		}
+		if n := t.processed.Len(); n > 0 && mean < t.processed[n-1].Mean {
+			return nil, fmt.Errorf("%w: centroid %d: means are not sorted", ErrInvalidText, i)
+		}
+		t.processed = append(t.processed, Centroid{Mean: mean, Weight: w})
+		weight += w
+	}
+	if math.IsInf(weight, 1) {
+		return nil, fmt.Errorf("%w: total weight overflows", ErrInvalidText)
+	}
+	if min > t.processed[0].Mean || max < t.processed[t.processed.Len()-1].Mean {
+		return nil, fmt.Errorf("%w: min and max do not enclose the centroids", ErrInvalidText)
+	}
+	t.processedWeight = weight
+	t.accepted = uint64(t.processed.Len())
+	t.min = min
+	t.max = max
+	return t, nil
+}
+
+func parseTextField(field, key string) (float64, error) {
+	if !strings.HasPrefix(field, key+"=") {
+		return 0, fmt.Errorf("%w: expected %s field", ErrInvalidText, key)
+	}
+	v := field[len(key)+1:]
+	x, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(x) {
+		return 0, fmt.Errorf("%w: invalid %s %q", ErrInvalidText, key, v)
+	}
+	return x, nil
+}
+
+func formatTextFloat(x float64) string {
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
@@ -0,0 +1,113 @@
+package tdigest_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+// formatText returns the text encoding of td, failing the test on error.
+func formatText(tb testing.TB, td *tdigest.TDigest) string {
+	tb.Helper()
+	text, err := tdigest.FormatText(td)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return text
+}
+
+func TestFormatText(t *testing.T) {
+	td := tdigest.NewWithCompression(3)
+	for _, x := range []float64{1, 2, 3, 4, 5} {
+		td.Add(x, 1)
+	}
+	want := "tdigest compression=3 min=1 max=5\n1:1,2.5:2,4:1,5:1\n"
+	if got := formatText(t, td); got != want {
+		t.Errorf("unexpected text, got %q want %q", got, want)
+	}
+
+	want = "tdigest compression=1000\n\n"
+	if got := formatText(t, tdigest.New()); got != want {
+		t.Errorf("unexpected text for empty digest, got %q want %q", got, want)
+	}
+}
+
+func TestParseText(t *testing.T) {
+	for _, td := range []*tdigest.TDigest{tdigest.New(), NormalDigest, UniformDigest} {
+		text := formatText(t, td)
+		got, err := tdigest.ParseText(text)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got.Centroids(nil), td.Centroids(nil)) {
+			t.Error("centroids differ after round trip")
+		}
+		if got.Compression != td.Compression {
+			t.Errorf("unexpected compression, got %g want %g", got.Compression, td.Compression)
+		}
+		for _, q := range quantiles {
+			if a, b := got.Quantile(q), td.Quantile(q); a != b && !(math.IsNaN(a) && math.IsNaN(b)) {
+				t.Errorf("quantile %g differs after round trip, got %g want %g", q, a, b)
+			}
+		}
+		if again := formatText(t, got); again != text {
+			t.Error("text differs after round trip")
+		}
+	}
+}
+
+func TestParseText_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "empty", text: ""},
+		{name: "missing body", text: "tdigest compression=100"},
+		{name: "bad header", text: "digest compression=100\n\n"},
+		{name: "zero compression", text: "tdigest compression=0\n\n"},
+		{name: "huge compression", text: "tdigest compression=1e9\n\n"},
+		{name: "nan compression", text: "tdigest compression=NaN\n\n"},
+		{name: "missing min max", text: "tdigest compression=100\n1:1\n"},
+		{name: "min max without centroids", text: "tdigest compression=100 min=1 max=1\n\n"},
+		{name: "swapped fields", text: "tdigest compression=100 max=1 min=1\n1:1\n"},
+		{name: "missing weight", text: "tdigest compression=100 min=1 max=1\n1\n"},
+		{name: "nan mean", text: "tdigest compression=100 min=1 max=1\nNaN:1\n"},
+		{name: "zero weight", text: "tdigest compression=100 min=1 max=1\n1:0\n"},
+		{name: "infinite weight", text: "tdigest compression=100 min=1 max=1\n1:+Inf\n"},
+		{name: "unsorted", text: "tdigest compression=100 min=1 max=2\n2:1,1:1\n"},
+		{name: "outside min", text: "tdigest compression=100 min=2 max=2\n1:1,2:1\n"},
+		{name: "trailing comma", text: "tdigest compression=100 min=1 max=1\n1:1,\n"},
+		{name: "extra line", text: "tdigest compression=100 min=1 max=1\n1:1\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tdigest.ParseText(tt.text); !errors.Is(err, tdigest.ErrInvalidText) {
+				t.Errorf("expected ErrInvalidText, got %v", err)
+			}
+		})
+	}
+}
+
+func FuzzParseText(f *testing.F) {
+	f.Add("tdigest compression=3 min=1 max=5\n1:1,2.5:2,4:1,5:1\n")
+	f.Add("tdigest compression=1000\n\n")
+	f.Add("tdigest compression=100 min=-Inf max=+Inf\n-Inf:1,+Inf:2\n")
+	f.Fuzz(func(t *testing.T, text string) {
+		td, err := tdigest.ParseText(text)
+		if err != nil {
+			return
+		}
+		again, err := tdigest.ParseText(formatText(t, td))
+		if err != nil {
+			t.Fatalf("formatted digest does not parse: %v", err)
+		}
+		if !reflect.DeepEqual(again.Centroids(nil), td.Centroids(nil)) {
+			t.Error("centroids differ after round trip")
+		}
+		td.Quantile(0.5)
+		td.CDF(0)
+	})
+}
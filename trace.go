@@ -0,0 +1,32 @@
+package tdigest
+
+import (
+	"context"
+	"runtime/trace"
+)
+
+// WithTracing annotates the expensive phases of the digest, such as sorting
+// and merging centroids, computing cumulative weights and encoding, as
+// runtime/trace regions. This shows which phase of the digest ran inside a
+// slow request when capturing an execution trace.
+func WithTracing() Option {
+	return func(t *TDigest) {
+		t.tracing = true
+	}
+}
+
+// startRegion starts a trace region if tracing is enabled, and returns nil
+// otherwise.
+func (t *TDigest) startRegion(name string) *trace.Region {
+	if !t.tracing {
+		return nil
+	}
+	return trace.StartRegion(context.Background(), name)
+}
+
+// endRegion ends a region returned by startRegion.
+func endRegion(r *trace.Region) {
+	if r != nil {
+		r.End()
+	}
+}
@@ -0,0 +1,32 @@
+package tdigest_test
+
+import (
+	"bytes"
+	"runtime/trace"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestWithTracing(t *testing.T) {
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skipf("tracing unavailable: %v", err)
+	}
+	td := tdigest.NewWithCompression(100, tdigest.WithTracing())
+	for _, x := range NormalData[:10000] {
+		td.Add(x, 1)
+	}
+	q := td.Quantile(0.5)
+	tdigest.FormatText(td)
+	trace.Stop()
+
+	if want := NormalDigest.Quantile(0.5); q < want*0.99 || q > want*1.01 {
+		t.Errorf("unexpected median, got %g want %g", q, want)
+	}
+	for _, region := range []string{"tdigest.sort", "tdigest.merge", "tdigest.cumulative", "tdigest.format"} {
+		if !bytes.Contains(buf.Bytes(), []byte(region)) {
+			t.Errorf("trace does not contain region %q", region)
+		}
+	}
+}
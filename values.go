@@ -0,0 +1,53 @@
+package tdigest
+
+// valueChunk is the number of values converted to float64 at a time by the
+// typed Add methods.
+const valueChunk = 256
+
+// AddFloat32Slice adds each of the float32 values xs with a weight of one,
+// like AddValues, converting them a chunk at a time without allocating.
+func (t *TDigest) AddFloat32Slice(xs []float32) {
+	var buf [valueChunk]float64
+	t.lock()
+	defer t.unlock()
+	for len(xs) > 0 {
+		n := copyFloat32s(buf[:], xs)
+		t.addBuffered(buf[:n])
+		xs = xs[n:]
+	}
+}
+
+// AddInt64Slice adds each of the int64 values xs with a weight of one, like
+// AddValues, converting them a chunk at a time without allocating. It suits
+// latencies in nanoseconds, for one. Values beyond 2^53 in magnitude are
+// rounded to the nearest float64.
+func (t *TDigest) AddInt64Slice(xs []int64) {
+	var buf [valueChunk]float64
+	t.lock()
+	defer t.unlock()
+	for len(xs) > 0 {
+		n := copyInt64s(buf[:], xs)
+		t.addBuffered(buf[:n])
+		xs = xs[n:]
+	}
+}
+
+func copyFloat32s(dst []float64, src []float32) int {
+	if len(src) < len(dst) {
+		dst = dst[:len(src)]
+	}
+	for i := range dst {
+		dst[i] = float64(src[i])
+	}
+	return len(dst)
+}
+
+func copyInt64s(dst []float64, src []int64) int {
+	if len(src) < len(dst) {
+		dst = dst[:len(src)]
+	}
+	for i := range dst {
+		dst[i] = float64(src[i])
+	}
+	return len(dst)
+}
@@ -0,0 +1,52 @@
+package tdigest_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestTdigest_AddFloat32Slice(t *testing.T) {
+	xs := make([]float32, 1000)
+	want := tdigest.NewWithCompression(100)
+	for i := range xs {
+		xs[i] = float32(UniformData[i])
+		want.Add(float64(xs[i]), 1)
+	}
+	xs = append(xs, float32(math.NaN()))
+	td := tdigest.NewWithCompression(100)
+	td.AddFloat32Slice(xs)
+	if !reflect.DeepEqual(td.Centroids(nil), want.Centroids(nil)) {
+		t.Error("centroids differ from adding converted values")
+	}
+	if td.Count() != 1000 {
+		t.Errorf("unexpected count, got %g want 1000", td.Count())
+	}
+}
+
+func TestTdigest_AddInt64Slice(t *testing.T) {
+	xs := make([]int64, 1000)
+	want := tdigest.NewWithCompression(100)
+	for i := range xs {
+		xs[i] = int64(NormalData[i] * 1e6)
+		want.Add(float64(xs[i]), 1)
+	}
+	td := tdigest.NewWithCompression(100)
+	td.AddInt64Slice(xs)
+	if !reflect.DeepEqual(td.Centroids(nil), want.Centroids(nil)) {
+		t.Error("centroids differ from adding converted values")
+	}
+}
+
+func TestTdigest_AddInt64SliceAllocs(t *testing.T) {
+	xs := make([]int64, 1000)
+	td := tdigest.NewWithCompression(100)
+	allocs := testing.AllocsPerRun(10, func() {
+		td.AddInt64Slice(xs)
+	})
+	if allocs != 0 {
+		t.Errorf("unexpected allocations, got %g want 0", allocs)
+	}
+}
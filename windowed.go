@@ -0,0 +1,214 @@
+package tdigest
+
+import (
+	"sync"
+	"time"
+)
+
+// WindowedTDigest is a digest over a sliding window of time, such as the
+// last hour for SLO reporting. The window is split into slots, each a
+// digest, held in a ring: samples are added to the current slot, Rotate
+// moves on to the next slot, dropping the oldest one, and queries merge
+// all slots. Unlike decay, samples count fully until their slot is
+// dropped, and not at all afterwards. It is safe for concurrent use.
+type WindowedTDigest struct {
+	mu      sync.Mutex
+	slots   []*TDigest
+	current int
+	// merged is reused between queries.
+	merged *TDigest
+
+	// starts holds the time each slot was started, according to now.
+	starts []time.Time
+	now    func() time.Time
+	// width is the duration of a slot in TTL mode, or zero.
+	width time.Duration
+	// rotation is the scheduler started by Start, if any.
+	rotation *rotation
+}
+
+// NewWindowed initializes a new windowed distribution with n slots, at
+// least one, each a digest with the given compression and options. For
+// example, 12 slots rotated every 5 minutes cover the last hour.
+func NewWindowed(c float64, n int, opts ...Option) *WindowedTDigest {
+	if n < 1 {
+		n = 1
+	}
+	w := &WindowedTDigest{
+		slots:  make([]*TDigest, n),
+		merged: NewWithCompression(c, opts...),
+		starts: make([]time.Time, n),
+		now:    time.Now,
+	}
+	for i := range w.slots {
+		w.slots[i] = NewWithCompression(c, opts...)
+	}
+	w.starts[0] = w.now()
+	return w
+}
+
+// NewWindowedTTL initializes a new windowed distribution in TTL mode, which
+// guarantees that queries never include samples older than ttl. The ttl is
+// split into n slots, which rotate by themselves as time passes, so that
+// every slot is dropped once it started more than ttl ago. Samples are
+// therefore dropped up to ttl/n before they reach the ttl.
+func NewWindowedTTL(c float64, ttl time.Duration, n int, opts ...Option) *WindowedTDigest {
+	w := NewWindowed(c, n, opts...)
+	w.width = ttl / time.Duration(len(w.slots))
+	if w.width <= 0 {
+		w.width = 1
+	}
+	return w
+}
+
+// SetClock replaces the clock used to time slots, time.Now by default, e.g.
+// by a simulated one in tests. The current slot is restarted at the time
+// of the new clock.
+func (w *WindowedTDigest) SetClock(now func() time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.now = now
+	w.starts[w.current] = now()
+}
+
+// expire rotates the slots in TTL mode until the current slot covers the
+// current time, resetting all of them if they all expired.
+func (w *WindowedTDigest) expire() {
+	if w.width == 0 {
+		return
+	}
+	elapsed := w.now().Sub(w.starts[w.current])
+	if elapsed < w.width {
+		return
+	}
+	k := elapsed / w.width
+	start := w.starts[w.current]
+	if n := time.Duration(len(w.slots)); k > n {
+		// Every slot expired, and rotating through all of them resets them.
+		start = start.Add((k - n) * w.width)
+		k = n
+	}
+	for ; k > 0; k-- {
+		w.rotate()
+		start = start.Add(w.width)
+		w.starts[w.current] = start
+	}
+}
+
+// Add adds a value x with a weight w to the current slot.
+func (w *WindowedTDigest) Add(x, weight float64) {
+	w.mu.Lock()
+	w.expire()
+	w.slots[w.current].Add(x, weight)
+	w.mu.Unlock()
+}
+
+// AddSlice adds each of the values xs with a weight of one to the current
+// slot.
+func (w *WindowedTDigest) AddSlice(xs []float64) {
+	w.mu.Lock()
+	w.expire()
+	w.slots[w.current].AddSlice(xs)
+	w.mu.Unlock()
+}
+
+// Merge merges the supplied digest into the current slot, see
+// TDigest.MergeErr. t2 must not be in use by other goroutines.
+func (w *WindowedTDigest) Merge(t2 *TDigest) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire()
+	return w.slots[w.current].MergeErr(t2)
+}
+
+// Rotate starts a new slot, dropping the samples of the oldest one. In TTL
+// mode, slots are rotated by themselves, and Rotate starts the new slot
+// early.
+func (w *WindowedTDigest) Rotate() {
+	w.rotateClosed(false)
+}
+
+func (w *WindowedTDigest) rotate() {
+	w.current = (w.current + 1) % len(w.slots)
+	w.slots[w.current].Reset()
+}
+
+// Digest returns a new digest holding the merged data of all slots.
+func (w *WindowedTDigest) Digest() *TDigest {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire()
+	w.mergeSlots()
+	return w.merged.Clone()
+}
+
+// Quantile returns the (approximate) quantile of the distribution over the
+// window, see TDigest.Quantile.
+func (w *WindowedTDigest) Quantile(q float64) float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire()
+	w.mergeSlots()
+	return w.merged.Quantile(q)
+}
+
+// CDF returns the cumulative distribution function of the distribution
+// over the window for a given value x, see TDigest.CDF.
+func (w *WindowedTDigest) CDF(x float64) float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire()
+	w.mergeSlots()
+	return w.merged.CDF(x)
+}
+
+// Count returns the total weight of all slots.
+func (w *WindowedTDigest) Count() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire()
+	var n float64
+	for _, s := range w.slots {
+		n += s.Count()
+	}
+	return n
+}
+
+// QuantileRange returns the (approximate) quantile of the samples in the
+// slots that overlap the interval from from to to, inclusive, such as the
+// p99 between 14:00 and 14:30. A slot covers the time from its start to the
+// start of the next slot, or to now for the current one. Since slots are
+// merged whole, the result is only as fine-grained as the slots; it is NaN
+// if no slot overlaps the interval.
+func (w *WindowedTDigest) QuantileRange(from, to time.Time, q float64) float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire()
+	w.merged.Reset()
+	end := w.now()
+	// Walk the slots from the newest to the oldest, each ending where the
+	// next one started.
+	for k := 0; k < len(w.slots); k++ {
+		i := (w.current - k + len(w.slots)) % len(w.slots)
+		start := w.starts[i]
+		if start.IsZero() {
+			break
+		}
+		if !start.After(to) && !end.Before(from) {
+			w.merged.merge(w.slots[i])
+		}
+		end = start
+	}
+	w.merged.Flush()
+	return w.merged.Quantile(q)
+}
+
+// mergeSlots resets merged and merges all slots into it. merged is flushed,
+// so that it can be read in strict mode.
+func (w *WindowedTDigest) mergeSlots() {
+	w.merged.Reset()
+	for _, s := range w.slots {
+		w.merged.merge(s)
+	}
+	w.merged.Flush()
+}
@@ -0,0 +1,118 @@
+package tdigest_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestWindowedTDigest(t *testing.T) {
+	w := tdigest.NewWindowed(100, 3)
+	w.AddSlice([]float64{1, 2, 3})
+	w.Rotate()
+	w.Add(10, 1)
+	w.Rotate()
+	other := tdigest.NewWithCompression(100)
+	other.AddValues(20, 30)
+	if err := w.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := w.Count(); got != 6 {
+		t.Errorf("unexpected count, got %g want 6", got)
+	}
+	if got := w.Quantile(0); got != 1 {
+		t.Errorf("unexpected minimum, got %g want 1", got)
+	}
+
+	// The oldest slot drops out of the window.
+	w.Rotate()
+	if got := w.Count(); got != 3 {
+		t.Errorf("unexpected count after rotation, got %g want 3", got)
+	}
+	if got := w.Quantile(0); got != 10 {
+		t.Errorf("unexpected minimum after rotation, got %g want 10", got)
+	}
+	if got := w.CDF(15); got < 0.2 || got > 0.5 {
+		t.Errorf("unexpected CDF after rotation, got %g", got)
+	}
+	td := w.Digest()
+	if td.Count() != 3 || td.Quantile(1) != 30 {
+		t.Error("unexpected digest of the window")
+	}
+
+	for i := 0; i < 2; i++ {
+		w.Rotate()
+	}
+	if got := w.Count(); got != 0 {
+		t.Errorf("unexpected count after the window passed, got %g want 0", got)
+	}
+}
+
+func TestWindowedTDigest_TTL(t *testing.T) {
+	now := time.Unix(1e9, 0)
+	w := tdigest.NewWindowedTTL(100, time.Minute, 4)
+	w.SetClock(func() time.Time { return now })
+	for i := 0; i < 8; i++ {
+		w.Add(float64(i), 1)
+		now = now.Add(10 * time.Second)
+	}
+	// Slots of 15s that started 50s, 35s, 20s and 5s ago hold the samples
+	// observed at or after 50s ago.
+	if got := w.Quantile(0); got != 3 {
+		t.Errorf("unexpected minimum, got %g want 3", got)
+	}
+	if got := w.Count(); got != 5 {
+		t.Errorf("unexpected count, got %g want 5", got)
+	}
+
+	now = now.Add(30 * time.Second)
+	if got := w.Count(); got != 2 {
+		t.Errorf("unexpected count, got %g want 2", got)
+	}
+	now = now.Add(time.Hour)
+	if got := w.Count(); got != 0 {
+		t.Errorf("unexpected count after the ttl passed, got %g want 0", got)
+	}
+	w.Add(42, 1)
+	if got := w.Quantile(0.5); got != 42 {
+		t.Errorf("unexpected median after the ttl passed, got %g want 42", got)
+	}
+}
+
+func TestWindowedTDigest_QuantileRange(t *testing.T) {
+	now := time.Unix(1e9, 0)
+	w := tdigest.NewWindowed(100, 4)
+	w.SetClock(func() time.Time { return now })
+	for i := 0; i < 5; i++ {
+		if i > 0 {
+			w.Rotate()
+		}
+		w.AddSlice([]float64{float64(10 * i), float64(10*i + 1)})
+		now = now.Add(time.Minute)
+	}
+	start := time.Unix(1e9, 0)
+	tests := []struct {
+		name     string
+		from, to time.Duration
+		q        float64
+		want     float64
+	}{
+		{name: "current", from: 4*time.Minute + time.Second, to: 5 * time.Minute, q: 0, want: 40},
+		{name: "middle", from: 2*time.Minute + time.Second, to: 2*time.Minute + 2*time.Second, q: 1, want: 21},
+		{name: "span", from: 90 * time.Second, to: 150 * time.Second, q: 0, want: 10},
+		{name: "span max", from: 90 * time.Second, to: 150 * time.Second, q: 1, want: 21},
+		{name: "dropped", from: 0, to: 30 * time.Second, q: 0.5, want: math.NaN()},
+		{name: "future", from: time.Hour, to: 2 * time.Hour, q: 0.5, want: math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := w.QuantileRange(start.Add(tt.from), start.Add(tt.to), tt.q)
+			if got != tt.want && !(math.IsNaN(got) && math.IsNaN(tt.want)) {
+				t.Errorf("unexpected quantile, got %g want %g", got, tt.want)
+			}
+		})
+	}
+}